@@ -18,7 +18,11 @@ package cargo
 
 import (
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/heroku/color"
@@ -26,13 +30,14 @@ import (
 	"github.com/paketo-buildpacks/libpak"
 	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/paketo-buildpacks/libpak/effect"
-	"github.com/paketo-buildpacks/libpak/sbom"
+	"github.com/paketo-community/cargo/mtimes"
 	"github.com/paketo-community/cargo/runner"
 	"github.com/paketo-community/cargo/tini"
 )
 
 type Build struct {
 	CargoService runner.CargoService
+	Clock        Clock
 	Logger       bard.Logger
 }
 
@@ -42,14 +47,29 @@ func (b Build) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
 
 	pr := libpak.PlanEntryResolver{Plan: context.Plan}
 
-	if _, ok, err := pr.Resolve(PlanEntryRustCargo); err != nil {
+	if entry, ok, err := pr.Resolve(PlanEntryRustCargo); err != nil {
 		return libcnb.BuildResult{}, fmt.Errorf("unable to resolve Rust Cargo plan entry\n%w", err)
 	} else if ok {
-		cr, err := libpak.NewConfigurationResolver(context.Buildpack, &b.Logger)
+		bcr, err := libpak.NewConfigurationResolver(context.Buildpack, &b.Logger)
 		if err != nil {
 			return libcnb.BuildResult{}, fmt.Errorf("unable to create configuration resolver\n%w", err)
 		}
 
+		// the project directory can't be configured in Cargo.toml, which is found through it
+		projectDirRaw, _ := bcr.Resolve("BP_CARGO_PROJECT_DIR")
+		projectDir, err := ProjectDir(context.Application.Path, projectDirRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_PROJECT_DIR\n%w", err)
+		}
+		if projectDir != context.Application.Path {
+			b.Logger.Infof("Building the Rust project in %s", projectDir)
+		}
+
+		cr, err := NewProjectConfigurationResolver(bcr, projectDir)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to read project configuration\n%w", err)
+		}
+
 		tiniEnabled := !cr.ResolveBool("BP_CARGO_TINI_DISABLED")
 		if tiniEnabled {
 			dr, err := libpak.NewDependencyResolver(context)
@@ -74,52 +94,373 @@ func (b Build) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
 		}
 
 		cargoHome, found := cr.Resolve("CARGO_HOME")
-		if !found {
-			return libcnb.BuildResult{}, fmt.Errorf("unable to locate cargo home")
+		if !found || cargoHome == "" {
+			return libcnb.BuildResult{}, ErrCargoHomeNotSet
 		}
 
-		includeFolders, _ := cr.Resolve("BP_INCLUDE_FILES")
-
-		// Deprecated: to be removed before the cargo 1.0.0 release
-		deprecatedExcludeFolders, usedDeprecatedExclude := cr.Resolve("BP_CARGO_EXCLUDE_FOLDERS")
-		if usedDeprecatedExclude {
-			b.Logger.Infof("%s: `BP_CARGO_EXCLUDE_FOLDERS` has been deprecated and will be removed before the paketo-community/cargo 1.0 GA release. Use `BP_INCLUDE_FILES` instead.", color.YellowString("Warning"))
-			includeFolders = fmt.Sprintf("%s:%s", includeFolders, strings.ReplaceAll(deprecatedExcludeFolders, ",", ":"))
-		}
+		cr = b.forwardDeprecated(cr, DeprecatedConfigurations)
 
+		includeFolders, _ := cr.Resolve("BP_INCLUDE_FILES")
 		excludeFolders, _ := cr.Resolve("BP_EXCLUDE_FILES")
 
 		cargoWorkspaceMembers, _ := cr.Resolve("BP_CARGO_WORKSPACE_MEMBERS")
 		cargoInstallArgs, _ := cr.Resolve("BP_CARGO_INSTALL_ARGS")
-		skipSBOMScan := cr.ResolveBool("BP_DISABLE_SBOM")
+
+		cargoInstallArgsPerStackRaw, _ := cr.Resolve("BP_CARGO_INSTALL_ARGS_PER_STACK")
+		cargoInstallArgsPerStack, err := ParseInstallArgsPerStack(cargoInstallArgsPerStackRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_INSTALL_ARGS_PER_STACK=%q\n%w", cargoInstallArgsPerStackRaw, err)
+		}
+
+		if stackArgs, ok := cargoInstallArgsPerStack[context.StackID]; ok {
+			b.Logger.Infof("Adding install arguments for stack %s: %s", context.StackID, stackArgs)
+			cargoInstallArgs = strings.TrimSpace(fmt.Sprintf("%s %s", cargoInstallArgs, stackArgs))
+		}
+
+		cargoInstallArgsFile, _ := cr.Resolve("BP_CARGO_INSTALL_ARGS_FILE")
+		if cargoInstallArgsFile != "" && !filepath.IsAbs(cargoInstallArgsFile) {
+			cargoInstallArgsFile = filepath.Join(context.Application.Path, cargoInstallArgsFile)
+		}
+
+		sbomScannerName, _ := cr.Resolve("BP_CARGO_SBOM_SCANNER")
+		sbomScanner, err := NewSBOMScanner(sbomScannerName, context.Layers, effect.NewExecutor(), b.Logger)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_SBOM_SCANNER\n%w", err)
+		}
+
+		sbomFormatsRaw, _ := cr.Resolve("BP_CARGO_SBOM_FORMATS")
+		sbomFormats, err := ParseSBOMFormats(sbomFormatsRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_SBOM_FORMATS\n%w", err)
+		}
+		if _, ok := sbomScanner.(CargoLockSBOMScanner); ok && len(sbomFormats) > 0 && !slices.Contains(sbomFormats, libcnb.CycloneDXJSON) {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_SBOM_FORMATS=%q, the native SBOM scanner only writes %s", sbomFormatsRaw, SBOMFormatCycloneDX)
+		}
+
+		skipSBOMScan := cr.ResolveBool("BP_DISABLE_SBOM") || sbomScanner == nil
+		requireSBOM := cr.ResolveBool("BP_CARGO_REQUIRE_SBOM")
+		if requireSBOM && skipSBOMScan {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to require an SBOM with BP_CARGO_REQUIRE_SBOM, the SBOM scan is disabled by BP_DISABLE_SBOM or BP_CARGO_SBOM_SCANNER=none")
+		}
 		staticType, _ := cr.Resolve("BP_STATIC_BINARY_TYPE")
+		stripRaw, _ := cr.Resolve("BP_CARGO_STRIP")
+		strip, err := strconv.ParseBool(stripRaw)
+		keepDebugSymbols := err == nil && !strip
+
+		restoreStrategy, _ := cr.Resolve("BP_CARGO_RESTORE_STRATEGY")
+		if restoreStrategy != "" && restoreStrategy != mtimes.StrategyMTimes && restoreStrategy != mtimes.StrategyChecksum {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_RESTORE_STRATEGY=%q, must be %q or %q",
+				restoreStrategy, mtimes.StrategyMTimes, mtimes.StrategyChecksum)
+		}
+
+		rustFlags, _ := cr.Resolve("BP_CARGO_RUSTFLAGS")
+		includeExamples := cr.ResolveBool("BP_CARGO_INCLUDE_EXAMPLES")
+		includeDepBins := cr.ResolveBool("BP_CARGO_INCLUDE_DEP_BINS")
+
+		memberFeaturesRaw, _ := cr.Resolve("BP_CARGO_MEMBER_FEATURES")
+		memberFeatures, err := runner.ParseMemberFeatures(memberFeaturesRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_MEMBER_FEATURES\n%w", err)
+		}
+
+		metadataArgsRaw, _ := cr.Resolve("BP_CARGO_METADATA_ARGS")
+		metadataArgs, err := shellwords.Parse(metadataArgsRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_METADATA_ARGS=%q\n%w", metadataArgsRaw, err)
+		}
+
+		metadataArgs, droppedMetadataArgs := runner.FilterMetadataArgs(metadataArgs)
+		if len(droppedMetadataArgs) > 0 {
+			b.Logger.Infof("%s: ignoring %s from BP_CARGO_METADATA_ARGS, only feature and platform selection can be passed to cargo metadata",
+				color.YellowString("Warning"), strings.Join(droppedMetadataArgs, " "))
+		}
+
+		logFormat, _ := cr.Resolve("BP_LOG_FORMAT")
+		events, err := NewEvents(logFormat, b.Logger.InfoWriter(), b.Clock)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_LOG_FORMAT\n%w", err)
+		}
+
+		var spans *Spans
+		if cr.ResolveBool("BP_CARGO_OTEL") {
+			spans = &Spans{Clock: b.Clock}
+			if span, ok := DetectSpan(entry.Metadata); ok {
+				spans.Add(span)
+			}
+		}
+
+		var registryCacheMaxBytes int64
+		if raw, _ := cr.Resolve("BP_CARGO_REGISTRY_CACHE_MAX_MB"); raw != "" {
+			maxMB, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || maxMB <= 0 {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_REGISTRY_CACHE_MAX_MB=%q, must be a positive number of megabytes", raw)
+			}
+			registryCacheMaxBytes = maxMB * 1024 * 1024
+		}
+
+		srcKeep := 0
+		if raw, _ := cr.Resolve("BP_CARGO_SRC_KEEP"); raw != "" {
+			srcKeep, err = strconv.Atoi(raw)
+			if err != nil || srcKeep <= 0 {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_SRC_KEEP=%q, must be a positive number of builds", raw)
+			}
+		}
+
+		var cleanHomeExcept []string
+		if raw, _ := cr.Resolve("BP_CARGO_CLEAN_HOME_EXCEPT"); strings.TrimSpace(raw) != "" {
+			cleanHomeExcept = runner.ParseCleanHomeExcept(raw)
+		}
+
+		// the target directory is linked to the cache layer, so compiled objects are kept but not used at launch
+		sccache := cr.ResolveBool("BP_CARGO_SCCACHE")
+		sccacheDir := ""
+		if sccache {
+			sccacheDir = filepath.Join(projectDir, "target", "sccache")
+		}
+
+		fetchRetry := 0
+		if raw, _ := cr.Resolve("BP_CARGO_FETCH_RETRY"); raw != "" {
+			fetchRetry, err = strconv.Atoi(raw)
+			if err != nil || fetchRetry < 0 {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_FETCH_RETRY=%q, must be a number of retries", raw)
+			}
+		}
+
+		installRetries := 0
+		if raw, _ := cr.Resolve("BP_CARGO_INSTALL_RETRIES"); raw != "" {
+			installRetries, err = strconv.Atoi(raw)
+			if err != nil || installRetries < 0 {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_INSTALL_RETRIES=%q, must be a number of retries", raw)
+			}
+		}
+
+		installRetryOnLock := 0
+		if raw, _ := cr.Resolve("BP_CARGO_INSTALL_RETRY_ON_LOCK"); raw != "" {
+			installRetryOnLock, err = strconv.Atoi(raw)
+			if err != nil || installRetryOnLock < 0 {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_INSTALL_RETRY_ON_LOCK=%q, must be a number of retries", raw)
+			}
+		}
+
+		var appBinDir string
+		if raw, _ := cr.Resolve("BP_CARGO_APP_BIN_DIR"); raw != "" {
+			appBinDir = filepath.Clean(raw)
+			if filepath.IsAbs(appBinDir) || appBinDir == "." || appBinDir == ".." || strings.HasPrefix(appBinDir, ".."+string(filepath.Separator)) {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_APP_BIN_DIR=%q, must be a directory inside the application", raw)
+			}
+		}
+
+		var jobs int
+		if raw, _ := cr.Resolve("BP_CARGO_JOBS"); raw != "" {
+			jobs, err = strconv.Atoi(raw)
+			if err != nil || jobs <= 0 {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_JOBS=%q, must be a positive number of jobs", raw)
+			}
+		}
+
+		verbosityRaw, _ := cr.Resolve("BP_CARGO_VERBOSE")
+		verbosity, err := runner.ParseVerbosity(verbosityRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_VERBOSE=%q\n%w", verbosityRaw, err)
+		}
+
+		var installTimeout time.Duration
+		if raw, _ := cr.Resolve("BP_CARGO_INSTALL_TIMEOUT_PER_MEMBER"); raw != "" {
+			installTimeout, err = time.ParseDuration(raw)
+			if err != nil || installTimeout <= 0 {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_INSTALL_TIMEOUT_PER_MEMBER=%q, must be a positive duration like 30m", raw)
+			}
+		}
+
+		target, _ := cr.Resolve("BP_CARGO_TARGET")
+		if target == "" {
+			target, err = ConfiguredTarget(projectDir)
+			if err != nil {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to read configured build target\n%w", err)
+			}
+
+			if target != "" {
+				b.Logger.Infof("Using build target %s from .cargo/config.toml", target)
+			}
+		}
+
+		features, _ := cr.Resolve("BP_CARGO_FEATURES")
+
+		linker, _ := cr.Resolve("BP_CARGO_LINKER")
+		linker = strings.TrimSpace(linker)
+
+		colorRaw, _ := cr.Resolve("BP_CARGO_COLOR")
+		cargoColor, err := runner.ParseColor(colorRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_COLOR\n%w", err)
+		}
+
+		binsRaw, _ := cr.Resolve("BP_CARGO_BINS")
+		bins := runner.ParseBins(binsRaw)
+
+		binExcludeRaw, _ := cr.Resolve("BP_CARGO_BIN_EXCLUDE")
+		binExcludePatterns, err := runner.ParseBinExcludePatterns(binExcludeRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_BIN_EXCLUDE\n%w", err)
+		}
+
+		cargoEnvRaw, _ := cr.Resolve("BP_CARGO_ENV")
+		cargoEnv, err := runner.ParseCargoEnv(cargoEnvRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_ENV\n%w", err)
+		}
+
+		// tokens only go into the build process environment, so they never reach a launch layer
+		registries, err := ConfigureRegistries(cargoHome, context.Platform.Bindings)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to configure private registries\n%w", err)
+		}
+		for _, registry := range registries {
+			b.Logger.Infof("Configuring private registry %s", registry.Name)
+		}
+		if err := ExportRegistries(registries); err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to configure private registries\n%w", err)
+		}
+
+		defaultProcess, _ := cr.Resolve("BP_CARGO_DEFAULT_PROCESS")
+
+		preBuild, _ := cr.Resolve("BP_CARGO_PRE_BUILD")
+		pathStrategy, _ := cr.Resolve("BP_CARGO_PATH_STRATEGY")
+
+		processArgsRaw, _ := cr.Resolve("BP_CARGO_PROCESS_ARGS")
+		processArgs, err := ParseProcessArgs(processArgsRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_PROCESS_ARGS\n%w", err)
+		}
+
+		processEnvRaw, _ := cr.Resolve("BP_CARGO_PROCESS_ENV")
+		processEnv, err := ParseProcessEnv(processEnvRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_PROCESS_ENV\n%w", err)
+		}
+
+		webArgsRaw, _ := cr.Resolve("BP_CARGO_WEB_ARGS")
+		webArgs, err := shellwords.Parse(webArgsRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_WEB_ARGS=%q\n%w", webArgsRaw, err)
+		}
+
+		processMembers, _ := cr.Resolve("BP_CARGO_PROCESS_MEMBERS")
+		runAudit := cr.ResolveBool("BP_CARGO_AUDIT")
+		cargoAuditIgnore, _ := cr.Resolve("BP_CARGO_AUDIT_IGNORE")
+
+		binaryProbesRaw, _ := cr.Resolve("BP_CARGO_POST_STRIP_VERIFY_PROBES")
+		binaryProbes, err := runner.ParseBinaryProbes(binaryProbesRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_POST_STRIP_VERIFY_PROBES\n%w", err)
+		}
+
+		// binaries are only stripped by cargo unless strip arguments are set
+		var stripArgs []string
+		if raw, ok := cr.Resolve("BP_CARGO_STRIP_ARGS"); ok {
+			if keepDebugSymbols {
+				b.Logger.Infof("%s: BP_CARGO_STRIP is false, ignoring BP_CARGO_STRIP_ARGS", color.YellowString("Warning"))
+			} else if stripArgs, err = runner.ParseStripArgs(raw); err != nil {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to use BP_CARGO_STRIP_ARGS=%q\n%w", raw, err)
+			}
+		}
+
+		targetCleanPatternsRaw, _ := cr.Resolve("BP_CARGO_TARGET_DIR_CLEAN_PATTERNS")
+		targetCleanPatterns, err := ParseTargetCleanPatterns(targetCleanPatternsRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_TARGET_DIR_CLEAN_PATTERNS\n%w", err)
+		}
+
+		keepTargetPathsRaw, _ := cr.Resolve("BP_CARGO_KEEP_TARGET_PATHS")
+		keepTargetPaths, err := ParseKeepTargetPaths(keepTargetPathsRaw)
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_KEEP_TARGET_PATHS\n%w", err)
+		}
+
+		// --frozen is --locked and --offline together, so it wins over both
+		locked := cr.ResolveBool("BP_CARGO_LOCKED")
+		frozen := cr.ResolveBool("BP_CARGO_FROZEN")
+		if frozen && !locked {
+			b.Logger.Infof("%s: BP_CARGO_FROZEN is set, ignoring BP_CARGO_LOCKED=false, --frozen implies --locked", color.YellowString("Warning"))
+		}
+
+		dryRun := cr.ResolveBool("BP_CARGO_DRY_RUN")
+		if dryRun {
+			b.Logger.Infof("%s: BP_CARGO_DRY_RUN is set, cargo commands are logged but not run and the image will not contain the application's binaries", color.YellowString("Warning"))
+		}
 
 		service := b.CargoService
 		if service == nil {
 			service = runner.NewCargoRunner(
+				runner.WithBinaryProbes(binaryProbes),
+				runner.WithBinExcludePatterns(binExcludePatterns),
+				runner.WithBins(bins),
+				runner.WithBuildBeforeInstall(cr.ResolveBool("BP_CARGO_BUILD_BEFORE_INSTALL")),
+				runner.WithCargoAuditIgnore(cargoAuditIgnore),
+				runner.WithCargoEnv(cargoEnv),
 				runner.WithCargoHome(cargoHome),
 				runner.WithCargoWorkspaceMembers(cargoWorkspaceMembers),
 				runner.WithCargoInstallArgs(cargoInstallArgs),
-				runner.WithExecutor(effect.NewExecutor()),
+				runner.WithCargoInstallArgsFile(cargoInstallArgsFile),
+				runner.WithCleanHomeExcept(cleanHomeExcept),
+				runner.WithColor(cargoColor),
+				runner.WithDryRun(dryRun),
+				runner.WithExecutor(runner.CommandExecutor{}),
+				runner.WithFeatures(features),
+				runner.WithFetchRetry(fetchRetry),
+				runner.WithFrozen(frozen),
+				runner.WithIncludeDepBins(includeDepBins),
+				runner.WithIncludeExamples(includeExamples),
+				runner.WithInstallRetries(installRetries),
+				runner.WithInstallRetryOnLock(installRetryOnLock),
+				runner.WithInstallTimeout(installTimeout),
+				runner.WithJobs(jobs),
+				runner.WithKeepDebugSymbols(keepDebugSymbols),
+				runner.WithLinker(linker),
+				runner.WithLocked(locked),
 				runner.WithLogger(b.Logger),
+				runner.WithMemberFeatures(memberFeatures),
+				runner.WithMetadataArgs(metadataArgs),
+				runner.WithOffline(cr.ResolveBool("BP_CARGO_OFFLINE")),
+				runner.WithRegistryCacheMaxBytes(registryCacheMaxBytes),
+				runner.WithRustFlags(rustFlags),
+				runner.WithSccacheDir(sccacheDir),
+				runner.WithSrcKeep(srcKeep),
 				runner.WithStack(context.StackID),
-				runner.WithStaticType(staticType))
+				runner.WithStaticType(staticType),
+				runner.WithStripArgs(stripArgs),
+				runner.WithTarget(target),
+				runner.WithVerbosity(verbosity))
 		}
 
-		cache := Cache{
-			AppPath: context.Application.Path,
-			Logger:  b.Logger,
+		if cr.ResolveBool("BP_CARGO_VALIDATE") {
+			b.Logger.Header("Validating Cargo manifest")
+			if err := service.ValidateManifest(projectDir); err != nil {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to validate manifest\n%w", err)
+			}
 		}
-		result.Layers = append(result.Layers, cache)
 
-		sbomScanner := sbom.NewSyftCLISBOMScanner(context.Layers, effect.NewExecutor(), b.Logger)
+		if runAudit {
+			b.Logger.Header("Auditing Cargo.lock for crates with security vulnerabilities")
+			if err := service.InstallTool("cargo-audit", []string{"--locked"}); err != nil {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to install cargo-audit\n%w", err)
+			}
+
+			if err := service.Audit(projectDir); err != nil {
+				return libcnb.BuildResult{}, fmt.Errorf("unable to pass cargo audit\n%w", err)
+			}
+		}
 
 		cargoToolsRaw, _ := cr.Resolve("BP_CARGO_INSTALL_TOOLS")
-		cargoTools, err := shellwords.Parse(cargoToolsRaw)
+		cargoTools, cargoToolVersions, err := ParseInstallTools(cargoToolsRaw)
 		if err != nil {
 			return libcnb.BuildResult{}, fmt.Errorf("unable to parse BP_CARGO_INSTALL_TOOLS=%q\n%w", cargoToolsRaw, err)
 		}
 
+		if sccache && !slices.Contains(cargoTools, "sccache") {
+			cargoTools = append(cargoTools, "sccache")
+		}
+
 		cargoToolsArgsRaw, _ := cr.Resolve("BP_CARGO_INSTALL_TOOLS_ARGS")
 		cargoToolsArgs, err := shellwords.Parse(cargoToolsArgsRaw)
 		if err != nil {
@@ -127,28 +468,84 @@ func (b Build) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
 		}
 
 		cargoLayer, err := NewCargo(
+			WithAppBinDir(appBinDir),
 			WithApplicationPath(context.Application.Path),
+			WithBinaryChecksums(cr.ResolveBool("BP_CARGO_BINARY_CHECKSUMS")),
+			WithBins(bins),
+			WithCargoHome(cargoHome),
 			WithCargoService(service),
+			WithClock(b.Clock),
+			WithCopyBinaries(cr.ResolveBool("BP_CARGO_COPY_BINARIES")),
+			WithDefaultProcess(defaultProcess),
+			WithFetch(fetchRetry > 0),
+			WithIncludeExamples(includeExamples),
 			WithIncludeFolders(includeFolders),
+			WithEvents(events),
 			WithExcludeFolders(excludeFolders),
+			WithFeatures(features),
 			WithInstallArgs(cargoInstallArgs),
+			WithInstallArgsFile(cargoInstallArgsFile),
+			WithKeepSource(cr.ResolveBool("BP_CARGO_KEEP_SOURCE")),
+			WithKeepTarget(cr.ResolveBool("BP_CARGO_KEEP_TARGET")),
+			WithKeepTargetPaths(keepTargetPaths),
 			WithLogger(b.Logger),
+			WithMemberFeatures(strings.TrimSpace(memberFeaturesRaw)),
+			WithPathStrategy(pathStrategy),
+			WithPreBuild(preBuild),
+			WithProcessArgs(processArgs),
+			WithProcessEnv(processEnv),
+			WithProcessMembers(processMembers),
+			WithProjectPath(projectDir),
+			WithPruneSources(srcKeep > 0),
+			WithRestoreStrategy(restoreStrategy),
+			WithRequireSBOM(requireSBOM),
 			WithRunSBOMScan(!skipSBOMScan),
+			WithRunTests(cr.ResolveBool("BP_CARGO_RUN_TESTS")),
+			WithSBOMFormats(sbomFormats),
 			WithSBOMScanner(sbomScanner),
+			WithSpans(spans),
 			WithStack(context.StackID),
+			WithStripArgs(stripArgs),
+			WithTarget(target),
+			WithTargetCleanPatterns(targetCleanPatterns),
 			WithTools(cargoTools),
 			WithToolsArgs(cargoToolsArgs),
-			WithWorkspaceMembers(cargoWorkspaceMembers))
+			WithToolVersions(cargoToolVersions),
+			WithVerifyBinaries(cr.ResolveBool("BP_CARGO_POST_STRIP_VERIFY")),
+			WithWebArgs(webArgs),
+			WithWorkerMode(cr.ResolveBool("BP_CARGO_WORKER_MODE")),
+			WithWorkspaceMembers(cargoWorkspaceMembers),
+			WithWriteProcfile(cr.ResolveBool("BP_CARGO_WRITE_PROCFILE")))
 		if err != nil {
 			return libcnb.BuildResult{}, fmt.Errorf("unable to create cargo layer contributor\n%w", err)
 		}
 
+		cargoLayer.QualifiedBinaries, err = cargoLayer.FindQualifiedBinaries()
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to find binaries of multiple members\n%w", err)
+		}
+
 		result.Processes, err = cargoLayer.BuildProcessTypes(tiniEnabled)
 		if err != nil {
 			return libcnb.BuildResult{}, fmt.Errorf("unable to build list of process types\n%w", err)
 		}
+		cargoLayer.Processes = result.Processes
 
-		result.Layers = append(result.Layers, cargoLayer)
+		// the cache is contributed before the application layer, which determines the toolchain it's built with
+		cache := Cache{
+			AppPath:                projectDir,
+			CargoVersion:           cargoLayer.CargoVersion,
+			ClearOnToolchainChange: cr.ResolveBool("BP_CARGO_CLEAR_CACHE_ON_TOOLCHAIN_CHANGE"),
+			LockDiff:               cr.ResolveBool("BP_CARGO_LOCK_DIFF"),
+			Logger:                 b.Logger,
+			RustVersion:            cargoLayer.RustVersion,
+		}
+		result.Layers = append(result.Layers, cache, cargoLayer)
+		result.BOM.Entries = append(result.BOM.Entries, ToolchainBOMEntry(cargoLayer.RustVersion, cargoLayer.CargoVersion))
+		events.Emit("toolchain", map[string]interface{}{
+			"rust-version":  cargoLayer.RustVersion,
+			"cargo-version": cargoLayer.CargoVersion,
+		})
 
 		if skipSBOMScan {
 			result.Labels = append(result.Labels, libcnb.Label{Key: "io.paketo.sbom.disabled", Value: "true"})
@@ -157,3 +554,45 @@ func (b Build) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
 
 	return result, nil
 }
+
+// DeprecatedConfiguration is a configuration name that has been replaced by another one
+type DeprecatedConfiguration struct {
+	Name        string
+	Replacement string
+
+	// Forward combines the value of the replacement, which may be empty, with the value of the deprecated name
+	Forward func(value string, deprecated string) string
+}
+
+// DeprecatedConfigurations are the configuration names that still work, with a warning, until they are removed
+var DeprecatedConfigurations = []DeprecatedConfiguration{
+	// to be removed before the cargo 1.0.0 release
+	{
+		Name:        "BP_CARGO_EXCLUDE_FOLDERS",
+		Replacement: "BP_INCLUDE_FILES",
+		Forward: func(value string, deprecated string) string {
+			return fmt.Sprintf("%s:%s", value, strings.ReplaceAll(deprecated, ",", ":"))
+		},
+	},
+}
+
+// forwardDeprecated warns about each deprecated configuration that is set and forwards its value to the replacement
+func (b Build) forwardDeprecated(cr ProjectConfigurationResolver, deprecations []DeprecatedConfiguration) ProjectConfigurationResolver {
+	for _, d := range deprecations {
+		deprecated, ok := cr.Resolve(d.Name)
+		if !ok {
+			continue
+		}
+
+		b.Logger.Infof("%s: `%s` has been deprecated and will be removed before the paketo-community/cargo 1.0 GA release. Use `%s` instead.",
+			color.YellowString("Warning"), d.Name, d.Replacement)
+
+		value, _ := cr.Resolve(d.Replacement)
+		if cr.Forwarded == nil {
+			cr.Forwarded = map[string]string{}
+		}
+		cr.Forwarded[d.Replacement] = d.Forward(value, deprecated)
+	}
+
+	return cr
+}
@@ -18,13 +18,17 @@ package cargo_test
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sbom"
 	"github.com/paketo-community/cargo/cargo"
+	"github.com/paketo-community/cargo/runner"
 	"github.com/paketo-community/cargo/runner/mocks"
 	"github.com/sclevine/spec"
 	"github.com/stretchr/testify/mock"
@@ -64,6 +68,7 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			},
 			"configurations": []map[string]interface{}{
 				{"name": "BP_CARGO_TINI_DISABLED", "default": "false"},
+				{"name": "BP_DISABLE_SBOM", "default": "false"},
 			},
 		}
 		ctx.StackID = "test-stack-id"
@@ -75,8 +80,8 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			CargoService: &service,
 		}
 
-		service.On("CargoVersion").Return("1.2.3", nil)
-		service.On("RustVersion").Return("1.2.3", nil)
+		service.On("Toolchain").Return("1.2.3", "1.2.3", nil)
+		service.On("ToolchainRequirements", mock.AnythingOfType("string")).Return(runner.ToolchainRequirements{}, nil)
 	})
 
 	it.After(func() {
@@ -91,6 +96,13 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		Expect(result.Layers).To(HaveLen(0))
 	})
 
+	it("fails without CARGO_HOME", func() {
+		ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+		_, err := cargoBuild.Build(ctx)
+		Expect(errors.Is(err, cargo.ErrCargoHomeNotSet)).To(BeTrue())
+	})
+
 	context("build plan entry exists", func() {
 		it.Before(func() {
 			Expect(os.Setenv("CARGO_HOME", "/does/not/matter")).To(Succeed())
@@ -112,6 +124,9 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			Expect(result.Layers[0].Name()).To(Equal("tini"))
 			Expect(result.Layers[1].Name()).To(Equal("Cargo Cache"))
 			Expect(result.Layers[2].Name()).To(Equal("Cargo"))
+			Expect(result.Layers[2].(cargo.Cargo).CargoHome).To(Equal("/does/not/matter"))
+			Expect(result.Layers[1].(cargo.Cache).RustVersion).To(Equal("1.2.3"))
+			Expect(result.Layers[1].(cargo.Cache).CargoVersion).To(Equal("1.2.3"))
 
 			Expect(result.Processes).To(HaveLen(3))
 			Expect(result.Processes).To(ContainElement(
@@ -152,6 +167,326 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 				}))
 		})
 
+		context("BP_CARGO_INSTALL_ARGS_PER_STACK is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_INSTALL_ARGS", "--locked")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_INSTALL_ARGS_PER_STACK", "test-stack-id=--target=x86_64-unknown-linux-musl;other-stack-id=--offline")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_INSTALL_ARGS")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_INSTALL_ARGS_PER_STACK")).To(Succeed())
+			})
+
+			it("adds the arguments for the matching stack", func() {
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers).To(HaveLen(3))
+				Expect(result.Layers[2].(cargo.Cargo).InstallArgs).To(Equal("--locked --target=x86_64-unknown-linux-musl"))
+			})
+
+			it("does not add arguments for other stacks", func() {
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+				Expect(os.Setenv("BP_CARGO_INSTALL_ARGS_PER_STACK", "other-stack-id=--offline")).To(Succeed())
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).InstallArgs).To(Equal("--locked"))
+			})
+		})
+
+		context("BP_CARGO_AUDIT is true", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_AUDIT", "true")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_AUDIT")).To(Succeed())
+			})
+
+			it("installs cargo-audit and audits the application", func() {
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				service.On("InstallTool", "cargo-audit", []string{"--locked"}).Return(nil)
+				service.On("Audit", ctx.Application.Path).Return(nil)
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+				_, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				service.AssertCalled(t, "InstallTool", "cargo-audit", []string{"--locked"})
+				service.AssertCalled(t, "Audit", ctx.Application.Path)
+			})
+
+			it("fails the build when vulnerabilities are found", func() {
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				service.On("InstallTool", "cargo-audit", []string{"--locked"}).Return(nil)
+				service.On("Audit", ctx.Application.Path).Return(fmt.Errorf("RUSTSEC-2020-0071"))
+
+				_, err := cargoBuild.Build(ctx)
+				Expect(err).To(MatchError(ContainSubstring("unable to pass cargo audit\nRUSTSEC-2020-0071")))
+			})
+		})
+
+		context("BP_CARGO_RUN_TESTS is true", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_RUN_TESTS", "true")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_RUN_TESTS")).To(Succeed())
+			})
+
+			it("runs the tests when the application layer is built", func() {
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[len(result.Layers)-1].(cargo.Cargo).RunTests).To(BeTrue())
+				service.AssertNotCalled(t, "Test", mock.Anything)
+			})
+		})
+
+		context("BP_CARGO_VALIDATE is true", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_VALIDATE", "true")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_VALIDATE")).To(Succeed())
+			})
+
+			it("validates the manifest", func() {
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				service.On("ValidateManifest", ctx.Application.Path).Return(nil)
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+				_, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				service.AssertCalled(t, "ValidateManifest", ctx.Application.Path)
+			})
+
+			it("fails the build when the manifest is invalid", func() {
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				service.On("ValidateManifest", ctx.Application.Path).Return(fmt.Errorf("failed to parse manifest"))
+
+				_, err := cargoBuild.Build(ctx)
+				Expect(err).To(MatchError(ContainSubstring("unable to validate manifest\nfailed to parse manifest")))
+				service.AssertNotCalled(t, "ToolchainRequirements", mock.Anything)
+			})
+		})
+
+		context("deprecated configuration is set", func() {
+			var buf *bytes.Buffer
+
+			it.Before(func() {
+				buf = &bytes.Buffer{}
+				cargoBuild.Logger = bard.NewLogger(buf)
+
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_EXCLUDE_FOLDERS")).To(Succeed())
+				Expect(os.Unsetenv("BP_INCLUDE_FILES")).To(Succeed())
+			})
+
+			it("warns and forwards the value to the replacement", func() {
+				Expect(os.Setenv("BP_CARGO_EXCLUDE_FOLDERS", "static,templates")).To(Succeed())
+				Expect(os.Setenv("BP_INCLUDE_FILES", "assets/*")).To(Succeed())
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).IncludeFolders).To(Equal("assets/*:static:templates"))
+				Expect(buf.String()).To(ContainSubstring("`BP_CARGO_EXCLUDE_FOLDERS` has been deprecated and will be removed before the paketo-community/cargo 1.0 GA release. Use `BP_INCLUDE_FILES` instead."))
+			})
+
+			it("does not warn when only the replacement is set", func() {
+				Expect(os.Setenv("BP_INCLUDE_FILES", "assets/*")).To(Succeed())
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).IncludeFolders).To(Equal("assets/*"))
+				Expect(buf.String()).NotTo(ContainSubstring("deprecated"))
+			})
+		})
+
+		context("BP_CARGO_APP_BIN_DIR is set", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_APP_BIN_DIR")).To(Succeed())
+			})
+
+			it("passes the directory to the cargo layer", func() {
+				Expect(os.Setenv("BP_CARGO_APP_BIN_DIR", "./.bin/")).To(Succeed())
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).AppBinDir).To(Equal(".bin"))
+				Expect(result.Processes[0].Command).To(Equal("tini"))
+				Expect(result.Processes[0].Arguments).To(ContainElement(filepath.Join(ctx.Application.Path, ".bin", "app1")))
+			})
+
+			it("fails on a directory outside of the application", func() {
+				Expect(os.Setenv("BP_CARGO_APP_BIN_DIR", "../bin")).To(Succeed())
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				_, err := cargoBuild.Build(ctx)
+				Expect(err).To(MatchError(`unable to use BP_CARGO_APP_BIN_DIR="../bin", must be a directory inside the application`))
+			})
+		})
+
+		context("BP_CARGO_RESTORE_STRATEGY is set", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_RESTORE_STRATEGY")).To(Succeed())
+			})
+
+			it("passes the strategy to the cargo layer", func() {
+				Expect(os.Setenv("BP_CARGO_RESTORE_STRATEGY", "checksum")).To(Succeed())
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).RestoreStrategy).To(Equal("checksum"))
+			})
+
+			it("fails on an unknown strategy", func() {
+				Expect(os.Setenv("BP_CARGO_RESTORE_STRATEGY", "ctime")).To(Succeed())
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				_, err := cargoBuild.Build(ctx)
+				Expect(err).To(MatchError(`unable to use BP_CARGO_RESTORE_STRATEGY="ctime", must be "mtimes" or "checksum"`))
+			})
+		})
+
+		context("build target", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, ".cargo"), 0755)).To(Succeed())
+				config, err := os.ReadFile("testdata/configured-target/.cargo/config.toml")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(os.WriteFile(filepath.Join(ctx.Application.Path, ".cargo", "config.toml"), config, 0644)).To(Succeed())
+
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_TARGET")).To(Succeed())
+			})
+
+			it("uses build.target from .cargo/config.toml", func() {
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).Target).To(Equal("x86_64-unknown-linux-musl"))
+			})
+
+			it("prefers BP_CARGO_TARGET", func() {
+				Expect(os.Setenv("BP_CARGO_TARGET", "aarch64-unknown-linux-musl")).To(Succeed())
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).Target).To(Equal("aarch64-unknown-linux-musl"))
+			})
+		})
+
+		context("BP_CARGO_SCCACHE is set", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_SCCACHE")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_INSTALL_TOOLS")).To(Succeed())
+			})
+
+			it("installs sccache as a tool", func() {
+				Expect(os.Setenv("BP_CARGO_SCCACHE", "true")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_INSTALL_TOOLS", "cargo-bloat sccache")).To(Succeed())
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).Tools).To(Equal([]string{"cargo-bloat", "sccache"}))
+			})
+
+			it("adds sccache to the tools", func() {
+				Expect(os.Setenv("BP_CARGO_SCCACHE", "true")).To(Succeed())
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).Tools).To(Equal([]string{"sccache"}))
+			})
+		})
+
+		context("BP_CARGO_FETCH_RETRY is set", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_FETCH_RETRY")).To(Succeed())
+			})
+
+			it("adds a fetch phase", func() {
+				Expect(os.Setenv("BP_CARGO_FETCH_RETRY", "3")).To(Succeed())
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).Fetch).To(BeTrue())
+			})
+
+			it("fails on an invalid value", func() {
+				Expect(os.Setenv("BP_CARGO_FETCH_RETRY", "-1")).To(Succeed())
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				_, err := cargoBuild.Build(ctx)
+				Expect(err).To(MatchError(`unable to use BP_CARGO_FETCH_RETRY="-1", must be a number of retries`))
+			})
+		})
+
+		context("BP_CARGO_BIN_EXCLUDE is set", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_BIN_EXCLUDE")).To(Succeed())
+			})
+
+			it("fails on an invalid pattern", func() {
+				Expect(os.Setenv("BP_CARGO_BIN_EXCLUDE", "*-bench,[test")).To(Succeed())
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+				_, err := cargoBuild.Build(ctx)
+				Expect(err).To(MatchError(ContainSubstring("unable to parse BP_CARGO_BIN_EXCLUDE\ninvalid glob pattern \"[test\"")))
+			})
+		})
+
 		context("BP_CARGO_TINI_DISABLED is true", func() {
 			it.Before(func() {
 				Expect(os.Setenv("BP_CARGO_TINI_DISABLED", "true")).To(Succeed())
@@ -266,5 +601,200 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					}))
 			})
 		})
+		context("BP_CARGO_KEEP_SOURCE is true", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_KEEP_SOURCE", "true")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_KEEP_SOURCE")).To(Succeed())
+			})
+
+			it("keeps the source code", func() {
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).KeepSource).To(BeTrue())
+			})
+		})
+
+		context("BP_CARGO_REQUIRE_SBOM is true", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_REQUIRE_SBOM", "true")).To(Succeed())
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_REQUIRE_SBOM")).To(Succeed())
+				Expect(os.Unsetenv("BP_DISABLE_SBOM")).To(Succeed())
+			})
+
+			it("requires the SBOM in the cargo layer", func() {
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).RequireSBOM).To(BeTrue())
+			})
+
+			it("fails when the SBOM scan is disabled", func() {
+				Expect(os.Setenv("BP_DISABLE_SBOM", "true")).To(Succeed())
+
+				_, err := cargoBuild.Build(ctx)
+				Expect(err).To(MatchError(ContainSubstring("the SBOM scan is disabled by BP_DISABLE_SBOM")))
+			})
+		})
+
+		it("records the toolchain in the build BOM", func() {
+			ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+
+			service.ExpectedCalls = nil
+			service.On("Toolchain").Return("1.80.1", "1.80.0", nil)
+			service.On("ToolchainRequirements", mock.AnythingOfType("string")).Return(runner.ToolchainRequirements{}, nil)
+			service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+
+			result, err := cargoBuild.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.BOM.Entries).To(ContainElement(libcnb.BOMEntry{
+				Name:     "rust-toolchain",
+				Metadata: map[string]interface{}{"cargo-version": "1.80.1", "rust-version": "1.80.0"},
+				Build:    true,
+			}))
+		})
+
+		context("BP_CARGO_PROJECT_DIR is set", func() {
+			it.Before(func() {
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+				Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "backend"), 0755)).To(Succeed())
+				t.Setenv("BP_CARGO_PROJECT_DIR", "backend")
+			})
+
+			it("builds the project in the subdirectory", func() {
+				backend := filepath.Join(ctx.Application.Path, "backend")
+				service.On("ProjectTargets", backend).Return([]string{"app1"}, nil)
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[1].(cargo.Cache).AppPath).To(Equal(backend))
+				Expect(result.Layers[2].(cargo.Cargo).ApplicationPath).To(Equal(ctx.Application.Path))
+				Expect(result.Layers[2].(cargo.Cargo).ProjectPath).To(Equal(backend))
+				Expect(result.Processes[0].Command).To(Equal("tini"))
+				Expect(result.Processes[0].Arguments).To(Equal([]string{"-g", "--", filepath.Join(ctx.Application.Path, "bin", "app1")}))
+				service.AssertCalled(t, "ToolchainRequirements", backend)
+			})
+
+			it("fails on an absolute directory", func() {
+				t.Setenv("BP_CARGO_PROJECT_DIR", "/backend")
+
+				_, err := cargoBuild.Build(ctx)
+				Expect(err).To(MatchError(ContainSubstring("it must be relative to the application directory")))
+			})
+		})
+
+		context("BP_CARGO_SBOM_SCANNER is set", func() {
+			it.Before(func() {
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+			})
+
+			it("uses syft by default", func() {
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).SBOMScanner).To(BeAssignableToTypeOf(sbom.SyftCLISBOMScanner{}))
+				Expect(result.Layers[2].(cargo.Cargo).RunSBOMScan).To(BeTrue())
+			})
+
+			it("uses the native scanner", func() {
+				t.Setenv("BP_CARGO_SBOM_SCANNER", "native")
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).SBOMScanner).To(BeAssignableToTypeOf(cargo.CargoLockSBOMScanner{}))
+				Expect(result.Layers[2].(cargo.Cargo).RunSBOMScan).To(BeTrue())
+			})
+
+			it("skips the SBOM scan with none", func() {
+				t.Setenv("BP_CARGO_SBOM_SCANNER", "none")
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).RunSBOMScan).To(BeFalse())
+				Expect(result.Labels).To(ContainElement(libcnb.Label{Key: "io.paketo.sbom.disabled", Value: "true"}))
+			})
+
+			it("fails on an unknown scanner", func() {
+				t.Setenv("BP_CARGO_SBOM_SCANNER", "trivy")
+
+				_, err := cargoBuild.Build(ctx)
+				Expect(err).To(MatchError(ContainSubstring(`unable to use SBOM scanner "trivy"`)))
+			})
+
+			it("passes the SBOM formats", func() {
+				t.Setenv("BP_CARGO_SBOM_FORMATS", "spdx,cyclonedx")
+
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[2].(cargo.Cargo).SBOMFormats).To(Equal([]libcnb.SBOMFormat{libcnb.SPDXJSON, libcnb.CycloneDXJSON}))
+			})
+
+			it("fails on formats the native scanner does not write", func() {
+				t.Setenv("BP_CARGO_SBOM_SCANNER", "native")
+				t.Setenv("BP_CARGO_SBOM_FORMATS", "spdx")
+
+				_, err := cargoBuild.Build(ctx)
+				Expect(err).To(MatchError(ContainSubstring("the native SBOM scanner only writes cyclonedx")))
+			})
+		})
+
+		context("disable-sbom is set in Cargo.toml", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte(`
+[package]
+name = "app"
+
+[package.metadata.cargo-buildpack]
+disable-sbom = true
+`), 0644)).To(Succeed())
+
+				ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "rust-cargo"})
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"app1"}, nil)
+			})
+
+			it("disables the SBOM scan", func() {
+				result, err := cargoBuild.Build(ctx)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Labels).To(ContainElement(libcnb.Label{Key: "io.paketo.sbom.disabled", Value: "true"}))
+				Expect(result.Layers[2].(cargo.Cargo).RunSBOMScan).To(BeFalse())
+			})
+
+			context("BP_DISABLE_SBOM is false", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_DISABLE_SBOM", "false")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_DISABLE_SBOM")).To(Succeed())
+				})
+
+				it("prefers the environment", func() {
+					result, err := cargoBuild.Build(ctx)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Labels).To(BeEmpty())
+					Expect(result.Layers[2].(cargo.Cargo).RunSBOMScan).To(BeTrue())
+				})
+			})
+		})
 	})
 }
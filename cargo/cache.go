@@ -17,43 +17,322 @@
 package cargo
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/buildpacks/libcnb"
+	"github.com/heroku/color"
 	"github.com/paketo-buildpacks/libpak/bard"
 )
 
 type Cache struct {
 	Logger  bard.Logger
 	AppPath string
+
+	// CargoVersion and RustVersion are the toolchain the cache is built with, they are recorded in the layer metadata
+	CargoVersion string
+	RustVersion  string
+
+	// ClearOnToolchainChange clears the cache if it was built with a different toolchain
+	ClearOnToolchainChange bool
+
+	// LockDiff logs the changes to Cargo.lock since the previous build, Cargo.lock is kept in the layer to compare with
+	LockDiff bool
 }
 
+// PreviousLockFile is the name of the copy of Cargo.lock kept in the cache layer when LockDiff is set
+const PreviousLockFile = "Cargo.lock.previous"
+
 func (c Cache) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	if c.ClearOnToolchainChange && c.toolchainChanged(layer.Metadata) {
+		c.Logger.Bodyf("Clearing cached target directory, it was built with Rust %s and Cargo %s",
+			layer.Metadata["rust-version"], layer.Metadata["cargo-version"])
+		if err := os.RemoveAll(layer.Path); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to clear layer directory %s\n%w", layer.Path, err)
+		}
+	}
+
 	if err := os.MkdirAll(layer.Path, 0755); err != nil {
 		return libcnb.Layer{}, fmt.Errorf("unable to create layer directory %s\n%w", layer.Path, err)
 	}
 
 	targetPath := filepath.Join(c.AppPath, "target")
 
-	// delete the target if it exists as we'll never need it
-	// users shouldn't push the target folder, but it can happen
-	if err := os.RemoveAll(targetPath); err != nil {
-		return libcnb.Layer{}, fmt.Errorf("unable to delete target directory\n%w", err)
+	linked, err := c.prepareTarget(targetPath, layer.Path)
+	if err != nil {
+		return libcnb.Layer{}, err
 	}
 
 	// symlink the target folder to the cache layer, so we persist build info
-	if err := os.Symlink(layer.Path, targetPath); err != nil {
+	if linked {
+		c.Logger.Bodyf("Using cached target directory %s", targetPath)
+	} else if err := os.Symlink(layer.Path, targetPath); err != nil {
 		return libcnb.Layer{}, fmt.Errorf("unable to link cache from %s to %s\n%w", layer.Path, targetPath, err)
 	} else {
 		c.Logger.Bodyf("Creating cached target directory %s", targetPath)
 	}
 
+	if c.LockDiff {
+		if err := c.diffLock(layer.Path); err != nil {
+			return libcnb.Layer{}, err
+		}
+	}
+
+	if layer.Metadata == nil {
+		layer.Metadata = map[string]interface{}{}
+	}
+	layer.Metadata["cargo-version"] = c.CargoVersion
+	layer.Metadata["rust-version"] = c.RustVersion
+
 	layer.Cache = true
 	return layer, nil
 }
 
+// prepareTarget checks if targetPath already links to layerPath and otherwise removes it, so it can be linked. Users
+// shouldn't push the target folder, but it can happen, so a target directory with contents is removed with a warning.
+func (c Cache) prepareTarget(targetPath string, layerPath string) (bool, error) {
+	fi, err := os.Lstat(targetPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("unable to read target directory\n%w", err)
+	}
+
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if link, err := os.Readlink(targetPath); err == nil && link == layerPath {
+			return true, nil
+		}
+	} else if fi.IsDir() {
+		entries, err := os.ReadDir(targetPath)
+		if err != nil {
+			return false, fmt.Errorf("unable to read target directory\n%w", err)
+		}
+
+		if len(entries) > 0 {
+			c.Logger.Bodyf("%s: replacing %s and its contents with the cached target directory, don't include the target directory in the application",
+				color.YellowString("Warning"), targetPath)
+		}
+	}
+
+	if err := os.RemoveAll(targetPath); err != nil {
+		return false, fmt.Errorf("unable to delete target directory\n%w", err)
+	}
+
+	return false, nil
+}
+
+// diffLock logs the changes between Cargo.lock and the copy kept by the previous build, then replaces the copy
+func (c Cache) diffLock(layerPath string) error {
+	lockPath := filepath.Join(c.AppPath, "Cargo.lock")
+	previousPath := filepath.Join(layerPath, PreviousLockFile)
+
+	current, err := ParseCargoLock(lockPath)
+	if errors.Is(err, os.ErrNotExist) {
+		c.Logger.Body("No Cargo.lock, skipping dependency changes")
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to read Cargo.lock\n%w", err)
+	}
+
+	previous, err := ParseCargoLock(previousPath)
+	if errors.Is(err, os.ErrNotExist) {
+		c.Logger.Body("No Cargo.lock from a previous build, dependency changes are shown from the next build on")
+	} else if err != nil {
+		c.Logger.Bodyf("%s: unable to read Cargo.lock from the previous build, skipping dependency changes\n%s", color.YellowString("Warning"), err)
+	} else if diff := DiffCargoLocks(previous, current); diff.Empty() {
+		c.Logger.Body("Cargo.lock is unchanged since the previous build")
+	} else {
+		c.Logger.Body("Cargo.lock changes since the previous build:")
+		for _, line := range diff.Lines() {
+			c.Logger.Bodyf("  %s", line)
+		}
+	}
+
+	content, err := os.ReadFile(lockPath)
+	if err != nil {
+		return fmt.Errorf("unable to read Cargo.lock\n%w", err)
+	}
+
+	if err := os.WriteFile(previousPath, content, 0644); err != nil {
+		return fmt.Errorf("unable to write %s\n%w", previousPath, err)
+	}
+
+	return nil
+}
+
+// ParseTargetCleanPatterns parses a comma separated list of glob patterns for paths in the target directory
+func ParseTargetCleanPatterns(raw string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(raw, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("unable to use pattern %q\n%w", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// PruneTarget removes the files and directories in targetDir whose name, or path relative to targetDir, matches one of
+// patterns and returns their relative paths. `.fingerprint` directories are kept, cargo needs them to tell which
+// artifacts are up to date.
+func PruneTarget(targetDir string, patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	var removed []string
+	err := filepath.WalkDir(targetDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == targetDir {
+			return nil
+		}
+
+		if d.IsDir() && d.Name() == ".fingerprint" {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(targetDir, path)
+		if err != nil {
+			return err
+		}
+
+		for _, pattern := range patterns {
+			nameMatch, _ := filepath.Match(pattern, d.Name())
+			pathMatch, _ := filepath.Match(pattern, rel)
+			if !nameMatch && !pathMatch {
+				continue
+			}
+
+			if err := os.RemoveAll(path); err != nil {
+				return fmt.Errorf("unable to remove %s\n%w", path, err)
+			}
+			removed = append(removed, rel)
+
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("unable to prune %s\n%w", targetDir, err)
+	}
+
+	return removed, nil
+}
+
+// ParseKeepTargetPaths parses a comma separated list of paths relative to the target directory
+func ParseKeepTargetPaths(raw string) ([]string, error) {
+	var paths []string
+	for _, path := range strings.Split(raw, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+
+		if !filepath.IsLocal(path) {
+			return nil, fmt.Errorf("unable to use %q, it must be a relative path within the target directory", path)
+		}
+		paths = append(paths, filepath.Clean(path))
+	}
+	return paths, nil
+}
+
+// KeepTarget replaces the link from the target directory in appDir to the cache layer with a copy of paths, relative
+// to the target directory, so they stay in the application. The whole target directory is copied if paths is empty.
+func KeepTarget(appDir string, paths []string) error {
+	targetPath := filepath.Join(appDir, "target")
+
+	cachePath, err := os.Readlink(targetPath)
+	if err != nil {
+		return fmt.Errorf("unable to read target link\n%w", err)
+	}
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	for _, path := range paths {
+		if _, err := os.Lstat(filepath.Join(cachePath, path)); err != nil {
+			return fmt.Errorf("unable to find %s in the target directory\n%w", path, err)
+		}
+	}
+
+	if err := os.Remove(targetPath); err != nil {
+		return fmt.Errorf("unable to remove target link\n%w", err)
+	}
+
+	for _, path := range paths {
+		if err := copyTree(filepath.Join(cachePath, path), filepath.Join(targetPath, path)); err != nil {
+			return fmt.Errorf("unable to copy %s from the target directory\n%w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// copyTree copies the file or directory src to dest, keeping permissions and symlinks
+func copyTree(src string, dest string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(dest, rel)
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(destPath, 0755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+				return err
+			}
+			return os.Symlink(link, destPath)
+		default:
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+				return err
+			}
+			return copyBinary(path, destPath, info.Mode())
+		}
+	})
+}
+
+// toolchainChanged checks if the cache was built with a different toolchain, a cache from before the toolchain was
+// recorded is kept
+func (c Cache) toolchainChanged(metadata map[string]interface{}) bool {
+	for key, current := range map[string]string{"cargo-version": c.CargoVersion, "rust-version": c.RustVersion} {
+		if previous, ok := metadata[key].(string); ok && previous != current {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (Cache) Name() string {
 	return "Cargo Cache"
 }
@@ -17,12 +17,14 @@
 package cargo_test
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/buildpacks/libcnb"
 	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/paketo-community/cargo/cargo"
 	"github.com/sclevine/spec"
 )
@@ -66,4 +68,294 @@ func testCache(t *testing.T, context spec.G, it spec.S) {
 
 		Expect(os.Readlink(targetPath)).To(Equal(layer.Path))
 	})
+
+	it("keeps the symlink if it already points to the layer", func() {
+		layer, err := ctx.Layers.Layer("test-layer")
+		Expect(err).NotTo(HaveOccurred())
+
+		targetPath := filepath.Join(appDir, "target")
+		Expect(os.Symlink(layer.Path, targetPath)).To(Succeed())
+
+		buf := &bytes.Buffer{}
+		_, err = cargo.Cache{Logger: bard.NewLogger(buf), AppPath: appDir}.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.Readlink(targetPath)).To(Equal(layer.Path))
+		Expect(buf.String()).To(ContainSubstring("Using cached target directory"))
+	})
+
+	it("replaces a symlink that points elsewhere", func() {
+		layer, err := ctx.Layers.Layer("test-layer")
+		Expect(err).NotTo(HaveOccurred())
+
+		targetPath := filepath.Join(appDir, "target")
+		Expect(os.Symlink(t.TempDir(), targetPath)).To(Succeed())
+
+		_, err = cargo.Cache{AppPath: appDir}.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.Readlink(targetPath)).To(Equal(layer.Path))
+	})
+
+	it("warns before replacing a target directory with contents", func() {
+		layer, err := ctx.Layers.Layer("test-layer")
+		Expect(err).NotTo(HaveOccurred())
+
+		targetPath := filepath.Join(appDir, "target")
+		Expect(os.MkdirAll(filepath.Join(targetPath, "debug"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(targetPath, "debug", "app"), []byte{}, 0644)).To(Succeed())
+
+		buf := &bytes.Buffer{}
+		_, err = cargo.Cache{Logger: bard.NewLogger(buf), AppPath: appDir}.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.Readlink(targetPath)).To(Equal(layer.Path))
+		Expect(buf.String()).To(ContainSubstring("Warning"))
+		Expect(buf.String()).To(ContainSubstring("replacing %s and its contents", targetPath))
+	})
+
+	it("replaces an empty target directory without a warning", func() {
+		layer, err := ctx.Layers.Layer("test-layer")
+		Expect(err).NotTo(HaveOccurred())
+
+		targetPath := filepath.Join(appDir, "target")
+		Expect(os.Mkdir(targetPath, 0755)).To(Succeed())
+
+		buf := &bytes.Buffer{}
+		_, err = cargo.Cache{Logger: bard.NewLogger(buf), AppPath: appDir}.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.Readlink(targetPath)).To(Equal(layer.Path))
+		Expect(buf.String()).NotTo(ContainSubstring("Warning"))
+	})
+
+	context("toolchain changes", func() {
+		var layer libcnb.Layer
+
+		it.Before(func() {
+			var err error
+			layer, err = ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.MkdirAll(filepath.Join(layer.Path, "release"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(layer.Path, "release", "app"), []byte{}, 0644)).To(Succeed())
+		})
+
+		it("records the toolchain", func() {
+			layer, err := cargo.Cache{AppPath: appDir, CargoVersion: "1.80.0", RustVersion: "1.80.1"}.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(layer.Metadata).To(Equal(map[string]interface{}{"cargo-version": "1.80.0", "rust-version": "1.80.1"}))
+		})
+
+		it("clears the cache when the toolchain changed", func() {
+			layer.Metadata = map[string]interface{}{"cargo-version": "1.79.0", "rust-version": "1.79.0"}
+
+			layer, err := cargo.Cache{AppPath: appDir, CargoVersion: "1.80.0", RustVersion: "1.80.1", ClearOnToolchainChange: true}.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, "release", "app")).ToNot(BeAnExistingFile())
+			Expect(layer.Path).To(BeADirectory())
+			Expect(layer.Metadata).To(Equal(map[string]interface{}{"cargo-version": "1.80.0", "rust-version": "1.80.1"}))
+		})
+
+		it("keeps the cache when the toolchain is unchanged", func() {
+			layer.Metadata = map[string]interface{}{"cargo-version": "1.80.0", "rust-version": "1.80.1"}
+
+			layer, err := cargo.Cache{AppPath: appDir, CargoVersion: "1.80.0", RustVersion: "1.80.1", ClearOnToolchainChange: true}.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, "release", "app")).To(BeARegularFile())
+		})
+
+		it("keeps a cache without a recorded toolchain", func() {
+			layer, err := cargo.Cache{AppPath: appDir, CargoVersion: "1.80.0", RustVersion: "1.80.1", ClearOnToolchainChange: true}.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, "release", "app")).To(BeARegularFile())
+		})
+
+		it("keeps the cache when clearing is disabled", func() {
+			layer.Metadata = map[string]interface{}{"cargo-version": "1.79.0", "rust-version": "1.79.0"}
+
+			layer, err := cargo.Cache{AppPath: appDir, CargoVersion: "1.80.0", RustVersion: "1.80.1"}.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, "release", "app")).To(BeARegularFile())
+		})
+	})
+
+	context("with BP_CARGO_LOCK_DIFF", func() {
+		var (
+			buf   *bytes.Buffer
+			layer libcnb.Layer
+		)
+
+		writeLock := func(path string, content string) {
+			Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		}
+
+		it.Before(func() {
+			var err error
+			layer, err = ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			buf = &bytes.Buffer{}
+			writeLock(filepath.Join(appDir, "Cargo.lock"), `version = 3
+
+[[package]]
+name = "serde"
+version = "1.0.200"
+
+[[package]]
+name = "tokio"
+version = "1.38.0"
+`)
+		})
+
+		it("logs the changes since the previous build", func() {
+			Expect(os.MkdirAll(layer.Path, 0755)).To(Succeed())
+			writeLock(filepath.Join(layer.Path, cargo.PreviousLockFile), `version = 3
+
+[[package]]
+name = "serde"
+version = "1.0.190"
+
+[[package]]
+name = "log"
+version = "0.4.21"
+`)
+
+			_, err := cargo.Cache{Logger: bard.NewLogger(buf), AppPath: appDir, LockDiff: true}.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buf.String()).To(ContainSubstring("Cargo.lock changes since the previous build:"))
+			Expect(buf.String()).To(ContainSubstring("+ tokio 1.38.0"))
+			Expect(buf.String()).To(ContainSubstring("~ serde 1.0.190 -> 1.0.200"))
+			Expect(buf.String()).To(ContainSubstring("- log 0.4.21"))
+			Expect(os.ReadFile(filepath.Join(layer.Path, cargo.PreviousLockFile))).To(ContainSubstring("1.38.0"))
+		})
+
+		it("stores Cargo.lock for the next build", func() {
+			_, err := cargo.Cache{Logger: bard.NewLogger(buf), AppPath: appDir, LockDiff: true}.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buf.String()).To(ContainSubstring("No Cargo.lock from a previous build"))
+			Expect(filepath.Join(layer.Path, cargo.PreviousLockFile)).To(BeARegularFile())
+
+			buf.Reset()
+			_, err = cargo.Cache{Logger: bard.NewLogger(buf), AppPath: appDir, LockDiff: true}.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf.String()).To(ContainSubstring("Cargo.lock is unchanged since the previous build"))
+		})
+
+		it("does not store Cargo.lock when disabled", func() {
+			_, err := cargo.Cache{Logger: bard.NewLogger(buf), AppPath: appDir}.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, cargo.PreviousLockFile)).NotTo(BeAnExistingFile())
+		})
+	})
+
+	context("pruning the target directory", func() {
+		var targetDir string
+
+		it.Before(func() {
+			targetDir = t.TempDir()
+
+			for _, path := range []string{
+				"release/app",
+				"release/deps/libserde-1234.rlib",
+				"release/deps/libserde-1234.rlib.bak",
+				"release/incremental/app-1/s-1/query-cache.bin",
+				"release/build/openssl-sys-1/output",
+				"release/.fingerprint/incremental-1/lib-incremental",
+				"debug/incremental/app-2/dep-graph.bin",
+			} {
+				Expect(os.MkdirAll(filepath.Join(targetDir, filepath.Dir(path)), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(targetDir, path), []byte{}, 0644)).To(Succeed())
+			}
+		})
+
+		it("parses the patterns", func() {
+			Expect(cargo.ParseTargetCleanPatterns(" incremental, *.rlib.bak ,,")).To(Equal([]string{"incremental", "*.rlib.bak"}))
+			Expect(cargo.ParseTargetCleanPatterns("")).To(BeEmpty())
+
+			_, err := cargo.ParseTargetCleanPatterns("[incremental")
+			Expect(err).To(MatchError(ContainSubstring(`unable to use pattern "[incremental"`)))
+		})
+
+		it("removes matching paths and keeps the others", func() {
+			removed, err := cargo.PruneTarget(targetDir, []string{"incremental", "*.rlib.bak", "release/build"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(ConsistOf(
+				"debug/incremental",
+				"release/build",
+				"release/deps/libserde-1234.rlib.bak",
+				"release/incremental",
+			))
+
+			Expect(filepath.Join(targetDir, "release", "incremental")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(targetDir, "debug", "incremental")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(targetDir, "release", "build")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(targetDir, "release", "deps", "libserde-1234.rlib.bak")).NotTo(BeAnExistingFile())
+
+			Expect(filepath.Join(targetDir, "release", "app")).To(BeARegularFile())
+			Expect(filepath.Join(targetDir, "release", "deps", "libserde-1234.rlib")).To(BeARegularFile())
+			Expect(filepath.Join(targetDir, "release", ".fingerprint", "incremental-1", "lib-incremental")).To(BeARegularFile())
+		})
+
+		it("parses the paths to keep", func() {
+			Expect(cargo.ParseKeepTargetPaths(" release/bindings, generated/ ,,")).To(Equal([]string{"release/bindings", "generated"}))
+			Expect(cargo.ParseKeepTargetPaths("")).To(BeEmpty())
+
+			_, err := cargo.ParseKeepTargetPaths("../secrets")
+			Expect(err).To(MatchError(ContainSubstring(`unable to use "../secrets"`)))
+			_, err = cargo.ParseKeepTargetPaths("/etc")
+			Expect(err).To(MatchError(ContainSubstring(`unable to use "/etc"`)))
+		})
+
+		it("copies the kept paths into the application", func() {
+			Expect(os.Symlink(targetDir, filepath.Join(appDir, "target"))).To(Succeed())
+
+			Expect(cargo.KeepTarget(appDir, []string{"release/deps", "release/app"})).To(Succeed())
+
+			fi, err := os.Lstat(filepath.Join(appDir, "target"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fi.IsDir()).To(BeTrue())
+
+			Expect(filepath.Join(appDir, "target", "release", "app")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "target", "release", "deps", "libserde-1234.rlib")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "target", "release", "build")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "target", "debug")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(targetDir, "release", "build", "openssl-sys-1", "output")).To(BeARegularFile())
+		})
+
+		it("copies the whole target directory without paths", func() {
+			Expect(os.Symlink(targetDir, filepath.Join(appDir, "target"))).To(Succeed())
+
+			Expect(cargo.KeepTarget(appDir, nil)).To(Succeed())
+
+			Expect(filepath.Join(appDir, "target", "debug", "incremental", "app-2", "dep-graph.bin")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "target", "release", "app")).To(BeARegularFile())
+		})
+
+		it("fails if a kept path does not exist", func() {
+			Expect(os.Symlink(targetDir, filepath.Join(appDir, "target"))).To(Succeed())
+
+			err := cargo.KeepTarget(appDir, []string{"release/missing"})
+			Expect(err).To(MatchError(ContainSubstring("unable to find release/missing in the target directory")))
+
+			link, err := os.Readlink(filepath.Join(appDir, "target"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal(targetDir))
+		})
+
+		it("removes nothing without patterns", func() {
+			removed, err := cargo.PruneTarget(targetDir, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(BeEmpty())
+			Expect(filepath.Join(targetDir, "release", "incremental")).To(BeADirectory())
+		})
+	})
 }
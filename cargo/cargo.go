@@ -17,21 +17,35 @@
 package cargo
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/buildpacks/libcnb"
+	"github.com/heroku/color"
+	"github.com/mattn/go-shellwords"
 	"github.com/paketo-buildpacks/libpak"
 	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/paketo-buildpacks/libpak/sbom"
 	"github.com/paketo-buildpacks/libpak/sherpa"
-	"github.com/paketo-buildpacks/source-removal/logic"
 	"github.com/paketo-community/cargo/mtimes"
 	"github.com/paketo-community/cargo/runner"
 )
 
+// ErrCargoHomeNotSet is returned when the location of CARGO_HOME is unknown
+var ErrCargoHomeNotSet = errors.New("unable to find CARGO_HOME, it must be set")
+
+const (
+	PathStrategyAppend  = "append"
+	PathStrategyPrepend = "prepend"
+	PathStrategyNone    = "none"
+)
+
 // Option is a function for configuring a Cargo
 type Option func(cargo Cargo) Cargo
 
@@ -43,6 +57,14 @@ func WithAdditionalMetadata(metadata map[string]interface{}) Option {
 	}
 }
 
+// WithAppBinDir sets the directory, relative to the application path, into which binaries are linked
+func WithAppBinDir(dir string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.AppBinDir = dir
+		return cargo
+	}
+}
+
 // WithApplicationPath sets app path
 func WithApplicationPath(ap string) Option {
 	return func(cargo Cargo) Cargo {
@@ -51,6 +73,30 @@ func WithApplicationPath(ap string) Option {
 	}
 }
 
+// WithBinaryChecksums sets if a `<bin>.sha256` file is written next to every installed binary
+func WithBinaryChecksums(checksums bool) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.BinaryChecksums = checksums
+		return cargo
+	}
+}
+
+// WithBins sets the binary targets which are installed, a different selection rebuilds the layer
+func WithBins(bins []string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.Bins = bins
+		return cargo
+	}
+}
+
+// WithCargoHome sets the location of CARGO_HOME, which is restored and preserved between builds
+func WithCargoHome(cargoHome string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.CargoHome = cargoHome
+		return cargo
+	}
+}
+
 // WithCargoService sets cargo service
 func WithCargoService(s runner.CargoService) Option {
 	return func(cargo Cargo) Cargo {
@@ -59,7 +105,23 @@ func WithCargoService(s runner.CargoService) Option {
 	}
 }
 
-// WithIncludeFolders sets logger
+// WithFeatures sets the features which are enabled for `cargo install`
+func WithFeatures(features string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.Features = features
+		return cargo
+	}
+}
+
+// WithFetch sets if dependencies are downloaded with `cargo fetch` before installing
+func WithFetch(fetch bool) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.Fetch = fetch
+		return cargo
+	}
+}
+
+// WithIncludeFolders sets the colon separated glob patterns of source files which are kept when the source is removed
 func WithIncludeFolders(f string) Option {
 	return func(cargo Cargo) Cargo {
 		cargo.IncludeFolders = f
@@ -67,7 +129,39 @@ func WithIncludeFolders(f string) Option {
 	}
 }
 
-// WithExcludeFolders sets logger
+// WithClock sets the clock used to time the phases of the build
+func WithClock(clock Clock) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.Clock = clock
+		return cargo
+	}
+}
+
+// WithCopyBinaries sets if binaries are copied into the application directory instead of being symlinked
+func WithCopyBinaries(copyBinaries bool) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.CopyBinaries = copyBinaries
+		return cargo
+	}
+}
+
+// WithDefaultProcess sets the name of the binary target to use as default process type
+func WithDefaultProcess(name string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.DefaultProcess = name
+		return cargo
+	}
+}
+
+// WithEvents sets where key build events are written, events are not written if nil
+func WithEvents(events *Events) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.Events = events
+		return cargo
+	}
+}
+
+// WithExcludeFolders sets the colon separated glob patterns of source files which are removed, even if they are included
 func WithExcludeFolders(f string) Option {
 	return func(cargo Cargo) Cargo {
 		cargo.ExcludeFolders = f
@@ -75,6 +169,14 @@ func WithExcludeFolders(f string) Option {
 	}
 }
 
+// WithIncludeExamples sets if examples become process types
+func WithIncludeExamples(includeExamples bool) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.IncludeExamples = includeExamples
+		return cargo
+	}
+}
+
 // WithInstallArgs sets install args
 func WithInstallArgs(args string) Option {
 	return func(cargo Cargo) Cargo {
@@ -83,6 +185,38 @@ func WithInstallArgs(args string) Option {
 	}
 }
 
+// WithInstallArgsFile sets the file of additional install args, which follow those of WithInstallArgs
+func WithInstallArgsFile(path string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.InstallArgsFile = path
+		return cargo
+	}
+}
+
+// WithKeepSource sets if the source code is kept in the application directory instead of being removed
+func WithKeepSource(keepSource bool) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.KeepSource = keepSource
+		return cargo
+	}
+}
+
+// WithKeepTarget sets if paths of the target directory are copied into the application
+func WithKeepTarget(keepTarget bool) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.KeepTarget = keepTarget
+		return cargo
+	}
+}
+
+// WithKeepTargetPaths sets the paths, relative to the target directory, which are copied into the application
+func WithKeepTargetPaths(paths []string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.KeepTargetPaths = paths
+		return cargo
+	}
+}
+
 // WithLogger sets logger
 func WithLogger(l bard.Logger) Option {
 	return func(cargo Cargo) Cargo {
@@ -91,6 +225,95 @@ func WithLogger(l bard.Logger) Option {
 	}
 }
 
+// WithMemberFeatures sets the features enabled per workspace member
+func WithMemberFeatures(features string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.MemberFeatures = features
+		return cargo
+	}
+}
+
+// WithPathStrategy sets how the application binaries are added to the launch PATH
+func WithPathStrategy(strategy string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.PathStrategy = strategy
+		return cargo
+	}
+}
+
+// WithPreBuild sets a shell command which runs in the application before it is built
+func WithPreBuild(command string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.PreBuild = command
+		return cargo
+	}
+}
+
+// WithProcessArgs sets the arguments of process types, by process type
+func WithProcessArgs(args map[string][]string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.ProcessArgs = args
+		return cargo
+	}
+}
+
+// WithProcessEnv sets the launch environment variables of process types, by process type
+func WithProcessEnv(env map[string]map[string]string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.ProcessEnv = env
+		return cargo
+	}
+}
+
+// WithProcessMembers sets a comma separated list of workspace members whose binaries become process types
+func WithProcessMembers(members string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.ProcessMembers = members
+		return cargo
+	}
+}
+
+// WithProjectPath sets the directory of the Rust project, which is the application path unless the project is in a
+// subdirectory
+func WithProjectPath(path string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.ProjectPath = path
+		return cargo
+	}
+}
+
+// WithPruneSources sets if extracted crate sources which recent builds did not use are pruned after installing
+func WithPruneSources(prune bool) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.PruneSources = prune
+		return cargo
+	}
+}
+
+// WithRequireSBOM sets if the build fails when the SBOM scan does not write an SBOM
+func WithRequireSBOM(require bool) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.RequireSBOM = require
+		return cargo
+	}
+}
+
+// WithRestoreStrategy sets how file modification times are restored between builds
+func WithRestoreStrategy(strategy string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.RestoreStrategy = strategy
+		return cargo
+	}
+}
+
+// WithRunTests sets if `cargo test` runs before the application is built
+func WithRunTests(run bool) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.RunTests = run
+		return cargo
+	}
+}
+
 // WithRunSBOMScan sets workspace members
 func WithRunSBOMScan(sc bool) Option {
 	return func(cargo Cargo) Cargo {
@@ -99,6 +322,14 @@ func WithRunSBOMScan(sc bool) Option {
 	}
 }
 
+// WithSBOMFormats sets the SBOM formats the layer is scanned for
+func WithSBOMFormats(formats []libcnb.SBOMFormat) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.SBOMFormats = formats
+		return cargo
+	}
+}
+
 // WithSBOMScanner sets workspace members
 func WithSBOMScanner(sc sbom.SBOMScanner) Option {
 	return func(cargo Cargo) Cargo {
@@ -107,6 +338,14 @@ func WithSBOMScanner(sc sbom.SBOMScanner) Option {
 	}
 }
 
+// WithSpans sets where the phases of the build are recorded, spans are not recorded if nil
+func WithSpans(spans *Spans) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.Spans = spans
+		return cargo
+	}
+}
+
 // WithStack sets logger
 func WithStack(stack string) Option {
 	return func(cargo Cargo) Cargo {
@@ -115,6 +354,30 @@ func WithStack(stack string) Option {
 	}
 }
 
+// WithStripArgs sets the arguments `strip` is run with on the installed binaries, nothing is stripped if not set
+func WithStripArgs(args []string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.StripArgs = args
+		return cargo
+	}
+}
+
+// WithTarget sets the target triple to build for
+func WithTarget(target string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.Target = target
+		return cargo
+	}
+}
+
+// WithTargetCleanPatterns sets the patterns of the paths pruned from the target directory after the build
+func WithTargetCleanPatterns(patterns []string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.TargetCleanPatterns = patterns
+		return cargo
+	}
+}
+
 // WithTools sets logger
 func WithTools(tools []string) Option {
 	return func(cargo Cargo) Cargo {
@@ -131,6 +394,46 @@ func WithToolsArgs(toolsArgs []string) Option {
 	}
 }
 
+// WithToolVersions sets the versions tools are pinned to
+func WithToolVersions(versions map[string]string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.ToolVersions = versions
+		return cargo
+	}
+}
+
+// WithVerifyBinaries sets if the installed binaries are run to verify that they still execute after stripping
+func WithVerifyBinaries(verify bool) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.VerifyBinaries = verify
+		return cargo
+	}
+}
+
+// WithWebArgs sets the arguments added to the default process type, after its process arguments
+func WithWebArgs(args []string) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.WebArgs = args
+		return cargo
+	}
+}
+
+// WithWorkerMode sets worker mode, which picks the default process type deterministically
+func WithWorkerMode(workerMode bool) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.WorkerMode = workerMode
+		return cargo
+	}
+}
+
+// WithWriteProcfile sets if the process types are written to a Procfile in the application directory
+func WithWriteProcfile(writeProcfile bool) Option {
+	return func(cargo Cargo) Cargo {
+		cargo.WriteProcfile = writeProcfile
+		return cargo
+	}
+}
+
 // WithWorkspaceMembers sets workspace members
 func WithWorkspaceMembers(ap string) Option {
 	return func(cargo Cargo) Cargo {
@@ -140,21 +443,61 @@ func WithWorkspaceMembers(ap string) Option {
 }
 
 type Cargo struct {
-	AdditionalMetadata map[string]interface{}
-	ApplicationPath    string
-	Cache              Cache
-	CargoService       runner.CargoService
-	IncludeFolders     string
-	ExcludeFolders     string
-	InstallArgs        string
-	LayerContributor   libpak.LayerContributor
-	Logger             bard.Logger
-	RunSBOMScan        bool
-	SBOMScanner        sbom.SBOMScanner
-	Stack              string
-	Tools              []string
-	ToolsArgs          []string
-	WorkspaceMembers   string
+	AdditionalMetadata  map[string]interface{}
+	AppBinDir           string
+	ApplicationPath     string
+	BinaryChecksums     bool
+	Bins                []string
+	Cache               Cache
+	CargoHome           string
+	CargoService        runner.CargoService
+	CargoVersion        string
+	Clock               Clock
+	CopyBinaries        bool
+	DefaultProcess      string
+	Events              *Events
+	IncludeFolders      string
+	ExcludeFolders      string
+	Features            string
+	Fetch               bool
+	IncludeExamples     bool
+	InstallArgs         string
+	InstallArgsFile     string
+	KeepSource          bool
+	KeepTarget          bool
+	KeepTargetPaths     []string
+	LayerContributor    libpak.LayerContributor
+	Logger              bard.Logger
+	MemberFeatures      string
+	PathStrategy        string
+	PreBuild            string
+	ProcessArgs         map[string][]string
+	ProcessEnv          map[string]map[string]string
+	ProcessMembers      string
+	Processes           []libcnb.Process
+	ProjectPath         string
+	PruneSources        bool
+	QualifiedBinaries   map[string]map[string]string
+	RequireSBOM         bool
+	RestoreStrategy     string
+	RunSBOMScan         bool
+	RunTests            bool
+	RustVersion         string
+	SBOMFormats         []libcnb.SBOMFormat
+	SBOMScanner         sbom.SBOMScanner
+	Spans               *Spans
+	Stack               string
+	StripArgs           []string
+	Target              string
+	TargetCleanPatterns []string
+	Tools               []string
+	ToolsArgs           []string
+	ToolVersions        map[string]string
+	VerifyBinaries      bool
+	WebArgs             []string
+	WorkerMode          bool
+	WorkspaceMembers    string
+	WriteProcfile       bool
 }
 
 // NewCargo creates a new cargo with the given options
@@ -165,6 +508,15 @@ func NewCargo(options ...Option) (Cargo, error) {
 		cargo = option(cargo)
 	}
 
+	switch cargo.PathStrategy {
+	case "":
+		cargo.PathStrategy = PathStrategyAppend
+	case PathStrategyAppend, PathStrategyPrepend, PathStrategyNone:
+	default:
+		return Cargo{}, fmt.Errorf("unable to use PATH strategy %q, must be %q, %q or %q",
+			cargo.PathStrategy, PathStrategyAppend, PathStrategyPrepend, PathStrategyNone)
+	}
+
 	metadata := map[string]interface{}{
 		"additional-arguments": cargo.InstallArgs,
 		"stack":                cargo.Stack,
@@ -173,20 +525,76 @@ func NewCargo(options ...Option) (Cargo, error) {
 		"workspace-members":    cargo.WorkspaceMembers,
 	}
 
+	if cargo.Target != "" {
+		metadata["target"] = cargo.Target
+	}
+
+	if cargo.BinaryChecksums {
+		metadata["binary-checksums"] = true
+	}
+
+	if len(cargo.Bins) > 0 {
+		metadata["bins"] = cargo.Bins
+	}
+
+	if cargo.Features != "" {
+		metadata["features"] = cargo.Features
+	}
+
+	if cargo.MemberFeatures != "" {
+		metadata["member-features"] = cargo.MemberFeatures
+	}
+
+	if cargo.PathStrategy != PathStrategyAppend {
+		metadata["path-strategy"] = cargo.PathStrategy
+	}
+
+	if len(cargo.SBOMFormats) > 0 {
+		var formats []string
+		for _, format := range cargo.SBOMFormats {
+			formats = append(formats, format.MediaType())
+		}
+		metadata["sbom-formats"] = formats
+	}
+
+	if cargo.PreBuild != "" {
+		metadata["pre-build"] = cargo.PreBuild
+	}
+
+	if cargo.RunTests {
+		metadata["run-tests"] = true
+	}
+
+	if len(cargo.StripArgs) > 0 {
+		metadata["strip-args"] = cargo.StripArgs
+	}
+
+	if len(cargo.ToolVersions) > 0 {
+		metadata["tool-versions"] = cargo.ToolVersions
+	}
+
 	var err error
 	metadata["files"], err = sherpa.NewFileListingHash(cargo.ApplicationPath)
 	if err != nil {
 		return Cargo{}, fmt.Errorf("unable to create file listing for %s\n%w", cargo.ApplicationPath, err)
 	}
 
-	metadata["cargo-version"], err = cargo.CargoService.CargoVersion()
+	cargo.CargoVersion, cargo.RustVersion, err = cargo.CargoService.Toolchain()
 	if err != nil {
-		return Cargo{}, fmt.Errorf("unable to determine cargo version\n%w", err)
+		return Cargo{}, fmt.Errorf("unable to determine toolchain versions\n%w", err)
 	}
+	metadata["cargo-version"] = cargo.CargoVersion
+	metadata["rust-version"] = cargo.RustVersion
 
-	metadata["rust-version"], err = cargo.CargoService.RustVersion()
+	requirements, err := cargo.CargoService.ToolchainRequirements(cargo.projectPath())
 	if err != nil {
-		return Cargo{}, fmt.Errorf("unable to determine rust version\n%w", err)
+		return Cargo{}, fmt.Errorf("unable to determine toolchain requirements\n%w", err)
+	}
+	if len(requirements.Editions) > 0 {
+		metadata["editions"] = requirements.Editions
+	}
+	if requirements.MSRV != "" {
+		metadata["msrv"] = requirements.MSRV
 	}
 
 	for k, v := range cargo.AdditionalMetadata {
@@ -203,34 +611,81 @@ func NewCargo(options ...Option) (Cargo, error) {
 }
 
 func (c Cargo) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	// the warnings are only recorded, a different count from the previous build must not cause a rebuild
+	warnings, hasWarnings := layer.Metadata["compile-warnings"]
+	delete(layer.Metadata, "compile-warnings")
+	// the binaries are recorded again from the layer after it is contributed
+	delete(layer.Metadata, "binaries")
+	contributed := false
+
 	layer, err := c.LayerContributor.Contribute(layer, func() (libcnb.Layer, error) {
+		contributed = true
+
 		preserver := mtimes.NewPreserver(c.Logger)
+		preserver.Strategy = c.RestoreStrategy
 
-		targetPath, err := os.Readlink(filepath.Join(c.ApplicationPath, "target"))
+		targetPath, err := readTargetLink(filepath.Join(c.projectPath(), "target"))
 		if err != nil {
-			return libcnb.Layer{}, fmt.Errorf("unable to read target link\n%w", err)
+			return libcnb.Layer{}, err
 		}
 
-		cargoHome, found := os.LookupEnv("CARGO_HOME")
-		if !found {
-			return libcnb.Layer{}, fmt.Errorf("unable to find CARGO_HOME, it must be set")
+		// the cache layer links the target directory to itself
+		cachePath := filepath.Join(filepath.Dir(layer.Path), c.Cache.Name())
+		if filepath.Clean(targetPath) != cachePath && !isWithin(cachePath, targetPath) {
+			return libcnb.Layer{}, fmt.Errorf("target link points to %s which is outside of the cache layer %s, remove %s and try again",
+				targetPath, cachePath, filepath.Join(c.projectPath(), "target"))
+		}
+
+		if c.CargoHome == "" {
+			return libcnb.Layer{}, ErrCargoHomeNotSet
 		}
 
 		if err := os.Setenv("CARGO_REGISTRIES_CRATES_IO_PROTOCOL", "sparse"); err != nil {
 			return libcnb.Layer{}, fmt.Errorf("unable to set CARGO_REGISTRIES_CRATES_IO_PROTOCOL\n%w", err)
 		}
 
-		if err = preserver.RestoreAll(targetPath, cargoHome, layer.Path); err != nil {
+		if err = preserver.RestoreAll(targetPath, c.CargoHome, layer.Path); err != nil {
 			return libcnb.Layer{}, fmt.Errorf("unable to restore all\n%w", err)
 		}
 
 		for _, tool := range c.Tools {
-			if err := c.CargoService.InstallTool(tool, c.ToolsArgs); err != nil {
-				return libcnb.Layer{}, fmt.Errorf("unable to install tool %s with args %v\n%w", tool, c.ToolsArgs, err)
+			args := c.ToolsArgs
+			if version, ok := c.ToolVersions[tool]; ok {
+				args = append([]string{"--version", version}, c.ToolsArgs...)
+			}
+
+			if err := c.CargoService.InstallTool(tool, args); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to install tool %s with args %v\n%w", tool, args, err)
 			}
 		}
 
-		members, err := c.CargoService.WorkspaceMembers(c.ApplicationPath, layer)
+		if c.Fetch {
+			end := c.Spans.Start("fetch", nil)
+			if err := c.CargoService.Fetch(c.projectPath()); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to fetch dependencies\n%w", err)
+			}
+			end()
+		}
+
+		// runs after the cache is restored and the tools are installed, so generated code lands in the source tree
+		if c.PreBuild != "" {
+			end := c.Spans.Start("pre-build", nil)
+			if err := c.CargoService.PreBuild(c.projectPath(), c.PreBuild); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to run BP_CARGO_PRE_BUILD\n%w", err)
+			}
+			end()
+		}
+
+		// the tests are built in the linked target directory, with the code generated by the pre-build command
+		if c.RunTests {
+			end := c.Spans.Start("test", nil)
+			if err := c.CargoService.Test(c.projectPath()); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to pass cargo test\n%w", err)
+			}
+			end()
+		}
+
+		members, err := c.CargoService.WorkspaceMembers(c.projectPath(), layer)
 		if err != nil {
 			return libcnb.Layer{}, fmt.Errorf("unable to fetch members\n%w", err)
 		}
@@ -240,36 +695,119 @@ func (c Cargo) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
 			return libcnb.Layer{}, fmt.Errorf("unable to check if path set\n%w", err)
 		}
 
+		compileStart := c.Clock.Now()
 		if len(members) == 0 {
 			c.Logger.Body("WARNING: no members detected, trying to install with no path. This may fail.")
 			// run `cargo install`
-			err = c.CargoService.Install(c.ApplicationPath, layer)
+			end := c.Spans.Start("install", map[string]string{"member": c.projectPath()})
+			err = c.CargoService.Install(c.projectPath(), layer)
 			if err != nil {
 				return libcnb.Layer{}, fmt.Errorf("unable to install default\n%w", err)
 			}
-		} else if (len(members) == 1 && members[0].Path == c.ApplicationPath) || isPathSet {
+			end()
+		} else if (len(members) == 1 && members[0].Path == c.projectPath()) || isPathSet {
 			// run `cargo install`
-			err = c.CargoService.Install(c.ApplicationPath, layer)
+			end := c.Spans.Start("install", map[string]string{"member": c.projectPath()})
+			err = c.CargoService.Install(c.projectPath(), layer)
 			if err != nil {
 				return libcnb.Layer{}, fmt.Errorf("unable to install single\n%w", err)
 			}
+			end()
 		} else { // if len(members) > 1 and --path not set
 			// run `cargo install --path=` for each member in the workspace
 			for _, member := range members {
-				err = c.CargoService.InstallMember(member.Path, c.ApplicationPath, layer)
+				end := c.Spans.Start("install", map[string]string{"member": member.Path})
+				err = c.CargoService.InstallMember(member.Path, c.projectPath(), layer)
 				if err != nil {
 					return libcnb.Layer{}, fmt.Errorf("unable to install member\n%w", err)
 				}
+
+				// binaries which other members have too are renamed before the next install overwrites them
+				if err := qualifyBinaries(filepath.Join(layer.Path, "bin"), c.QualifiedBinaries[member.Path]); err != nil {
+					return libcnb.Layer{}, fmt.Errorf("unable to rename binaries of member\n%w", err)
+				}
+				end()
+				c.Events.Emit("member-built", map[string]interface{}{"member": member.Path})
+			}
+		}
+		compileTime := c.Clock.Now().Sub(compileStart)
+		c.Logger.Bodyf("Compiled in %s", compileTime.Round(time.Second))
+		c.Events.Emit("compiled", map[string]interface{}{"duration-ms": compileTime.Milliseconds()})
+
+		warnings = c.CargoService.CompileWarnings()
+
+		if len(c.StripArgs) > 0 {
+			if err := c.CargoService.StripBinaries(filepath.Join(layer.Path, "bin")); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to strip binaries\n%w", err)
+			}
+		}
+
+		if c.VerifyBinaries {
+			if err := c.CargoService.VerifyBinaries(filepath.Join(layer.Path, "bin")); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to verify binaries\n%w", err)
+			}
+		}
+
+		checksums, err := BinaryChecksums(filepath.Join(layer.Path, "bin"))
+		if err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to calculate binary checksums\n%w", err)
+		}
+
+		c.Logger.Body("Binary checksums:")
+		for _, checksum := range checksums {
+			c.Logger.Bodyf("  sha256:%s  %s", checksum.Checksum, checksum.Name)
+		}
+
+		if c.BinaryChecksums {
+			if err := WriteChecksumFiles(filepath.Join(layer.Path, ChecksumsDir), checksums); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to write binary checksums\n%w", err)
+			}
+		}
+
+		if c.PruneSources {
+			if err := c.CargoService.PruneRegistrySources(c.projectPath()); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to prune registry sources\n%w", err)
 			}
 		}
 
 		if c.RunSBOMScan {
-			if err := c.SBOMScanner.ScanLayer(layer, c.ApplicationPath, libcnb.CycloneDXJSON, libcnb.SyftJSON); err != nil {
+			end := c.Spans.Start("sbom-scan", nil)
+			formats := c.sbomFormats()
+			if err := c.SBOMScanner.ScanLayer(layer, c.projectPath(), formats...); err != nil {
 				return libcnb.Layer{}, fmt.Errorf("unable to create layer %s SBoM \n%w", layer.Name, err)
 			}
+
+			if c.RequireSBOM {
+				if err := checkSBOM(layer, formats); err != nil {
+					return libcnb.Layer{}, fmt.Errorf("unable to create layer %s SBoM, BP_CARGO_REQUIRE_SBOM is set\n%w", layer.Name, err)
+				}
+			}
+
+			// the Cargo.lock dependencies and the toolchain are only added to the CycloneDX SBOM
+			if slices.Contains(formats, libcnb.CycloneDXJSON) {
+				lockPath := filepath.Join(c.projectPath(), "Cargo.lock")
+				if _, err := os.Stat(lockPath); err == nil {
+					if err := WriteCargoLockSBOM(lockPath, layer.SBOMPath(libcnb.CycloneDXJSON)); err != nil {
+						return libcnb.Layer{}, fmt.Errorf("unable to add Cargo.lock dependencies to layer %s SBoM\n%w", layer.Name, err)
+					}
+				}
+
+				if err := AddSBOMComponents(layer.SBOMPath(libcnb.CycloneDXJSON), ToolchainComponents(c.RustVersion, c.CargoVersion)); err != nil {
+					return libcnb.Layer{}, fmt.Errorf("unable to add Rust toolchain to layer %s SBoM\n%w", layer.Name, err)
+				}
+			}
+			end()
 		}
 
-		err = preserver.PreserveAll(targetPath, cargoHome, layer.Path)
+		if len(c.TargetCleanPatterns) > 0 {
+			removed, err := PruneTarget(targetPath, c.TargetCleanPatterns)
+			if err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to prune target directory\n%w", err)
+			}
+			c.Logger.Bodyf("Pruned %d paths from the target directory", len(removed))
+		}
+
+		err = preserver.PreserveAll(targetPath, c.CargoHome, layer.Path)
 		if err != nil {
 			return libcnb.Layer{}, fmt.Errorf("unable to preserve all\n%w", err)
 		}
@@ -280,50 +818,216 @@ func (c Cargo) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
 		return libcnb.Layer{}, fmt.Errorf("unable to contribute application layer\n%w", err)
 	}
 
-	c.Logger.Header("Removing source code")
-	err = logic.Include(c.ApplicationPath, c.IncludeFolders)
-	if err != nil {
-		return libcnb.Layer{}, err
+	if contributed || hasWarnings {
+		if layer.Metadata == nil {
+			layer.Metadata = map[string]interface{}{}
+		}
+		layer.Metadata["compile-warnings"] = warnings
 	}
 
-	err = logic.Exclude(c.ApplicationPath, c.ExcludeFolders)
-	if err != nil {
-		return libcnb.Layer{}, err
+	if c.KeepTarget {
+		if err := KeepTarget(c.projectPath(), c.KeepTargetPaths); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to keep the target directory, BP_CARGO_KEEP_TARGET is set\n%w", err)
+		}
+		c.Logger.Bodyf("Copied the kept target directory paths into %s", filepath.Join(c.projectPath(), "target"))
 	}
 
-	if err := os.MkdirAll(filepath.Join(c.ApplicationPath, "bin"), 0755); err != nil {
-		return libcnb.Layer{}, fmt.Errorf("unable make app path %s/bin\n%w", c.ApplicationPath, err)
+	if c.KeepSource {
+		c.Logger.Header("Keeping source code")
+	} else {
+		c.Logger.Header("Removing source code")
+		end := c.Spans.Start("cleanup", nil)
+		ignore, err := ReadIgnoreFile(c.ApplicationPath)
+		if err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to read %s\n%w", IgnoreFile, err)
+		}
+		if len(ignore) > 0 {
+			c.Logger.Bodyf("Using the patterns from %s", IgnoreFile)
+		}
+
+		include := c.IncludeFolders
+		if c.KeepTarget {
+			target, err := filepath.Rel(c.ApplicationPath, filepath.Join(c.projectPath(), "target"))
+			if err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to find the target directory in %s\n%w", c.ApplicationPath, err)
+			}
+			include = joinPatterns(include, target)
+		}
+		if err := RemoveSource(c.ApplicationPath, include, c.ExcludeFolders, ignore); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to remove source code\n%w", err)
+		}
+		end()
+	}
+
+	appBin := c.appBinPath()
+	if err := os.MkdirAll(appBin, 0755); err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable make app path %s\n%w", appBin, err)
 	}
 
-	// symlink app files from layer to workspace
-	err = filepath.Walk(filepath.Join(layer.Path, "bin"), func(path string, info os.FileInfo, err error) error {
+	// symlink, or copy, app files from layer to workspace, `cargo install` puts binaries under `<root>/bin` for every target
+	// including one from `--target` or `build.target`, unlike `cargo build` which uses `target/<triple>/<profile>`
+	if c.CopyBinaries {
+		c.Logger.Bodyf("Copying binaries to %s", appBin)
+	}
+	binaries, err := c.CargoService.InstalledBinaries(layer)
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to find installed binaries\n%w", err)
+	}
+
+	layerBin := filepath.Join(layer.Path, "bin")
+	names := []string{}
+	for _, path := range binaries {
+		rel, err := filepath.Rel(layerBin, path)
 		if err != nil {
-			return err
+			return libcnb.Layer{}, fmt.Errorf("unable to find path of %s in %s\n%w", path, layerBin, err)
 		}
+		names = append(names, filepath.ToSlash(rel))
+		destPath := filepath.Join(appBin, rel)
 
-		destPath := strings.Replace(path, layer.Path, c.ApplicationPath, 1)
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to create %s\n%w", filepath.Dir(destPath), err)
+		}
 
-		if info.IsDir() {
-			return os.MkdirAll(destPath, 0755)
+		if c.CopyBinaries {
+			info, err := os.Stat(path)
+			if err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to read %s\n%w", path, err)
+			}
+
+			if err := copyBinary(path, destPath, info.Mode()); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to copy binary\n%w", err)
+			}
+		} else if err := os.Symlink(path, destPath); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to link %s to %s\n%w", path, destPath, err)
 		}
+	}
 
-		return os.Symlink(path, destPath)
-	})
-	if err != nil {
-		return libcnb.Layer{}, fmt.Errorf("unable to walk\n%w", err)
+	if layer.Metadata == nil {
+		layer.Metadata = map[string]interface{}{}
 	}
+	layer.Metadata["binaries"] = names
+	c.Events.Emit("binaries", map[string]interface{}{"binaries": names})
 
-	layer.LaunchEnvironment.Append("PATH", ":", filepath.Join(c.ApplicationPath, "bin"))
+	if c.WriteProcfile {
+		procfile := filepath.Join(c.ApplicationPath, "Procfile")
+		if _, err := os.Stat(procfile); err == nil {
+			c.Logger.Bodyf("%s: %s already exists, not writing process types", color.YellowString("Warning"), procfile)
+		} else if err := os.WriteFile(procfile, []byte(Procfile(c.Processes)), 0644); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to write %s\n%w", procfile, err)
+		} else {
+			c.Logger.Bodyf("Writing process types to %s", procfile)
+		}
+	}
+
+	if c.Spans != nil {
+		spansFile := filepath.Join(layer.Path, SpansFile)
+		if err := c.Spans.Write(spansFile); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to write build spans\n%w", err)
+		}
+		c.Logger.Bodyf("Writing build spans to %s", spansFile)
+	}
+
+	switch c.PathStrategy {
+	case PathStrategyPrepend:
+		layer.LaunchEnvironment.Prepend("PATH", ":", appBin)
+	case PathStrategyNone:
+	default:
+		layer.LaunchEnvironment.Append("PATH", ":", appBin)
+	}
+
+	// libcnb.Process has no environment of its own, process specific variables go in the layer's launch environment
+	for _, pType := range slices.Sorted(maps.Keys(c.ProcessEnv)) {
+		if !slices.ContainsFunc(c.Processes, func(p libcnb.Process) bool { return p.Type == pType }) {
+			c.Logger.Bodyf("%s: BP_CARGO_PROCESS_ENV sets variables for %s which is not a process type, skipping", color.YellowString("Warning"), pType)
+			continue
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(c.ProcessEnv[pType])) {
+			layer.LaunchEnvironment.ProcessOverride(pType, name, c.ProcessEnv[pType][name])
+		}
+	}
 
 	return layer, nil
 }
 
+// sbomFormats returns the configured SBOM formats, CycloneDX and Syft by default, limited to the formats the scanner
+// writes if it has a Formats method
+func (c Cargo) sbomFormats() []libcnb.SBOMFormat {
+	formats := c.SBOMFormats
+	if len(formats) == 0 {
+		formats = []libcnb.SBOMFormat{libcnb.CycloneDXJSON, libcnb.SyftJSON}
+	}
+
+	if scanner, ok := c.SBOMScanner.(interface{ Formats() []libcnb.SBOMFormat }); ok {
+		supported := scanner.Formats()
+		return slices.DeleteFunc(slices.Clone(formats), func(f libcnb.SBOMFormat) bool { return !slices.Contains(supported, f) })
+	}
+
+	return formats
+}
+
+// checkSBOM checks that the SBOM scan wrote the SBOM files of layer in formats and that they are not empty
+func checkSBOM(layer libcnb.Layer, formats []libcnb.SBOMFormat) error {
+	for _, format := range formats {
+		path := layer.SBOMPath(format)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("unable to find SBOM %s\n%w", path, err)
+		}
+
+		if info.Size() == 0 {
+			return fmt.Errorf("unable to use SBOM %s, it is empty", path)
+		}
+	}
+
+	return nil
+}
+
+// projectPath is the directory of the Rust project, ProjectPath or the application path if it is not set
+func (c Cargo) projectPath() string {
+	if c.ProjectPath == "" {
+		return c.ApplicationPath
+	}
+
+	return c.ProjectPath
+}
+
+// appBinPath is the directory in the application into which binaries are linked, `bin` unless AppBinDir is set
+func (c Cargo) appBinPath() string {
+	if c.AppBinDir == "" {
+		return filepath.Join(c.ApplicationPath, "bin")
+	}
+
+	return filepath.Join(c.ApplicationPath, c.AppBinDir)
+}
+
+// isWithin checks that path is located strictly below parent
+func isWithin(parent string, path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+
+	rel, err := filepath.Rel(parent, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 func (c Cargo) IsPathSet() (bool, error) {
 	envArgs, err := runner.FilterInstallArgs(c.InstallArgs)
 	if err != nil {
 		return false, fmt.Errorf("unable to filter: %w", err)
 	}
 
+	fileArgs, err := runner.ReadInstallArgsFile(c.InstallArgsFile)
+	if err != nil {
+		return false, err
+	}
+	envArgs = append(envArgs, fileArgs...)
+
 	for _, arg := range envArgs {
 		if arg == "--path" || strings.HasPrefix(arg, "--path=") {
 			return true, nil
@@ -334,21 +1038,41 @@ func (c Cargo) IsPathSet() (bool, error) {
 }
 
 func (c Cargo) BuildProcessTypes(tiniEnabled bool) ([]libcnb.Process, error) {
-	binaryTargets, err := c.CargoService.ProjectTargets(c.ApplicationPath)
+	targets, err := c.binaryTargets()
 	if err != nil {
 		return []libcnb.Process{}, fmt.Errorf("unable to find project targets\n%w", err)
 	}
+	qualified := qualifiedBinaries(targets)
+	binaryTargets := c.processTargets(targets)
+
+	var names []string
+	for _, target := range binaryTargets {
+		names = append(names, target.Name)
+	}
+	duplicates := duplicateNames(names)
+	if duplicates["web"] {
+		c.Logger.Bodyf("%s: multiple members have a binary named web, set BP_CARGO_DEFAULT_PROCESS to pick the default process", color.YellowString("Warning"))
+	}
 
 	procs := []libcnb.Process{}
+	examples := map[int]bool{}
 	for _, target := range binaryTargets {
-		command := filepath.Join(c.ApplicationPath, "bin", target)
-		args := []string{}
+		binary := target.Name
+		if name, ok := qualified[target.MemberPath][target.Name]; ok {
+			binary = name
+		}
+		command := filepath.Join(c.appBinPath(), binary)
+		pType := processType(target, duplicates)
+		args := append([]string{}, c.ProcessArgs[pType]...)
 		if tiniEnabled {
 			args = append([]string{"-g", "--", command}, args...)
 			command = "tini"
 		}
+		if target.Example {
+			examples[len(procs)] = true
+		}
 		procs = append(procs, libcnb.Process{
-			Type:      target,
+			Type:      pType,
 			Command:   command,
 			Arguments: args,
 			Direct:    true,
@@ -357,22 +1081,341 @@ func (c Cargo) BuildProcessTypes(tiniEnabled bool) ([]libcnb.Process, error) {
 	}
 
 	if len(procs) > 0 {
-		found := false
-		for i := 0; i < len(procs) && !found; i++ {
-			if procs[i].Type == "web" {
-				procs[i].Default = true
-				found = true
+		i := c.defaultProcessIndex(procs, examples)
+		procs[i].Default = true
+		procs[i].Arguments = append(procs[i].Arguments, c.WebArgs...)
+	}
+
+	return procs, nil
+}
+
+// FindQualifiedBinaries returns the binaries which multiple workspace members have, by member path, with the name
+// they are installed as, `<member>-<binary>`, so one member's binary doesn't overwrite the other's
+func (c Cargo) FindQualifiedBinaries() (map[string]map[string]string, error) {
+	targets, err := c.binaryTargets()
+	if err != nil {
+		return nil, fmt.Errorf("unable to find project targets\n%w", err)
+	}
+
+	return qualifiedBinaries(targets), nil
+}
+
+// binaryTargets returns the binary targets of the project. The details of the targets are only looked up if they are
+// needed.
+func (c Cargo) binaryTargets() ([]runner.Target, error) {
+	if strings.TrimSpace(c.ProcessMembers) == "" && !c.IncludeExamples {
+		names, err := c.CargoService.ProjectTargets(c.projectPath())
+		if err != nil {
+			return []runner.Target{}, err
+		}
+
+		if len(duplicateNames(names)) == 0 {
+			var targets []runner.Target
+			for _, name := range names {
+				targets = append(targets, runner.Target{Name: name})
 			}
+			return targets, nil
 		}
+	}
+
+	return c.CargoService.ProjectTargetDetails(c.projectPath())
+}
+
+// processTargets returns the binary targets which should become process types, limited to the targets owned by
+// ProcessMembers if set
+func (c Cargo) processTargets(targets []runner.Target) []runner.Target {
+	if strings.TrimSpace(c.ProcessMembers) == "" {
+		return targets
+	}
 
-		if !found {
-			procs[0].Default = true
+	members := map[string]bool{}
+	for _, member := range strings.Split(c.ProcessMembers, ",") {
+		members[strings.TrimSpace(member)] = true
+	}
+
+	var filtered []runner.Target
+	for _, target := range targets {
+		if members[target.Member] {
+			filtered = append(filtered, target)
 		}
 	}
 
-	return procs, nil
+	return filtered
+}
+
+// qualifyBinaries renames the installed binaries of a member to the names they are installed as
+func qualifyBinaries(binDir string, names map[string]string) error {
+	for name, qualified := range names {
+		err := os.Rename(filepath.Join(binDir, name), filepath.Join(binDir, qualified))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("unable to rename %s to %s\n%w", name, qualified, err)
+		}
+	}
+
+	return nil
+}
+
+// qualifiedBinaries maps the path of each member to its binaries which another member has too, with the name they
+// are installed as
+func qualifiedBinaries(targets []runner.Target) map[string]map[string]string {
+	owners := map[string]map[string]bool{}
+	for _, target := range targets {
+		if target.MemberPath == "" {
+			continue
+		}
+		if owners[target.Name] == nil {
+			owners[target.Name] = map[string]bool{}
+		}
+		owners[target.Name][target.MemberPath] = true
+	}
+
+	qualified := map[string]map[string]string{}
+	for _, target := range targets {
+		if len(owners[target.Name]) < 2 {
+			continue
+		}
+		if qualified[target.MemberPath] == nil {
+			qualified[target.MemberPath] = map[string]string{}
+		}
+		qualified[target.MemberPath][target.Name] = fmt.Sprintf("%s-%s", target.Member, target.Name)
+	}
+
+	return qualified
+}
+
+// ParseProcessArgs parses a `;` separated list of `<process type>=<arguments>` entries into a map of process type to
+// its arguments. `:` may be used instead of `=`. The arguments are split like a shell would, so quoted arguments with
+// spaces stay a single argument and a quoted or escaped `;` doesn't end the entry.
+func ParseProcessArgs(raw string) (map[string][]string, error) {
+	processArgs := map[string][]string{}
+
+	for _, entry := range splitProcessArgs(raw) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		i := strings.IndexAny(entry, "=:")
+		if i < 0 {
+			return nil, fmt.Errorf("unable to parse %q, expected <process type>=<arguments>", entry)
+		}
+
+		pType, rawArgs := strings.TrimSpace(entry[:i]), entry[i+1:]
+		if pType == "" || strings.ContainsAny(pType, " \t\"'") || strings.HasPrefix(pType, "-") {
+			return nil, fmt.Errorf("unable to parse %q, expected <process type>=<arguments>", entry)
+		}
+
+		args, err := shellwords.Parse(rawArgs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse arguments of %s\n%w", pType, err)
+		}
+
+		processArgs[pType] = args
+	}
+
+	return processArgs, nil
+}
+
+// ParseProcessEnv parses a `;` separated list of `<process type>:<NAME>=<value> ...` entries into a map of process
+// type to its environment variables. The variables are split like a shell would, so quoted values may contain spaces
+// and a quoted or escaped `;`. A process type may be given in more than one entry.
+func ParseProcessEnv(raw string) (map[string]map[string]string, error) {
+	processEnv := map[string]map[string]string{}
+
+	for _, entry := range splitProcessArgs(raw) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		i := strings.Index(entry, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("unable to parse %q, expected <process type>:<NAME>=<value>", entry)
+		}
+
+		pType := strings.TrimSpace(entry[:i])
+		if pType == "" || strings.ContainsAny(pType, " \t\"'=/") {
+			return nil, fmt.Errorf("unable to parse %q, expected <process type>:<NAME>=<value>", entry)
+		}
+
+		vars, err := shellwords.Parse(entry[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse environment variables of %s\n%w", pType, err)
+		}
+
+		for _, v := range vars {
+			name, value, ok := strings.Cut(v, "=")
+			if !ok || name == "" || strings.Contains(name, "/") {
+				return nil, fmt.Errorf("unable to parse %q of %s, expected <NAME>=<value>", v, pType)
+			}
+
+			if processEnv[pType] == nil {
+				processEnv[pType] = map[string]string{}
+			}
+			processEnv[pType][name] = value
+		}
+	}
+
+	return processEnv, nil
+}
+
+// splitProcessArgs splits raw on `;` outside of quotes and not escaped by a backslash
+func splitProcessArgs(raw string) []string {
+	var (
+		entries []string
+		current strings.Builder
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range raw {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ';':
+			entries = append(entries, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+
+	return append(entries, current.String())
+}
+
+// duplicateNames returns the names which occur more than once
+func duplicateNames(names []string) map[string]bool {
+	counts := map[string]int{}
+	for _, name := range names {
+		counts[name]++
+	}
+
+	duplicates := map[string]bool{}
+	for name, count := range counts {
+		if count > 1 {
+			duplicates[name] = true
+		}
+	}
+
+	return duplicates
+}
+
+// processType names the process type of a target, which is qualified with the owning member if multiple members
+// have a binary with that name. Examples are prefixed with `example-`.
+func processType(target runner.Target, duplicates map[string]bool) string {
+	name := target.Name
+	if duplicates[target.Name] && target.Member != "" {
+		name = fmt.Sprintf("%s-%s", target.Member, target.Name)
+	}
+
+	if target.Example {
+		return fmt.Sprintf("example-%s", name)
+	}
+	return name
+}
+
+// defaultProcessIndex picks the default process, which is the configured default process, or with worker mode the
+// alphabetically first process, otherwise `web` or the first process
+func (c Cargo) defaultProcessIndex(procs []libcnb.Process, examples map[int]bool) int {
+	if c.DefaultProcess != "" {
+		// process types can't contain `:`, so `member:binary` is accepted as an alias for `member-binary`
+		defaultProcess := strings.ReplaceAll(c.DefaultProcess, ":", "-")
+		for i := range procs {
+			if procs[i].Type == defaultProcess {
+				return i
+			}
+		}
+		c.Logger.Bodyf("%s: default process %s does not match any binary target, ignoring", color.YellowString("Warning"), c.DefaultProcess)
+	}
+
+	// examples are only the default if there is nothing else
+	candidates := []int{}
+	for i := range procs {
+		if !examples[i] {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return 0
+	}
+
+	if c.WorkerMode {
+		first := candidates[0]
+		for _, i := range candidates {
+			if procs[i].Type < procs[first].Type {
+				first = i
+			}
+		}
+		c.Logger.Bodyf("Worker mode enabled, using %s as the default process type", procs[first].Type)
+		return first
+	}
+
+	for _, i := range candidates {
+		if procs[i].Type == "web" {
+			return i
+		}
+	}
+
+	return candidates[0]
 }
 
 func (c Cargo) Name() string {
 	return "Cargo"
 }
+
+// readTargetLink returns where the target directory links to, the cache layer links it before the application layer is
+// contributed, so a missing link or a real directory means the cache layer did not run or something replaced its link
+func readTargetLink(targetPath string) (string, error) {
+	info, err := os.Lstat(targetPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("unable to find %s, the Cargo Cache layer must be contributed before the application layer to link it to the cache", targetPath)
+	} else if err != nil {
+		return "", fmt.Errorf("unable to read target link\n%w", err)
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		return "", fmt.Errorf("unable to use %s, it is a %s and not a link to the cache layer, the Cargo Cache layer must be contributed before the application layer and nothing may replace its link, remove %s and try again",
+			targetPath, describeFileMode(info.Mode()), targetPath)
+	}
+
+	link, err := os.Readlink(targetPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read target link\n%w", err)
+	}
+
+	return link, nil
+}
+
+func describeFileMode(mode os.FileMode) string {
+	if mode.IsDir() {
+		return "directory"
+	}
+	return "file"
+}
+
+// copyBinary copies a binary from the layer to the application directory, keeping its permissions
+func copyBinary(path string, destPath string, mode os.FileMode) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	if err := sherpa.CopyFile(in, destPath); err != nil {
+		return fmt.Errorf("unable to copy %s to %s\n%w", path, destPath, err)
+	}
+
+	if err := os.Chmod(destPath, mode.Perm()); err != nil {
+		return fmt.Errorf("unable to set permissions of %s\n%w", destPath, err)
+	}
+
+	return nil
+}
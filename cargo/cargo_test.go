@@ -17,17 +17,25 @@
 package cargo_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/buildpacks/libcnb"
 	. "github.com/onsi/gomega"
 	"github.com/paketo-buildpacks/libpak/bard"
 	sbomMocks "github.com/paketo-buildpacks/libpak/sbom/mocks"
 	"github.com/paketo-community/cargo/cargo"
+	"github.com/paketo-community/cargo/runner"
 	"github.com/paketo-community/cargo/runner/mocks"
 	"github.com/sclevine/spec"
 	"github.com/stretchr/testify/mock"
@@ -55,7 +63,6 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 
 		cargoHome = t.TempDir()
 		Expect(err).NotTo(HaveOccurred())
-		Expect(os.Setenv("CARGO_HOME", cargoHome)).ToNot(HaveOccurred())
 
 		logger = bard.NewLogger(io.Discard)
 
@@ -65,7 +72,6 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 	})
 
 	it.After(func() {
-		Expect(os.Unsetenv("CARGO_HOME")).To(Succeed())
 		Expect(os.RemoveAll(ctx.Application.Path)).To(Succeed())
 		Expect(os.RemoveAll(ctx.Layers.Path)).To(Succeed())
 		Expect(os.RemoveAll(cargoHome)).To(Succeed())
@@ -73,12 +79,20 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 
 	context("contribution scenarios", func() {
 		var (
-			appFile string
+			appFile         string
+			compileWarnings int
 		)
 
 		it.Before(func() {
-			service.On("CargoVersion").Return("1.2.3", nil)
-			service.On("RustVersion").Return("1.2.3", nil)
+			compileWarnings = 0
+			service.On("CompileWarnings").Return(func() int { return compileWarnings })
+			service.On("InstalledBinaries", mock.AnythingOfType("libcnb.Layer")).Return(func(layer libcnb.Layer) []string {
+				binaries, err := runner.CargoRunner{}.InstalledBinaries(layer)
+				Expect(err).NotTo(HaveOccurred())
+				return binaries
+			}, nil)
+			service.On("Toolchain").Return("1.2.3", "1.2.3", nil)
+			service.On("ToolchainRequirements", mock.AnythingOfType("string")).Return(runner.ToolchainRequirements{}, nil)
 
 			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "src"), 0755)).To(Succeed())
 			appFile = filepath.Join(ctx.Application.Path, "src", "main.rs")
@@ -117,6 +131,32 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 				// can't reliably check hash value because it differs every time due to temp path changing on every test run
 				Expect(r.LayerContributor.ExpectedMetadata).To(HaveKey("files"))
 				Expect(r.LayerContributor.ExpectedMetadata.(map[string]interface{})["files"]).To(HaveLen(64))
+				Expect(r.LayerContributor.ExpectedMetadata).ToNot(HaveKey("editions"))
+				Expect(r.LayerContributor.ExpectedMetadata).ToNot(HaveKey("msrv"))
+			})
+
+			it("adds the toolchain requirements", func() {
+				service := &mocks.CargoService{}
+				service.On("Toolchain").Return("1.75.0", "1.75.0", nil)
+				service.On("ToolchainRequirements", ctx.Application.Path).Return(runner.ToolchainRequirements{
+					Editions: []string{"2018", "2021"},
+					MSRV:     "1.74.1",
+				}, nil)
+
+				r, err := cargo.NewCargo(
+					cargo.WithApplicationPath(ctx.Application.Path),
+					cargo.WithCargoService(service),
+					cargo.WithSBOMScanner(sbomScanner))
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(r.LayerContributor.ExpectedMetadata).To(HaveKeyWithValue("editions", []string{"2018", "2021"}))
+				Expect(r.LayerContributor.ExpectedMetadata).To(HaveKeyWithValue("msrv", "1.74.1"))
+			})
+			it("rejects an unknown PATH strategy", func() {
+				_, err := cargo.NewCargo(
+					cargo.WithApplicationPath(ctx.Application.Path),
+					cargo.WithPathStrategy("sideways"))
+				Expect(err).To(MatchError(`unable to use PATH strategy "sideways", must be "append", "prepend" or "none"`))
 			})
 		})
 
@@ -207,6 +247,321 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 					}))
 			})
 
+			context("process and web args are set", func() {
+				it.Before(func() {
+					service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"migrate", "web"}, nil)
+				})
+
+				it("adds the web args to the default process after its process args", func() {
+					r, err := cargo.NewCargo(
+						cargo.WithApplicationPath(ctx.Application.Path),
+						cargo.WithCargoService(service),
+						cargo.WithProcessArgs(map[string][]string{"web": {"--workers", "4"}, "migrate": {"--dry-run"}}),
+						cargo.WithSBOMScanner(sbomScanner),
+						cargo.WithWebArgs([]string{"--bind", "0.0.0.0:8080"}))
+					Expect(err).ToNot(HaveOccurred())
+
+					procs, err := r.BuildProcessTypes(false)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(procs).To(Equal([]libcnb.Process{
+						{Type: "migrate", Command: filepath.Join(ctx.Application.Path, "bin", "migrate"), Arguments: []string{"--dry-run"}, Direct: true},
+						{Type: "web", Command: filepath.Join(ctx.Application.Path, "bin", "web"), Arguments: []string{"--workers", "4", "--bind", "0.0.0.0:8080"}, Direct: true, Default: true},
+					}))
+				})
+
+				it("adds the args after the binary when tini is enabled", func() {
+					r, err := cargo.NewCargo(
+						cargo.WithApplicationPath(ctx.Application.Path),
+						cargo.WithCargoService(service),
+						cargo.WithSBOMScanner(sbomScanner),
+						cargo.WithWebArgs([]string{"--bind", "0.0.0.0:8080"}))
+					Expect(err).ToNot(HaveOccurred())
+
+					procs, err := r.BuildProcessTypes(true)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(procs[1].Arguments).To(Equal([]string{"-g", "--", filepath.Join(ctx.Application.Path, "bin", "web"), "--bind", "0.0.0.0:8080"}))
+					Expect(procs[0].Arguments).To(Equal([]string{"-g", "--", filepath.Join(ctx.Application.Path, "bin", "migrate")}))
+				})
+
+				it("parses process args", func() {
+					args, err := cargo.ParseProcessArgs("web=--bind '0.0.0.0:8080' ; worker=")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(args).To(Equal(map[string][]string{"web": {"--bind", "0.0.0.0:8080"}, "worker": {}}))
+
+					_, err = cargo.ParseProcessArgs("--bind")
+					Expect(err).To(MatchError(ContainSubstring(`unable to parse "--bind"`)))
+				})
+
+				it("keeps quoted process args together", func() {
+					args, err := cargo.ParseProcessArgs(`web:"--config /etc/app.toml" --name 'my app';worker=--queue "jobs;retries" --sep \;`)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(args).To(Equal(map[string][]string{
+						"web":    {"--config /etc/app.toml", "--name", "my app"},
+						"worker": {"--queue", "jobs;retries", "--sep", ";"},
+					}))
+				})
+
+				it("rejects process args without a process type", func() {
+					_, err := cargo.ParseProcessArgs("--bind 0.0.0.0:8080")
+					Expect(err).To(MatchError(ContainSubstring(`unable to parse "--bind 0.0.0.0:8080"`)))
+
+					_, err = cargo.ParseProcessArgs(`web="--unterminated`)
+					Expect(err).To(MatchError(ContainSubstring("unable to parse arguments of web")))
+				})
+			})
+
+			context("process env is set", func() {
+				it("parses process env", func() {
+					env, err := cargo.ParseProcessEnv(`worker:QUEUE=default LOG="debug info";web:PORT=8080;worker:SEP=\;`)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(env).To(Equal(map[string]map[string]string{
+						"web":    {"PORT": "8080"},
+						"worker": {"QUEUE": "default", "LOG": "debug info", "SEP": ";"},
+					}))
+
+					env, err = cargo.ParseProcessEnv("")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(env).To(BeEmpty())
+				})
+
+				it("rejects invalid process env", func() {
+					_, err := cargo.ParseProcessEnv("QUEUE=default")
+					Expect(err).To(MatchError(ContainSubstring(`unable to parse "QUEUE=default"`)))
+
+					_, err = cargo.ParseProcessEnv("worker:QUEUE")
+					Expect(err).To(MatchError(ContainSubstring(`unable to parse "QUEUE" of worker`)))
+
+					_, err = cargo.ParseProcessEnv(`worker:QUEUE="default`)
+					Expect(err).To(MatchError(ContainSubstring("unable to parse environment variables of worker")))
+				})
+			})
+
+			context("process members are set", func() {
+				it("only includes binaries of the listed members", func() {
+					service.On("ProjectTargetDetails", mock.AnythingOfType("string")).Return([]runner.Target{
+						{Name: "api", Member: "api"},
+						{Name: "migrate", Member: "db"},
+						{Name: "worker", Member: "worker"},
+						{Name: "worker-admin", Member: "worker"},
+					}, nil)
+
+					r, err := cargo.NewCargo(
+						cargo.WithApplicationPath(ctx.Application.Path),
+						cargo.WithCargoService(service),
+						cargo.WithProcessMembers("api, worker"),
+						cargo.WithSBOMScanner(sbomScanner))
+					Expect(err).ToNot(HaveOccurred())
+
+					procs, err := r.BuildProcessTypes(false)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(procs).To(HaveLen(3))
+					Expect(procs[0].Type).To(Equal("api"))
+					Expect(procs[0].Default).To(BeTrue())
+					Expect(procs[1].Type).To(Equal("worker"))
+					Expect(procs[2].Type).To(Equal("worker-admin"))
+
+					service.AssertNotCalled(t, "ProjectTargets", mock.Anything)
+				})
+			})
+
+			context("examples are included", func() {
+				it("adds non-default process types for examples", func() {
+					service.On("ProjectTargetDetails", mock.AnythingOfType("string")).Return([]runner.Target{
+						{Name: "client", Member: "app", Example: true},
+						{Name: "app", Member: "app"},
+						{Name: "web", Member: "app", Example: true},
+					}, nil)
+
+					r, err := cargo.NewCargo(
+						cargo.WithApplicationPath(ctx.Application.Path),
+						cargo.WithCargoService(service),
+						cargo.WithIncludeExamples(true),
+						cargo.WithSBOMScanner(sbomScanner))
+					Expect(err).ToNot(HaveOccurred())
+
+					procs, err := r.BuildProcessTypes(false)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(procs).To(Equal([]libcnb.Process{
+						{Type: "example-client", Command: filepath.Join(ctx.Application.Path, "bin", "client"), Arguments: []string{}, Direct: true},
+						{Type: "app", Command: filepath.Join(ctx.Application.Path, "bin", "app"), Arguments: []string{}, Direct: true, Default: true},
+						{Type: "example-web", Command: filepath.Join(ctx.Application.Path, "bin", "web"), Arguments: []string{}, Direct: true},
+					}))
+				})
+			})
+
+			context("multiple members have a binary with the same name", func() {
+				it.Before(func() {
+					service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"web", "migrate", "web"}, nil)
+					service.On("ProjectTargetDetails", mock.AnythingOfType("string")).Return([]runner.Target{
+						{Name: "web", Member: "api", MemberPath: "/workspace/api"},
+						{Name: "migrate", Member: "api", MemberPath: "/workspace/api"},
+						{Name: "web", Member: "worker", MemberPath: "/workspace/worker"},
+					}, nil)
+				})
+
+				it("qualifies the process types with the member", func() {
+					buf := &bytes.Buffer{}
+
+					r, err := cargo.NewCargo(
+						cargo.WithApplicationPath(ctx.Application.Path),
+						cargo.WithCargoService(service),
+						cargo.WithLogger(bard.NewLogger(buf)),
+						cargo.WithSBOMScanner(sbomScanner))
+					Expect(err).ToNot(HaveOccurred())
+
+					procs, err := r.BuildProcessTypes(false)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(procs).To(HaveLen(3))
+					Expect(procs[0].Type).To(Equal("api-web"))
+					Expect(procs[0].Command).To(Equal(filepath.Join(ctx.Application.Path, "bin", "api-web")))
+					Expect(procs[0].Default).To(BeTrue())
+					Expect(procs[1].Type).To(Equal("migrate"))
+					Expect(procs[1].Command).To(Equal(filepath.Join(ctx.Application.Path, "bin", "migrate")))
+					Expect(procs[2].Type).To(Equal("worker-web"))
+					Expect(procs[2].Command).To(Equal(filepath.Join(ctx.Application.Path, "bin", "worker-web")))
+					Expect(procs[2].Default).To(BeFalse())
+					Expect(procs[0].Command).ToNot(Equal(procs[2].Command))
+
+					Expect(buf.String()).To(ContainSubstring("multiple members have a binary named web"))
+				})
+
+				it("picks the default with a qualified name", func() {
+					for _, name := range []string{"worker:web", "worker-web"} {
+						r, err := cargo.NewCargo(
+							cargo.WithApplicationPath(ctx.Application.Path),
+							cargo.WithCargoService(service),
+							cargo.WithDefaultProcess(name),
+							cargo.WithSBOMScanner(sbomScanner))
+						Expect(err).ToNot(HaveOccurred())
+
+						procs, err := r.BuildProcessTypes(false)
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(procs[0].Default).To(BeFalse())
+						Expect(procs[2].Type).To(Equal("worker-web"))
+						Expect(procs[2].Default).To(BeTrue())
+					}
+				})
+				it("finds the binaries which multiple members have", func() {
+					r, err := cargo.NewCargo(
+						cargo.WithApplicationPath(ctx.Application.Path),
+						cargo.WithCargoService(service),
+						cargo.WithSBOMScanner(sbomScanner))
+					Expect(err).ToNot(HaveOccurred())
+
+					qualified, err := r.FindQualifiedBinaries()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(qualified).To(Equal(map[string]map[string]string{
+						"/workspace/api":    {"web": "api-web"},
+						"/workspace/worker": {"web": "worker-web"},
+					}))
+				})
+			})
+
+			context("default process is set", func() {
+				it.Before(func() {
+					service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"web", "server", "worker"}, nil)
+				})
+
+				it("makes the named target the default", func() {
+					r, err := cargo.NewCargo(
+						cargo.WithApplicationPath(ctx.Application.Path),
+						cargo.WithCargoService(service),
+						cargo.WithDefaultProcess("server"),
+						cargo.WithSBOMScanner(sbomScanner))
+					Expect(err).ToNot(HaveOccurred())
+
+					procs, err := r.BuildProcessTypes(false)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(procs).To(HaveLen(3))
+					for _, proc := range procs {
+						Expect(proc.Default).To(Equal(proc.Type == "server"), proc.Type)
+					}
+				})
+
+				it("falls back to web and warns if the named target does not exist", func() {
+					logs := &bytes.Buffer{}
+					r, err := cargo.NewCargo(
+						cargo.WithApplicationPath(ctx.Application.Path),
+						cargo.WithCargoService(service),
+						cargo.WithDefaultProcess("api"),
+						cargo.WithLogger(bard.NewLogger(logs)),
+						cargo.WithSBOMScanner(sbomScanner))
+					Expect(err).ToNot(HaveOccurred())
+
+					procs, err := r.BuildProcessTypes(false)
+					Expect(err).ToNot(HaveOccurred())
+
+					for _, proc := range procs {
+						Expect(proc.Default).To(Equal(proc.Type == "web"), proc.Type)
+					}
+					Expect(logs.String()).To(ContainSubstring("default process api does not match any binary target, ignoring"))
+				})
+
+				it("uses web when unset", func() {
+					r, err := cargo.NewCargo(
+						cargo.WithApplicationPath(ctx.Application.Path),
+						cargo.WithCargoService(service),
+						cargo.WithSBOMScanner(sbomScanner))
+					Expect(err).ToNot(HaveOccurred())
+
+					procs, err := r.BuildProcessTypes(false)
+					Expect(err).ToNot(HaveOccurred())
+
+					for _, proc := range procs {
+						Expect(proc.Default).To(Equal(proc.Type == "web"), proc.Type)
+					}
+				})
+			})
+
+			context("worker mode", func() {
+				it("ignores web and uses the lexicographically first target as default", func() {
+					service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"web", "worker", "cron", "reaper"}, nil)
+
+					logs := &bytes.Buffer{}
+					r, err := cargo.NewCargo(
+						cargo.WithApplicationPath(ctx.Application.Path),
+						cargo.WithCargoService(service),
+						cargo.WithLogger(bard.NewLogger(logs)),
+						cargo.WithSBOMScanner(sbomScanner),
+						cargo.WithWorkerMode(true))
+					Expect(err).ToNot(HaveOccurred())
+
+					procs, err := r.BuildProcessTypes(false)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(procs).To(HaveLen(4))
+					for _, proc := range procs {
+						Expect(proc.Default).To(Equal(proc.Type == "cron"), proc.Type)
+					}
+					Expect(logs.String()).To(ContainSubstring("Worker mode enabled, using cron as the default process type"))
+				})
+
+				it("picks the same default regardless of target order", func() {
+					service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"reaper", "cron", "worker"}, nil)
+
+					r, err := cargo.NewCargo(
+						cargo.WithApplicationPath(ctx.Application.Path),
+						cargo.WithCargoService(service),
+						cargo.WithSBOMScanner(sbomScanner),
+						cargo.WithWorkerMode(true))
+					Expect(err).ToNot(HaveOccurred())
+
+					procs, err := r.BuildProcessTypes(true)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(procs).To(HaveLen(3))
+					Expect(procs[1].Type).To(Equal("cron"))
+					Expect(procs[1].Default).To(BeTrue())
+					Expect(procs[0].Default).To(BeFalse())
+					Expect(procs[2].Default).To(BeFalse())
+				})
+			})
+
 			it("includes all binary targets as process types run by tini with first as default", func() {
 				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"foo", "bar", "baz"}, nil)
 
@@ -257,13 +612,14 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 				var err error
 
 				cache := cargo.Cache{AppPath: ctx.Application.Path, Logger: logger}
-				cacheLayer, err = ctx.Layers.Layer("cache-layer")
+				cacheLayer, err = ctx.Layers.Layer(cargo.Cache{}.Name())
 				Expect(err).NotTo(HaveOccurred())
 				cacheLayer, err = cache.Contribute(cacheLayer)
 				Expect(err).NotTo(HaveOccurred())
 
 				c, err = cargo.NewCargo(
 					cargo.WithApplicationPath(ctx.Application.Path),
+					cargo.WithCargoHome(cargoHome),
 					cargo.WithCargoService(service),
 					cargo.WithSBOMScanner(sbomScanner),
 					cargo.WithTools([]string{"foo-tool"}),
@@ -298,6 +654,32 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 				Expect(service.Calls[2].Arguments[0]).To(Equal("foo-tool"))
 				Expect(service.Calls[2].Arguments[1]).To(Equal([]string{"--baz"}))
 			})
+
+			it("installs a pinned tool version", func() {
+				service.On("InstallTool", "foo-tool", []string{"--version", "1.2.3", "--baz"}).Return(nil)
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
+				})
+
+				c, err := cargo.NewCargo(
+					cargo.WithApplicationPath(ctx.Application.Path),
+					cargo.WithCargoHome(cargoHome),
+					cargo.WithCargoService(service),
+					cargo.WithTools([]string{"foo-tool"}),
+					cargo.WithToolsArgs([]string{"--baz"}),
+					cargo.WithToolVersions(map[string]string{"foo-tool": "1.2.3"}))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(c.LayerContributor.ExpectedMetadata).To(HaveKeyWithValue("tool-versions", map[string]string{"foo-tool": "1.2.3"}))
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				service.AssertCalled(t, "InstallTool", "foo-tool", []string{"--version", "1.2.3", "--baz"})
+			})
 		})
 
 		context("cargo workspace members", func() {
@@ -310,13 +692,14 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 				var err error
 
 				cache := cargo.Cache{AppPath: ctx.Application.Path, Logger: logger}
-				cacheLayer, err = ctx.Layers.Layer("cache-layer")
+				cacheLayer, err = ctx.Layers.Layer(cargo.Cache{}.Name())
 				Expect(err).NotTo(HaveOccurred())
 				cacheLayer, err = cache.Contribute(cacheLayer)
 				Expect(err).NotTo(HaveOccurred())
 
 				c, err = cargo.NewCargo(
 					cargo.WithApplicationPath(ctx.Application.Path),
+					cargo.WithCargoHome(cargoHome),
 					cargo.WithCargoService(service),
 					cargo.WithSBOMScanner(sbomScanner),
 					cargo.WithRunSBOMScan(true))
@@ -414,139 +797,845 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 				Expect(outputLayer.LaunchEnvironment["PATH.append"]).To(Equal(filepath.Join(ctx.Application.Path, "bin")))
 			})
 
-			it("contributes cargo layer with one member without SBOM", func() {
-				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
-					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
-				}, nil)
-
-				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
-					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
-					err := os.WriteFile(filepath.Join(layer.Path, "bin", "my-binary"), []byte("contents"), 0644)
-					Expect(err).ToNot(HaveOccurred())
-					return nil
-				})
-
-				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"my-binary", "other"}, nil)
-
-				inputLayer, err := ctx.Layers.Layer("cargo-layer")
-				Expect(err).ToNot(HaveOccurred())
-
-				c.RunSBOMScan = false
-
-				outputLayer, err := c.Contribute(inputLayer)
-				Expect(err).NotTo(HaveOccurred())
-
-				Expect(os.Getenv("CARGO_REGISTRIES_CRATES_IO_PROTOCOL")).To(Equal("sparse"))
-
-				sbomScanner.AssertNotCalled(t, "ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON)
-
-				Expect(outputLayer.LayerTypes.Cache).To(BeTrue())
-				Expect(outputLayer.LayerTypes.Build).To(BeFalse())
-				Expect(outputLayer.LayerTypes.Launch).To(BeTrue())
-
-				// app files should be deleted
-				Expect(appFile).ToNot(BeAnExistingFile())
-
-				// preserver should have run
-				Expect(filepath.Join(outputLayer.Path, "mtimes.json")).To(BeARegularFile())
-				Expect(filepath.Join(cargoHome, "mtimes.json")).To(BeARegularFile())
-				Expect(filepath.Join(cacheLayer.Path, "mtimes.json")).To(BeARegularFile())
-
-				// we should have two copies of the binary, one in the layer an one in the app root
-				Expect(filepath.Join(outputLayer.Path, "bin", "my-binary")).To(BeARegularFile())
-				Expect(filepath.Join(ctx.Application.Path, "bin", "my-binary")).To(BeARegularFile())
-				Expect(filepath.Join(ctx.Application.Path, "mtimes.json")).ToNot(BeARegularFile())
-
-				// Ensure `/workspace/bin` is added to the PATH at launch
-				Expect(outputLayer.LaunchEnvironment["PATH.append"]).To(Equal(filepath.Join(ctx.Application.Path, "bin")))
-			})
+			context("an SBOM is required", func() {
+				var inputLayer libcnb.Layer
 
-			context("--path is set", func() {
-				it("contributes cargo layer with multiples member but --path set", func() {
+				it.Before(func() {
 					service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
-						{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "basics")},
-						{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "todo")},
+						{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
 					}, nil)
-
-					// include `--path`
-					c.InstallArgs = "--path=./todo"
-
 					service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
-						Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
-						err := os.WriteFile(filepath.Join(layer.Path, "bin", "my-binary"), []byte("contents"), 0644)
-						Expect(err).ToNot(HaveOccurred())
-						return nil
+						return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
 					})
 
-					service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"my-binary"}, nil)
-
-					inputLayer, err := ctx.Layers.Layer("cargo-layer")
+					var err error
+					inputLayer, err = ctx.Layers.Layer("cargo-layer")
 					Expect(err).ToNot(HaveOccurred())
 
-					sbomScanner.On("ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON).Return(nil)
+					c.RequireSBOM = true
+				})
 
-					outputLayer, err := c.Contribute(inputLayer)
-					Expect(err).NotTo(HaveOccurred())
+				it("fails when the scan fails", func() {
+					sbomScanner.On("ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON).Return(fmt.Errorf("syft: command not found"))
 
-					Expect(os.Getenv("CARGO_REGISTRIES_CRATES_IO_PROTOCOL")).To(Equal("sparse"))
+					_, err := c.Contribute(inputLayer)
+					Expect(err).To(MatchError(ContainSubstring("syft: command not found")))
+				})
 
-					sbomScanner.AssertCalled(t, "ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON)
+				it("fails when the scan does not write an SBOM", func() {
+					sbomScanner.On("ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON).Return(nil)
 
-					Expect(outputLayer.LayerTypes.Cache).To(BeTrue())
-					Expect(outputLayer.LayerTypes.Build).To(BeFalse())
-					Expect(outputLayer.LayerTypes.Launch).To(BeTrue())
+					_, err := c.Contribute(inputLayer)
+					Expect(err).To(MatchError(ContainSubstring("BP_CARGO_REQUIRE_SBOM is set")))
+					Expect(err).To(MatchError(ContainSubstring("unable to find SBOM")))
+				})
 
-					// app files should be deleted
-					Expect(appFile).ToNot(BeAnExistingFile())
+				it("fails when the scan writes an empty SBOM", func() {
+					sbomScanner.On("ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON).Run(func(mock.Arguments) {
+						Expect(os.WriteFile(inputLayer.SBOMPath(libcnb.CycloneDXJSON), []byte("{}"), 0644)).To(Succeed())
+						Expect(os.WriteFile(inputLayer.SBOMPath(libcnb.SyftJSON), []byte{}, 0644)).To(Succeed())
+					}).Return(nil)
 
-					// preserver should have run
-					Expect(filepath.Join(outputLayer.Path, "mtimes.json")).To(BeARegularFile())
-					Expect(filepath.Join(cargoHome, "mtimes.json")).To(BeARegularFile())
-					Expect(filepath.Join(cacheLayer.Path, "mtimes.json")).To(BeARegularFile())
+					_, err := c.Contribute(inputLayer)
+					Expect(err).To(MatchError(ContainSubstring("it is empty")))
+				})
 
-					// we should have two copies of the binary, one in the layer an one in the app root
-					Expect(filepath.Join(outputLayer.Path, "bin", "my-binary")).To(BeARegularFile())
-					Expect(filepath.Join(ctx.Application.Path, "bin", "my-binary")).To(BeARegularFile())
-					Expect(filepath.Join(ctx.Application.Path, "mtimes.json")).ToNot(BeARegularFile())
+				it("passes when the scan writes an SBOM", func() {
+					sbomScanner.On("ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON).Run(func(mock.Arguments) {
+						Expect(os.WriteFile(inputLayer.SBOMPath(libcnb.CycloneDXJSON), []byte(`{"bomFormat":"CycloneDX"}`), 0644)).To(Succeed())
+						Expect(os.WriteFile(inputLayer.SBOMPath(libcnb.SyftJSON), []byte(`{"artifacts":[]}`), 0644)).To(Succeed())
+					}).Return(nil)
+					service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{}, nil)
 
-					// Ensure `/workspace/bin` is added to the PATH at launch
-					Expect(outputLayer.LaunchEnvironment["PATH.append"]).To(Equal(filepath.Join(ctx.Application.Path, "bin")))
+					_, err := c.Contribute(inputLayer)
+					Expect(err).NotTo(HaveOccurred())
 				})
 			})
 
-			it("contributes cargo layer with multiple members", func() {
+			it("scans for the configured SBOM formats", func() {
 				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
-					{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "basics")},
-					{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "todo")},
-					{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "hello")},
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
 				}, nil)
-
-				service.On("InstallMember", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(memberPath string, srcDir string, layer libcnb.Layer) error {
-					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
-					err := os.WriteFile(filepath.Join(layer.Path, "bin", filepath.Base(memberPath)), []byte("contents"), 0644)
-					Expect(err).ToNot(HaveOccurred())
-					return nil
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
 				})
 
-				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"todo", "hello"}, nil)
-
 				inputLayer, err := ctx.Layers.Layer("cargo-layer")
 				Expect(err).ToNot(HaveOccurred())
 
-				sbomScanner.On("ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON).Return(nil)
+				sbomScanner.On("ScanLayer", inputLayer, ctx.Application.Path, libcnb.SPDXJSON).Return(nil)
+
+				c.SBOMFormats = []libcnb.SBOMFormat{libcnb.SPDXJSON}
 
 				outputLayer, err := c.Contribute(inputLayer)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(os.Getenv("CARGO_REGISTRIES_CRATES_IO_PROTOCOL")).To(Equal("sparse"))
-
-				sbomScanner.AssertCalled(t, "ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON)
+				sbomScanner.AssertCalled(t, "ScanLayer", inputLayer, ctx.Application.Path, libcnb.SPDXJSON)
+				sbomScanner.AssertNumberOfCalls(t, "ScanLayer", 1)
+				Expect(outputLayer.SBOMPath(libcnb.CycloneDXJSON)).ToNot(BeAnExistingFile())
+			})
 
-				Expect(outputLayer.LayerTypes.Cache).To(BeTrue())
-				Expect(outputLayer.LayerTypes.Build).To(BeFalse())
-				Expect(outputLayer.LayerTypes.Launch).To(BeTrue())
+			it("adds Cargo.lock dependencies to the layer SBOM", func() {
+				lock, err := os.ReadFile("testdata/Cargo.lock")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), lock, 0644)).To(Succeed())
 
-				// app files should be deleted
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					return os.WriteFile(filepath.Join(layer.Path, "bin", "my-binary"), []byte("contents"), 0644)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				sbomScanner.On("ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON).Return(nil)
+
+				outputLayer, err := c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				sbomScanner.AssertCalled(t, "ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON)
+
+				bom := readBOM(t, outputLayer.SBOMPath(libcnb.CycloneDXJSON))
+				Expect(bom["components"]).To(HaveLen(5))
+				Expect(bom["components"]).To(ContainElement(HaveKeyWithValue("purl", "pkg:cargo/serde@1.0.0")))
+				Expect(bom["components"]).To(ContainElement(HaveKeyWithValue("purl", "pkg:generic/rust@1.2.3")))
+				Expect(bom["components"]).To(ContainElement(HaveKeyWithValue("purl", "pkg:generic/cargo@1.2.3")))
+			})
+
+			it("fetches dependencies before installing", func() {
+				service.On("Fetch", ctx.Application.Path).Return(nil)
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.Fetch = true
+				c.RunSBOMScan = false
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				var methods []string
+				for _, call := range service.Calls {
+					methods = append(methods, call.Method)
+				}
+				Expect(methods).To(ContainElements("Fetch", "WorkspaceMembers", "Install"))
+				order := []string{"Fetch", "WorkspaceMembers", "Install"}
+				Expect(slices.DeleteFunc(methods, func(m string) bool { return !slices.Contains(order, m) })).To(Equal(order))
+			})
+
+			it("writes spans for the phases of the build", func() {
+				service.On("Fetch", ctx.Application.Path).Return(nil)
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "todo")},
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "hello")},
+				}, nil)
+				service.On("InstallMember", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(memberPath string, srcDir string, layer libcnb.Layer) error {
+					return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				sbomScanner.On("ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON).Return(nil)
+
+				start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+				now := start
+				c.Spans = &cargo.Spans{Clock: func() time.Time {
+					now = now.Add(time.Second)
+					return now
+				}}
+				c.Fetch = true
+
+				outputLayer, err := c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(c.Spans.Records).To(Equal([]cargo.Span{
+					{Name: "fetch", Start: start.Add(1 * time.Second), End: start.Add(2 * time.Second)},
+					{Name: "install", Start: start.Add(3 * time.Second), End: start.Add(4 * time.Second), Attributes: map[string]string{"member": filepath.Join(ctx.Application.Path, "todo")}},
+					{Name: "install", Start: start.Add(5 * time.Second), End: start.Add(6 * time.Second), Attributes: map[string]string{"member": filepath.Join(ctx.Application.Path, "hello")}},
+					{Name: "sbom-scan", Start: start.Add(7 * time.Second), End: start.Add(8 * time.Second)},
+					{Name: "cleanup", Start: start.Add(9 * time.Second), End: start.Add(10 * time.Second)},
+				}))
+				Expect(filepath.Join(outputLayer.Path, cargo.SpansFile)).To(BeARegularFile())
+			})
+
+			it("logs how long the compile took", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				times := []time.Time{
+					time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+					time.Date(2024, 1, 1, 0, 3, 12, 400, time.UTC),
+				}
+				c.Clock = func() time.Time {
+					now := times[0]
+					times = times[1:]
+					return now
+				}
+
+				logs := &bytes.Buffer{}
+				c.Logger = bard.NewLogger(logs)
+				c.RunSBOMScan = false
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(logs.String()).To(ContainSubstring("Compiled in 3m12s"))
+			})
+
+			it("writes build events as JSON lines", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "api")},
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "worker")},
+				}, nil)
+				service.On("InstallMember", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(memberPath string, srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					return os.WriteFile(filepath.Join(layer.Path, "bin", filepath.Base(memberPath)), []byte("contents"), 0755)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				out := &bytes.Buffer{}
+				c.Events = &cargo.Events{Clock: c.Clock, Writer: out}
+				c.RunSBOMScan = false
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				var names []string
+				for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+					var event cargo.Event
+					Expect(json.Unmarshal([]byte(line), &event)).To(Succeed(), line)
+					names = append(names, event.Name)
+
+					if event.Name == "binaries" {
+						Expect(event.Fields).To(HaveKeyWithValue("binaries", []interface{}{"api", "worker"}))
+					}
+				}
+				Expect(names).To(Equal([]string{"member-built", "member-built", "compiled", "binaries"}))
+			})
+
+			it("writes binary checksum files", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					return os.WriteFile(filepath.Join(layer.Path, "bin", "web"), []byte("web binary"), 0755)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.BinaryChecksums = true
+				c.RunSBOMScan = false
+
+				outputLayer, err := c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := os.ReadFile(filepath.Join(outputLayer.Path, "checksums", "web.sha256"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(contents)).To(Equal("143e5ce3b7c2407fa6c750774990691d0933d159cdf3158cefd049f1b86926f1  web\n"))
+
+				// the checksum files are not binaries
+				Expect(filepath.Join(outputLayer.Path, "bin", "web.sha256")).ToNot(BeAnExistingFile())
+				Expect(filepath.Join(ctx.Application.Path, "bin", "web.sha256")).ToNot(BeAnExistingFile())
+				Expect(outputLayer.Metadata).To(HaveKeyWithValue("binaries", []string{"web"}))
+			})
+
+			context("keeping the source code", func() {
+				it.Before(func() {
+					service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+						{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+					}, nil)
+					service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+						Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+						return os.WriteFile(filepath.Join(layer.Path, "bin", "web"), []byte("contents"), 0755)
+					})
+
+					c.RunSBOMScan = false
+				})
+
+				it("keeps the source code when enabled", func() {
+					inputLayer, err := ctx.Layers.Layer("cargo-layer")
+					Expect(err).ToNot(HaveOccurred())
+
+					c.KeepSource = true
+
+					_, err = c.Contribute(inputLayer)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(appFile).To(BeARegularFile())
+					Expect(filepath.Join(ctx.Application.Path, "bin", "web")).To(BeAnExistingFile())
+				})
+
+				it("removes the source code by default", func() {
+					inputLayer, err := ctx.Layers.Layer("cargo-layer")
+					Expect(err).ToNot(HaveOccurred())
+
+					_, err = c.Contribute(inputLayer)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(appFile).ToNot(BeAnExistingFile())
+					Expect(filepath.Join(ctx.Application.Path, "bin", "web")).To(BeAnExistingFile())
+				})
+			})
+
+			it("verifies the binaries after installing", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
+				})
+				service.On("VerifyBinaries", mock.AnythingOfType("string")).Return(fmt.Errorf("unable to run web --version"))
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.RunSBOMScan = false
+				c.VerifyBinaries = true
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).To(MatchError(ContainSubstring("unable to verify binaries")))
+				service.AssertCalled(t, "VerifyBinaries", filepath.Join(inputLayer.Path, "bin"))
+			})
+
+			it("strips the binaries before verifying them", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
+				})
+				service.On("StripBinaries", mock.AnythingOfType("string")).Return(nil)
+				service.On("VerifyBinaries", mock.AnythingOfType("string")).Return(nil)
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{}, nil)
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.RunSBOMScan = false
+				c.StripArgs = []string{"--strip-unneeded"}
+				c.VerifyBinaries = true
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				var methods []string
+				for _, call := range service.Calls {
+					methods = append(methods, call.Method)
+				}
+				Expect(methods).To(ContainElements("StripBinaries", "VerifyBinaries"))
+				Expect(slices.Index(methods, "StripBinaries")).To(BeNumerically("<", slices.Index(methods, "VerifyBinaries")))
+				service.AssertCalled(t, "StripBinaries", filepath.Join(inputLayer.Path, "bin"))
+			})
+
+			it("records the compile warnings without rebuilding for them", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.RunSBOMScan = false
+				compileWarnings = 3
+
+				outputLayer, err := c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(outputLayer.Metadata).To(HaveKeyWithValue("compile-warnings", 3))
+				service.AssertNumberOfCalls(t, "Install", 1)
+
+				outputLayer, err = c.Contribute(outputLayer)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(outputLayer.Metadata).To(HaveKeyWithValue("compile-warnings", 3))
+				service.AssertNumberOfCalls(t, "Install", 1)
+			})
+
+			it("records the installed binaries without rebuilding for them", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					Expect(os.WriteFile(filepath.Join(layer.Path, "bin", "worker"), []byte("contents"), 0755)).ToNot(HaveOccurred())
+					return os.WriteFile(filepath.Join(layer.Path, "bin", "api"), []byte("contents"), 0755)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.RunSBOMScan = false
+
+				outputLayer, err := c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(outputLayer.Metadata).To(HaveKeyWithValue("binaries", []string{"api", "worker"}))
+				Expect(c.LayerContributor.ExpectedMetadata).ToNot(HaveKey("binaries"))
+				service.AssertNumberOfCalls(t, "Install", 1)
+
+				outputLayer, err = c.Contribute(outputLayer)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(outputLayer.Metadata).To(HaveKeyWithValue("binaries", []string{"api", "worker"}))
+				service.AssertNumberOfCalls(t, "Install", 1)
+			})
+
+			it("prunes registry sources after installing", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
+				})
+				service.On("PruneRegistrySources", ctx.Application.Path).Return(nil)
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.PruneSources = true
+				c.RunSBOMScan = false
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				var methods []string
+				for _, call := range service.Calls {
+					methods = append(methods, call.Method)
+				}
+				order := []string{"Install", "PruneRegistrySources"}
+				Expect(slices.DeleteFunc(methods, func(m string) bool { return !slices.Contains(order, m) })).To(Equal(order))
+			})
+
+			it("runs the pre-build command after installing tools and before installing", func() {
+				service.On("InstallTool", "sqlx-cli", []string{}).Return(nil)
+				service.On("PreBuild", ctx.Application.Path, "cargo sqlx prepare").Return(nil)
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.PreBuild = "cargo sqlx prepare"
+				c.RunSBOMScan = false
+				c.Tools = []string{"sqlx-cli"}
+				c.ToolsArgs = []string{}
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				var methods []string
+				for _, call := range service.Calls {
+					methods = append(methods, call.Method)
+				}
+				order := []string{"InstallTool", "PreBuild", "Install"}
+				Expect(slices.DeleteFunc(methods, func(m string) bool { return !slices.Contains(order, m) })).To(Equal(order))
+			})
+
+			it("fails when the pre-build command fails", func() {
+				service.On("PreBuild", ctx.Application.Path, "./codegen.sh").Return(fmt.Errorf("exit status 1"))
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.PreBuild = "./codegen.sh"
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).To(MatchError(ContainSubstring("unable to run BP_CARGO_PRE_BUILD")))
+				service.AssertNotCalled(t, "Install", mock.Anything, mock.Anything)
+			})
+
+			it("runs the tests after the pre-build command and before installing", func() {
+				service.On("PreBuild", ctx.Application.Path, "./codegen.sh").Return(nil)
+				service.On("Test", ctx.Application.Path).Return(nil)
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.PreBuild = "./codegen.sh"
+				c.RunSBOMScan = false
+				c.RunTests = true
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				var methods []string
+				for _, call := range service.Calls {
+					methods = append(methods, call.Method)
+				}
+				order := []string{"PreBuild", "Test", "Install"}
+				Expect(slices.DeleteFunc(methods, func(m string) bool { return !slices.Contains(order, m) })).To(Equal(order))
+			})
+
+			it("fails when the tests fail", func() {
+				service.On("Test", ctx.Application.Path).Return(fmt.Errorf("cargo test failed"))
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.RunTests = true
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).To(MatchError(ContainSubstring("unable to pass cargo test\ncargo test failed")))
+				service.AssertNotCalled(t, "Install", mock.Anything, mock.Anything)
+			})
+
+			it("writes the process types to a Procfile", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					return os.WriteFile(filepath.Join(layer.Path, "bin", "web"), []byte("contents"), 0644)
+				})
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"my-binary", "web"}, nil)
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.RunSBOMScan = false
+				c.WriteProcfile = true
+				c.Processes, err = c.BuildProcessTypes(true)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				procfile, err := os.ReadFile(filepath.Join(ctx.Application.Path, "Procfile"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(procfile)).To(Equal(fmt.Sprintf(`# default: web
+my-binary: tini -g -- %[1]s/bin/my-binary
+web: tini -g -- %[1]s/bin/web
+`, ctx.Application.Path)))
+			})
+
+			context("BP_CARGO_PATH_STRATEGY", func() {
+				it.Before(func() {
+					service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+						{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+					}, nil)
+					service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+						Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+						return os.WriteFile(filepath.Join(layer.Path, "bin", "my-binary"), []byte("contents"), 0644)
+					})
+
+					c.RunSBOMScan = false
+				})
+
+				it("prepends the application binaries to the launch PATH", func() {
+					inputLayer, err := ctx.Layers.Layer("cargo-layer")
+					Expect(err).ToNot(HaveOccurred())
+
+					c.PathStrategy = cargo.PathStrategyPrepend
+
+					outputLayer, err := c.Contribute(inputLayer)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(outputLayer.LaunchEnvironment["PATH.prepend"]).To(Equal(filepath.Join(ctx.Application.Path, "bin")))
+					Expect(outputLayer.LaunchEnvironment).ToNot(HaveKey("PATH.append"))
+				})
+
+				it("leaves the launch PATH alone", func() {
+					inputLayer, err := ctx.Layers.Layer("cargo-layer")
+					Expect(err).ToNot(HaveOccurred())
+
+					c.PathStrategy = cargo.PathStrategyNone
+
+					outputLayer, err := c.Contribute(inputLayer)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(outputLayer.LaunchEnvironment).ToNot(HaveKey("PATH.append"))
+					Expect(outputLayer.LaunchEnvironment).ToNot(HaveKey("PATH.prepend"))
+					Expect(outputLayer.LaunchEnvironment).ToNot(HaveKey("PATH.delim"))
+				})
+			})
+
+			it("contributes cargo layer with one member without SBOM", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					err := os.WriteFile(filepath.Join(layer.Path, "bin", "my-binary"), []byte("contents"), 0644)
+					Expect(err).ToNot(HaveOccurred())
+					return nil
+				})
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"my-binary", "other"}, nil)
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.RunSBOMScan = false
+
+				outputLayer, err := c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.Getenv("CARGO_REGISTRIES_CRATES_IO_PROTOCOL")).To(Equal("sparse"))
+
+				sbomScanner.AssertNotCalled(t, "ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON)
+
+				Expect(outputLayer.LayerTypes.Cache).To(BeTrue())
+				Expect(outputLayer.LayerTypes.Build).To(BeFalse())
+				Expect(outputLayer.LayerTypes.Launch).To(BeTrue())
+
+				// app files should be deleted
+				Expect(appFile).ToNot(BeAnExistingFile())
+
+				// preserver should have run
+				Expect(filepath.Join(outputLayer.Path, "mtimes.json")).To(BeARegularFile())
+				Expect(filepath.Join(cargoHome, "mtimes.json")).To(BeARegularFile())
+				Expect(filepath.Join(cacheLayer.Path, "mtimes.json")).To(BeARegularFile())
+
+				// we should have two copies of the binary, one in the layer an one in the app root
+				Expect(filepath.Join(outputLayer.Path, "bin", "my-binary")).To(BeARegularFile())
+				Expect(filepath.Join(ctx.Application.Path, "bin", "my-binary")).To(BeARegularFile())
+				Expect(filepath.Join(ctx.Application.Path, "mtimes.json")).ToNot(BeARegularFile())
+
+				// Ensure `/workspace/bin` is added to the PATH at launch
+				Expect(outputLayer.LaunchEnvironment["PATH.append"]).To(Equal(filepath.Join(ctx.Application.Path, "bin")))
+			})
+
+			it("links the binaries of a target triple into the application", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+
+				// `cargo install --root` puts binaries in `<root>/bin` for every target, the build output stays in
+				// the target directory under `<triple>/release`
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(cacheLayer.Path, "x86_64-unknown-linux-musl", "release"), 0755)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(cacheLayer.Path, "x86_64-unknown-linux-musl", "release", "my-binary"), []byte("contents"), 0755)).To(Succeed())
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).To(Succeed())
+					return os.WriteFile(filepath.Join(layer.Path, "bin", "my-binary"), []byte("contents"), 0755)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.RunSBOMScan = false
+				c.Target = "x86_64-unknown-linux-musl"
+
+				outputLayer, err := c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.Readlink(filepath.Join(ctx.Application.Path, "bin", "my-binary"))).To(Equal(filepath.Join(outputLayer.Path, "bin", "my-binary")))
+				Expect(filepath.Join(ctx.Application.Path, "bin", "x86_64-unknown-linux-musl")).ToNot(BeAnExistingFile())
+			})
+
+			it("copies the binaries instead of symlinking them", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					err := os.WriteFile(filepath.Join(layer.Path, "bin", "my-binary"), []byte("contents"), 0755)
+					Expect(err).ToNot(HaveOccurred())
+					return nil
+				})
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"my-binary"}, nil)
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.CopyBinaries = true
+				c.RunSBOMScan = false
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				info, err := os.Lstat(filepath.Join(ctx.Application.Path, "bin", "my-binary"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Mode().IsRegular()).To(BeTrue())
+				Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+				Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "bin", "my-binary"))).To(Equal([]byte("contents")))
+			})
+
+			it("links the binaries into the configured bin directory", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					err := os.WriteFile(filepath.Join(layer.Path, "bin", "my-binary"), []byte("contents"), 0755)
+					Expect(err).ToNot(HaveOccurred())
+					return nil
+				})
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"my-binary"}, nil)
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.AppBinDir = ".bin"
+				c.RunSBOMScan = false
+
+				outputLayer, err := c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.Readlink(filepath.Join(ctx.Application.Path, ".bin", "my-binary"))).To(Equal(filepath.Join(outputLayer.Path, "bin", "my-binary")))
+				Expect(filepath.Join(ctx.Application.Path, "bin")).ToNot(BeAnExistingFile())
+				Expect(outputLayer.LaunchEnvironment["PATH.append"]).To(Equal(filepath.Join(ctx.Application.Path, ".bin")))
+
+				procs, err := c.BuildProcessTypes(true)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(procs).To(Equal([]libcnb.Process{
+					{
+						Type:      "my-binary",
+						Command:   "tini",
+						Arguments: []string{"-g", "--", filepath.Join(ctx.Application.Path, ".bin", "my-binary")},
+						Direct:    true,
+						Default:   true,
+					},
+				}))
+			})
+
+			it("sets the process env in the launch environment of the process types", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(nil)
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.Processes = []libcnb.Process{{Type: "web"}, {Type: "worker"}}
+				c.ProcessEnv = map[string]map[string]string{
+					"worker":  {"QUEUE": "default", "LOG": "debug"},
+					"missing": {"FOO": "bar"},
+				}
+				c.RunSBOMScan = false
+
+				outputLayer, err := c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(outputLayer.LaunchEnvironment).To(Equal(libcnb.Environment{
+					"PATH.append":           filepath.Join(ctx.Application.Path, "bin"),
+					"PATH.delim":            ":",
+					"worker/QUEUE.override": "default",
+					"worker/LOG.override":   "debug",
+				}))
+			})
+
+			context("--path is set", func() {
+				it("contributes cargo layer with multiples member but --path set", func() {
+					service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+						{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "basics")},
+						{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "todo")},
+					}, nil)
+
+					// include `--path`
+					c.InstallArgs = "--path=./todo"
+
+					service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+						Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+						err := os.WriteFile(filepath.Join(layer.Path, "bin", "my-binary"), []byte("contents"), 0644)
+						Expect(err).ToNot(HaveOccurred())
+						return nil
+					})
+
+					service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"my-binary"}, nil)
+
+					inputLayer, err := ctx.Layers.Layer("cargo-layer")
+					Expect(err).ToNot(HaveOccurred())
+
+					sbomScanner.On("ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON).Return(nil)
+
+					outputLayer, err := c.Contribute(inputLayer)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(os.Getenv("CARGO_REGISTRIES_CRATES_IO_PROTOCOL")).To(Equal("sparse"))
+
+					sbomScanner.AssertCalled(t, "ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON)
+
+					Expect(outputLayer.LayerTypes.Cache).To(BeTrue())
+					Expect(outputLayer.LayerTypes.Build).To(BeFalse())
+					Expect(outputLayer.LayerTypes.Launch).To(BeTrue())
+
+					// app files should be deleted
+					Expect(appFile).ToNot(BeAnExistingFile())
+
+					// preserver should have run
+					Expect(filepath.Join(outputLayer.Path, "mtimes.json")).To(BeARegularFile())
+					Expect(filepath.Join(cargoHome, "mtimes.json")).To(BeARegularFile())
+					Expect(filepath.Join(cacheLayer.Path, "mtimes.json")).To(BeARegularFile())
+
+					// we should have two copies of the binary, one in the layer an one in the app root
+					Expect(filepath.Join(outputLayer.Path, "bin", "my-binary")).To(BeARegularFile())
+					Expect(filepath.Join(ctx.Application.Path, "bin", "my-binary")).To(BeARegularFile())
+					Expect(filepath.Join(ctx.Application.Path, "mtimes.json")).ToNot(BeARegularFile())
+
+					// Ensure `/workspace/bin` is added to the PATH at launch
+					Expect(outputLayer.LaunchEnvironment["PATH.append"]).To(Equal(filepath.Join(ctx.Application.Path, "bin")))
+				})
+			})
+
+			it("contributes cargo layer with multiple members", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "basics")},
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "todo")},
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path, "hello")},
+				}, nil)
+
+				service.On("InstallMember", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(memberPath string, srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					err := os.WriteFile(filepath.Join(layer.Path, "bin", filepath.Base(memberPath)), []byte("contents"), 0644)
+					Expect(err).ToNot(HaveOccurred())
+					return nil
+				})
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"todo", "hello"}, nil)
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				sbomScanner.On("ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON).Return(nil)
+
+				outputLayer, err := c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.Getenv("CARGO_REGISTRIES_CRATES_IO_PROTOCOL")).To(Equal("sparse"))
+
+				sbomScanner.AssertCalled(t, "ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON)
+
+				Expect(outputLayer.LayerTypes.Cache).To(BeTrue())
+				Expect(outputLayer.LayerTypes.Build).To(BeFalse())
+				Expect(outputLayer.LayerTypes.Launch).To(BeTrue())
+
+				// app files should be deleted
 				Expect(appFile).ToNot(BeAnExistingFile())
 
 				// preserver should have run
@@ -570,14 +1659,78 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 				Expect(outputLayer.LaunchEnvironment["PATH.append"]).To(Equal(filepath.Join(ctx.Application.Path, "bin")))
 			})
 
+			it("renames binaries which multiple members have", func() {
+				apiPath := filepath.Join(ctx.Application.Path, "api")
+				workerPath := filepath.Join(ctx.Application.Path, "worker")
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: apiPath},
+					{Scheme: "file", Path: workerPath},
+				}, nil)
+
+				service.On("InstallMember", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(memberPath string, srcDir string, layer libcnb.Layer) error {
+					// like `cargo install`, refuse to replace a binary installed by another member
+					bin := filepath.Join(layer.Path, "bin", "web")
+					if _, err := os.Stat(bin); err == nil {
+						return fmt.Errorf("binary `web` already exists in destination")
+					}
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					return os.WriteFile(bin, []byte(filepath.Base(memberPath)), 0755)
+				})
+
+				service.On("ProjectTargets", mock.AnythingOfType("string")).Return([]string{"web", "web"}, nil)
+
+				c.QualifiedBinaries = map[string]map[string]string{
+					apiPath:    {"web": "api-web"},
+					workerPath: {"web": "worker-web"},
+				}
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				sbomScanner.On("ScanLayer", inputLayer, ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON).Return(nil)
+
+				outputLayer, err := c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(outputLayer.Path, "bin", "web")).ToNot(BeAnExistingFile())
+				Expect(os.ReadFile(filepath.Join(outputLayer.Path, "bin", "api-web"))).To(Equal([]byte("api")))
+				Expect(os.ReadFile(filepath.Join(outputLayer.Path, "bin", "worker-web"))).To(Equal([]byte("worker")))
+				Expect(filepath.Join(ctx.Application.Path, "bin", "api-web")).To(BeAnExistingFile())
+				Expect(filepath.Join(ctx.Application.Path, "bin", "worker-web")).To(BeAnExistingFile())
+			})
+
+			it("uses the configured CARGO_HOME instead of the environment", func() {
+				otherHome := t.TempDir()
+				Expect(os.Setenv("CARGO_HOME", otherHome)).To(Succeed())
+				defer os.Unsetenv("CARGO_HOME")
+
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					return os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.RunSBOMScan = false
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(cargoHome, "mtimes.json")).To(BeARegularFile())
+				Expect(filepath.Join(otherHome, "mtimes.json")).ToNot(BeAnExistingFile())
+			})
+
 			it("fails cause CARGO_HOME isn't set", func() {
-				Expect(os.Unsetenv("CARGO_HOME")).To(Succeed())
+				c.CargoHome = ""
 
 				inputLayer, err := ctx.Layers.Layer("cargo-layer")
 				Expect(err).ToNot(HaveOccurred())
 
 				_, err = c.Contribute(inputLayer)
-				Expect(err).To(MatchError(ContainSubstring("unable to find CARGO_HOME, it must be set")))
+				Expect(errors.Is(err, cargo.ErrCargoHomeNotSet)).To(BeTrue())
 
 				// app files should not be deleted
 				Expect(appFile).To(BeAnExistingFile())
@@ -589,6 +1742,101 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("target link points outside of the cache layer", func() {
+			var (
+				c           cargo.Cargo
+				externalDir string
+			)
+
+			it.Before(func() {
+				var err error
+
+				externalDir = t.TempDir()
+				Expect(os.Symlink(externalDir, filepath.Join(ctx.Application.Path, "target"))).To(Succeed())
+
+				c, err = cargo.NewCargo(
+					cargo.WithApplicationPath(ctx.Application.Path),
+					cargo.WithCargoHome(cargoHome),
+					cargo.WithCargoService(service),
+					cargo.WithSBOMScanner(sbomScanner))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			it("fails without touching the external path", func() {
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("target link points to %s which is outside of the cache layer", externalDir))))
+
+				// app files should not be deleted
+				Expect(appFile).To(BeAnExistingFile())
+
+				// preserver should not have run
+				Expect(filepath.Join(externalDir, "mtimes.json")).ToNot(BeARegularFile())
+				Expect(filepath.Join(cargoHome, "mtimes.json")).ToNot(BeARegularFile())
+
+				service.AssertNotCalled(t, "WorkspaceMembers", mock.Anything, mock.Anything)
+			})
+
+			it("fails when the link points into another layer", func() {
+				otherLayer, err := ctx.Layers.Layer("other-layer")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(os.MkdirAll(filepath.Join(otherLayer.Path, "target"), 0755)).To(Succeed())
+
+				Expect(os.Remove(filepath.Join(ctx.Application.Path, "target"))).To(Succeed())
+				Expect(os.Symlink(filepath.Join(otherLayer.Path, "target"), filepath.Join(ctx.Application.Path, "target"))).To(Succeed())
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("target link points to %s which is outside of the cache layer %s",
+					filepath.Join(otherLayer.Path, "target"), filepath.Join(ctx.Layers.Path, "Cargo Cache")))))
+
+				service.AssertNotCalled(t, "WorkspaceMembers", mock.Anything, mock.Anything)
+			})
+		})
+
+		context("target is not a link to the cache layer", func() {
+			var c cargo.Cargo
+
+			it.Before(func() {
+				var err error
+
+				c, err = cargo.NewCargo(
+					cargo.WithApplicationPath(ctx.Application.Path),
+					cargo.WithCargoHome(cargoHome),
+					cargo.WithCargoService(service),
+					cargo.WithSBOMScanner(sbomScanner))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			it("explains that a real target directory must be removed", func() {
+				targetDir := filepath.Join(ctx.Application.Path, "target")
+				Expect(os.MkdirAll(filepath.Join(targetDir, "release"), 0755)).To(Succeed())
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("unable to use %s, it is a directory and not a link to the cache layer", targetDir))))
+				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("remove %s and try again", targetDir))))
+
+				Expect(appFile).To(BeAnExistingFile())
+				Expect(filepath.Join(targetDir, "release")).To(BeADirectory())
+				service.AssertNotCalled(t, "WorkspaceMembers", mock.Anything, mock.Anything)
+			})
+
+			it("explains that the cache layer must link it first", func() {
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).To(MatchError(ContainSubstring("the Cargo Cache layer must be contributed before the application layer to link it to the cache")))
+			})
+		})
+
 		context("skip deleting certain app files", func() {
 			var (
 				c            cargo.Cargo
@@ -601,7 +1849,7 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 				var err error
 
 				cache := cargo.Cache{AppPath: ctx.Application.Path, Logger: logger}
-				cacheLayer, err = ctx.Layers.Layer("cache-layer")
+				cacheLayer, err = ctx.Layers.Layer(cargo.Cache{}.Name())
 				Expect(err).NotTo(HaveOccurred())
 				cacheLayer, err = cache.Contribute(cacheLayer)
 				Expect(err).NotTo(HaveOccurred())
@@ -609,11 +1857,13 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 				appFilesKeep = []string{
 					filepath.Join(ctx.Application.Path, "static", "index.html"),
 					filepath.Join(ctx.Application.Path, "templates", "index.html"),
+					filepath.Join(ctx.Application.Path, "config", "app.toml"),
 				}
 
 				appFilesGone = []string{
 					filepath.Join(ctx.Application.Path, "target", "stuff"),
 					filepath.Join(ctx.Application.Path, "other", "file.txt"),
+					filepath.Join(ctx.Application.Path, "config", "app.yaml"),
 				}
 
 				for _, appFile := range append(appFilesKeep, appFilesGone...) {
@@ -623,8 +1873,9 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 
 				c, err = cargo.NewCargo(
 					cargo.WithApplicationPath(ctx.Application.Path),
+					cargo.WithCargoHome(cargoHome),
 					cargo.WithCargoService(service),
-					cargo.WithIncludeFolders("static/*:templates/*"),
+					cargo.WithIncludeFolders("static/*:templates/*:config/*.toml"),
 					cargo.WithSBOMScanner(sbomScanner))
 
 				Expect(err).ToNot(HaveOccurred())
@@ -679,6 +1930,161 @@ func testCargo(t *testing.T, context spec.G, it spec.S) {
 				Expect(filepath.Join(ctx.Application.Path, "bin", "my-binary")).To(BeARegularFile())
 				Expect(filepath.Join(ctx.Application.Path, "mtimes.json")).ToNot(BeARegularFile())
 			})
+
+			it("copies the kept target paths into the application", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(cacheLayer.Path, "bindings"), 0755)).ToNot(HaveOccurred())
+					Expect(os.WriteFile(filepath.Join(cacheLayer.Path, "bindings", "api.rs"), []byte("generated"), 0644)).ToNot(HaveOccurred())
+					Expect(os.MkdirAll(filepath.Join(cacheLayer.Path, "release", "deps"), 0755)).ToNot(HaveOccurred())
+					Expect(os.WriteFile(filepath.Join(cacheLayer.Path, "release", "deps", "libapi.rlib"), []byte{}, 0644)).ToNot(HaveOccurred())
+
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					return os.WriteFile(filepath.Join(layer.Path, "bin", "my-binary"), []byte("contents"), 0644)
+				})
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.RunSBOMScan = false
+				c.KeepTarget = true
+				c.KeepTargetPaths = []string{"bindings"}
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(ctx.Application.Path, "target", "bindings", "api.rs")).To(BeARegularFile())
+				Expect(filepath.Join(ctx.Application.Path, "target", "release")).ToNot(BeAnExistingFile())
+				Expect(filepath.Join(cacheLayer.Path, "release", "deps", "libapi.rlib")).To(BeARegularFile())
+				Expect(filepath.Join(ctx.Application.Path, "other", "file.txt")).ToNot(BeAnExistingFile())
+				Expect(filepath.Join(ctx.Application.Path, "static", "index.html")).To(BeARegularFile())
+			})
+
+			it("merges the patterns from .cargoignore", func() {
+				service.On("WorkspaceMembers", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: filepath.Join(ctx.Application.Path)},
+				}, nil)
+				service.On("Install", mock.AnythingOfType("string"), mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					return os.WriteFile(filepath.Join(layer.Path, "bin", "my-binary"), []byte("contents"), 0644)
+				})
+
+				Expect(os.WriteFile(filepath.Join(ctx.Application.Path, cargo.IgnoreFile), []byte(`# keep the docs, but not the drafts
+!other
+other/draft.txt
+templates
+`), 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "other", "draft.txt"), []byte{}, 0644)).To(Succeed())
+
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.RunSBOMScan = false
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(ctx.Application.Path, "static", "index.html")).To(BeARegularFile())
+				Expect(filepath.Join(ctx.Application.Path, "config", "app.toml")).To(BeARegularFile())
+				Expect(filepath.Join(ctx.Application.Path, "other", "file.txt")).To(BeARegularFile())
+				Expect(filepath.Join(ctx.Application.Path, "other", "draft.txt")).ToNot(BeAnExistingFile())
+				Expect(filepath.Join(ctx.Application.Path, "templates")).ToNot(BeAnExistingFile())
+				Expect(filepath.Join(ctx.Application.Path, "target")).ToNot(BeAnExistingFile())
+				Expect(filepath.Join(ctx.Application.Path, cargo.IgnoreFile)).ToNot(BeAnExistingFile())
+				Expect(filepath.Join(ctx.Application.Path, "bin", "my-binary")).To(BeARegularFile())
+			})
+		})
+
+		context("the project is in a subdirectory", func() {
+			var (
+				c          cargo.Cargo
+				cacheLayer libcnb.Layer
+				backend    string
+			)
+
+			it.Before(func() {
+				var err error
+
+				backend = filepath.Join(ctx.Application.Path, "backend")
+				Expect(os.MkdirAll(filepath.Join(backend, "src"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(backend, "src", "main.rs"), []byte{}, 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "frontend"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "frontend", "index.js"), []byte{}, 0644)).To(Succeed())
+
+				cache := cargo.Cache{AppPath: backend, Logger: logger}
+				cacheLayer, err = ctx.Layers.Layer(cargo.Cache{}.Name())
+				Expect(err).NotTo(HaveOccurred())
+				cacheLayer, err = cache.Contribute(cacheLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				service.On("WorkspaceMembers", backend, mock.AnythingOfType("libcnb.Layer")).Return([]url.URL{
+					{Scheme: "file", Path: backend},
+				}, nil)
+				service.On("Install", backend, mock.AnythingOfType("libcnb.Layer")).Return(func(srcDir string, layer libcnb.Layer) error {
+					Expect(os.MkdirAll(filepath.Join(cacheLayer.Path, "bindings"), 0755)).ToNot(HaveOccurred())
+					Expect(os.WriteFile(filepath.Join(cacheLayer.Path, "bindings", "api.rs"), []byte("generated"), 0644)).ToNot(HaveOccurred())
+
+					Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).ToNot(HaveOccurred())
+					return os.WriteFile(filepath.Join(layer.Path, "bin", "my-binary"), []byte("contents"), 0644)
+				})
+				service.On("ProjectTargets", backend).Return([]string{"my-binary"}, nil)
+
+				c, err = cargo.NewCargo(
+					cargo.WithApplicationPath(ctx.Application.Path),
+					cargo.WithCargoHome(cargoHome),
+					cargo.WithCargoService(service),
+					cargo.WithProjectPath(backend),
+					cargo.WithSBOMScanner(sbomScanner))
+				Expect(err).ToNot(HaveOccurred())
+
+				c.Processes, err = c.BuildProcessTypes(false)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			it("builds the project and cleans up the whole application", func() {
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				sbomScanner.On("ScanLayer", inputLayer, backend, libcnb.CycloneDXJSON, libcnb.SyftJSON).Return(nil)
+				c.RunSBOMScan = true
+				c.WriteProcfile = true
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				service.AssertCalled(t, "Install", backend, mock.AnythingOfType("libcnb.Layer"))
+				sbomScanner.AssertCalled(t, "ScanLayer", inputLayer, backend, libcnb.CycloneDXJSON, libcnb.SyftJSON)
+
+				Expect(appFile).ToNot(BeAnExistingFile())
+				Expect(filepath.Join(ctx.Application.Path, "frontend")).ToNot(BeAnExistingFile())
+				Expect(backend).ToNot(BeAnExistingFile())
+
+				Expect(filepath.Join(ctx.Application.Path, "bin", "my-binary")).To(BeARegularFile())
+				Expect(c.Processes[0].Command).To(Equal(filepath.Join(ctx.Application.Path, "bin", "my-binary")))
+
+				procfile, err := os.ReadFile(filepath.Join(ctx.Application.Path, "Procfile"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(procfile)).To(ContainSubstring(filepath.Join(ctx.Application.Path, "bin", "my-binary")))
+			})
+
+			it("keeps the target directory of the project", func() {
+				inputLayer, err := ctx.Layers.Layer("cargo-layer")
+				Expect(err).ToNot(HaveOccurred())
+
+				c.RunSBOMScan = false
+				c.KeepTarget = true
+				c.KeepTargetPaths = []string{"bindings"}
+
+				_, err = c.Contribute(inputLayer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(backend, "target", "bindings", "api.rs")).To(BeARegularFile())
+				Expect(filepath.Join(backend, "src")).ToNot(BeAnExistingFile())
+				Expect(filepath.Join(ctx.Application.Path, "frontend")).ToNot(BeAnExistingFile())
+				Expect(filepath.Join(ctx.Application.Path, "bin", "my-binary")).To(BeARegularFile())
+			})
 		})
 	})
 }
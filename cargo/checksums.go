@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-community/cargo/mtimes"
+)
+
+// ChecksumFileExtension is the extension of the sidecar files holding the checksum of a binary
+const ChecksumFileExtension = ".sha256"
+
+// ChecksumsDir is the directory of the application layer holding the checksum files, it is kept out of `bin` so the
+// checksum files are not linked into the application like binaries
+const ChecksumsDir = "checksums"
+
+// BinaryChecksum is the SHA-256 checksum of an installed binary
+type BinaryChecksum struct {
+	Name     string
+	Checksum string
+}
+
+// BinaryChecksums calculates the SHA-256 checksum of every binary in binDir, sorted by name
+func BinaryChecksums(binDir string) ([]BinaryChecksum, error) {
+	entries, err := os.ReadDir(binDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to read %s\n%w", binDir, err)
+	}
+
+	var checksums []BinaryChecksum
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasSuffix(entry.Name(), ChecksumFileExtension) {
+			continue
+		}
+
+		sum, err := mtimes.Checksum(filepath.Join(binDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to calculate checksum\n%w", err)
+		}
+
+		checksums = append(checksums, BinaryChecksum{Name: entry.Name(), Checksum: sum})
+	}
+
+	sort.Slice(checksums, func(i, j int) bool {
+		return checksums[i].Name < checksums[j].Name
+	})
+
+	return checksums, nil
+}
+
+// WriteChecksumFiles writes a `<bin>.sha256` file for every binary into dir, in the format used by `sha256sum`
+func WriteChecksumFiles(dir string, checksums []BinaryChecksum) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("unable to create %s\n%w", dir, err)
+	}
+
+	for _, c := range checksums {
+		path := filepath.Join(dir, c.Name+ChecksumFileExtension)
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("%s  %s\n", c.Checksum, c.Name)), 0644); err != nil {
+			return fmt.Errorf("unable to write %s\n%w", path, err)
+		}
+	}
+
+	return nil
+}
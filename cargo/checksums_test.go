@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-community/cargo/cargo"
+	"github.com/sclevine/spec"
+)
+
+func testChecksums(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		binDir string
+	)
+
+	it.Before(func() {
+		binDir = t.TempDir()
+		Expect(os.WriteFile(filepath.Join(binDir, "web"), []byte("web binary"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binDir, "app"), []byte{}, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binDir, "app.sha256"), []byte("stale"), 0644)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(binDir, "sub"), 0755)).To(Succeed())
+	})
+
+	it("calculates the checksum of every binary", func() {
+		checksums, err := cargo.BinaryChecksums(binDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(checksums).To(Equal([]cargo.BinaryChecksum{
+			{Name: "app", Checksum: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+			{Name: "web", Checksum: "143e5ce3b7c2407fa6c750774990691d0933d159cdf3158cefd049f1b86926f1"},
+		}))
+	})
+
+	it("writes checksum files", func() {
+		checksums, err := cargo.BinaryChecksums(binDir)
+		Expect(err).ToNot(HaveOccurred())
+		checksumsDir := filepath.Join(t.TempDir(), "checksums")
+		Expect(cargo.WriteChecksumFiles(checksumsDir, checksums)).To(Succeed())
+
+		contents, err := os.ReadFile(filepath.Join(checksumsDir, "app.sha256"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(contents)).To(Equal("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  app\n"))
+	})
+
+	it("returns nothing without binaries", func() {
+		checksums, err := cargo.BinaryChecksums(filepath.Join(binDir, "missing"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(checksums).To(BeEmpty())
+	})
+}
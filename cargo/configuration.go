@@ -0,0 +1,288 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/mattn/go-shellwords"
+	"github.com/paketo-buildpacks/libpak"
+)
+
+// ProjectConfigurationKey is the key under `[package.metadata]` or `[workspace.metadata]` in Cargo.toml which holds
+// buildpack configuration
+const ProjectConfigurationKey = "cargo-buildpack"
+
+// ProjectConfigurationResolver resolves configuration from the environment first, then from the project's Cargo.toml
+// and finally from the buildpack defaults
+type ProjectConfigurationResolver struct {
+	Resolver libpak.ConfigurationResolver
+	Project  map[string]string
+
+	// Forwarded holds values of deprecated configuration names forwarded to their replacements, they take precedence
+	Forwarded map[string]string
+}
+
+type projectMetadata struct {
+	Metadata map[string]interface{} `toml:"metadata"`
+}
+
+type projectManifest struct {
+	Package   projectMetadata `toml:"package"`
+	Workspace projectMetadata `toml:"workspace"`
+}
+
+// NewProjectConfigurationResolver reads `BP_CARGO_*` configuration from the Cargo.toml in applicationPath. Keys are
+// mapped to configuration names by upper casing them, replacing `-` with `_` and prefixing `BP_CARGO_`, so
+// `install-args` maps to `BP_CARGO_INSTALL_ARGS`. The exception is `disable-sbom`, which maps to `BP_DISABLE_SBOM`. Package settings take precedence over workspace settings.
+// A key which is not a configuration of the buildpack, or which can only be set in the environment, fails so that a
+// typo is not silently ignored.
+func NewProjectConfigurationResolver(resolver libpak.ConfigurationResolver, applicationPath string) (ProjectConfigurationResolver, error) {
+	p := ProjectConfigurationResolver{Resolver: resolver, Project: map[string]string{}}
+
+	var manifest projectManifest
+	if _, err := toml.DecodeFile(filepath.Join(applicationPath, "Cargo.toml"), &manifest); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return p, nil
+		}
+		return ProjectConfigurationResolver{}, fmt.Errorf("unable to decode Cargo.toml\n%w", err)
+	}
+
+	for _, metadata := range []projectMetadata{manifest.Workspace, manifest.Package} {
+		raw, ok := metadata.Metadata[ProjectConfigurationKey]
+		if !ok {
+			continue
+		}
+
+		settings, ok := raw.(map[string]interface{})
+		if !ok {
+			return ProjectConfigurationResolver{}, fmt.Errorf("unable to read metadata.%s, expected a table but got %T", ProjectConfigurationKey, raw)
+		}
+
+		for key, value := range settings {
+			// feature requirements are only used by detection
+			if key == ProjectRequiresKey {
+				continue
+			}
+
+			name := ProjectConfigurationName(key)
+			if slices.Contains(environmentOnlyConfigurations, name) {
+				return ProjectConfigurationResolver{}, fmt.Errorf("unable to read metadata.%s.%s, %s can only be set in the environment", ProjectConfigurationKey, key, name)
+			} else if !isConfiguration(resolver, name) {
+				return ProjectConfigurationResolver{}, fmt.Errorf("unable to read metadata.%s.%s, %s is not a configuration of the buildpack", ProjectConfigurationKey, key, name)
+			}
+
+			switch v := value.(type) {
+			case string, bool, int64:
+				p.Project[name] = fmt.Sprint(v)
+			default:
+				return ProjectConfigurationResolver{}, fmt.Errorf("unable to read metadata.%s.%s, unsupported type %T", ProjectConfigurationKey, key, value)
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// projectConfigurationAliases maps Cargo.toml metadata keys to configuration names that do not follow the
+// `BP_CARGO_*` naming scheme
+var projectConfigurationAliases = map[string]string{
+	"disable-sbom": "BP_DISABLE_SBOM",
+}
+
+// environmentOnlyConfigurations are read before Cargo.toml is found, so they cannot be set in it
+var environmentOnlyConfigurations = []string{"BP_CARGO_PROJECT_DIR"}
+
+// isConfiguration checks if name is declared in buildpack.toml or is a deprecated name which is still forwarded
+func isConfiguration(resolver libpak.ConfigurationResolver, name string) bool {
+	for _, c := range resolver.Configurations {
+		if c.Name == name {
+			return true
+		}
+	}
+
+	for _, d := range DeprecatedConfigurations {
+		if d.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProjectConfigurationName maps a Cargo.toml metadata key to its configuration name, usually `BP_CARGO_*`
+func ProjectConfigurationName(key string) string {
+	if name, ok := projectConfigurationAliases[key]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("BP_CARGO_%s", strings.ToUpper(strings.ReplaceAll(key, "-", "_")))
+}
+
+// Resolve returns the value of name and whether it was explicitly set, either forwarded from a deprecated name, in the
+// environment or in Cargo.toml
+func (p ProjectConfigurationResolver) Resolve(name string) (string, bool) {
+	if value, ok := p.Forwarded[name]; ok {
+		return value, true
+	}
+
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+
+	if value, ok := p.Project[name]; ok {
+		return value, true
+	}
+
+	return p.Resolver.Resolve(name)
+}
+
+// ResolveBool returns the value of name as a boolean, values that cannot be parsed are treated as false
+func (p ProjectConfigurationResolver) ResolveBool(name string) bool {
+	value, _ := p.Resolve(name)
+	if value == "" {
+		return false
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false
+	}
+
+	return b
+}
+
+// ParseInstallArgsPerStack parses a `;` separated list of `<stack-id>=<arguments>` entries into a map of stack id to
+// install arguments
+func ParseInstallArgsPerStack(raw string) (map[string]string, error) {
+	perStack := map[string]string{}
+
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		stack, args, found := strings.Cut(entry, "=")
+		stack = strings.TrimSpace(stack)
+		if !found || stack == "" {
+			return nil, fmt.Errorf("unable to parse %q, expected <stack-id>=<arguments>", entry)
+		}
+
+		perStack[stack] = strings.TrimSpace(args)
+	}
+
+	return perStack, nil
+}
+
+// ParseInstallTools parses a space separated list of tools into their names and a map of tool name to the version it
+// is pinned to with `<name>@<version>`. A partial version like `0.12` becomes the requirement `~0.12`, cargo only
+// accepts complete versions without a requirement operator.
+func ParseInstallTools(raw string) ([]string, map[string]string, error) {
+	entries, err := shellwords.Parse(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse %q\n%w", raw, err)
+	}
+
+	names := []string{}
+	versions := map[string]string{}
+	for _, entry := range entries {
+		name, version, found := strings.Cut(entry, "@")
+		if name == "" || found && version == "" {
+			return nil, nil, fmt.Errorf("unable to parse %q, expected <name> or <name>@<version>", entry)
+		}
+
+		names = append(names, name)
+		if found {
+			if version[0] >= '0' && version[0] <= '9' && strings.Count(version, ".") < 2 {
+				version = "~" + version
+			}
+			versions[name] = version
+		}
+	}
+
+	return names, versions, nil
+}
+
+// ConfiguredTarget reads `build.target` from the project's `.cargo/config.toml`, or the legacy `.cargo/config`, and
+// returns an empty string if no target is configured
+func ConfiguredTarget(applicationPath string) (string, error) {
+	for _, name := range []string{"config.toml", "config"} {
+		path := filepath.Join(applicationPath, ".cargo", name)
+
+		var config struct {
+			Build struct {
+				Target interface{} `toml:"target"`
+			} `toml:"build"`
+		}
+
+		if _, err := toml.DecodeFile(path, &config); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return "", fmt.Errorf("unable to decode %s\n%w", path, err)
+		}
+
+		switch target := config.Build.Target.(type) {
+		case nil:
+			return "", nil
+		case string:
+			return target, nil
+		case []interface{}:
+			if len(target) == 0 {
+				return "", nil
+			}
+
+			if t, ok := target[0].(string); ok && len(target) == 1 {
+				return t, nil
+			}
+
+			return "", fmt.Errorf("unable to use build.target %v from %s, only a single target is supported, set BP_CARGO_TARGET to pick one", target, path)
+		default:
+			return "", fmt.Errorf("unable to read build.target from %s, unsupported type %T", path, target)
+		}
+	}
+
+	return "", nil
+}
+
+// ProjectDir returns the directory of the Rust project, dir relative to applicationPath or applicationPath itself if
+// dir is empty. The directory must be inside of the application.
+func ProjectDir(applicationPath string, dir string) (string, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return applicationPath, nil
+	}
+
+	if filepath.IsAbs(dir) {
+		return "", fmt.Errorf("unable to use %s, it must be relative to the application directory", dir)
+	}
+
+	projectDir := filepath.Join(applicationPath, dir)
+	if projectDir != applicationPath && !isWithin(applicationPath, projectDir) {
+		return "", fmt.Errorf("unable to use %s, it is outside of the application directory", dir)
+	}
+
+	return projectDir, nil
+}
@@ -0,0 +1,275 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-community/cargo/cargo"
+	"github.com/sclevine/spec"
+)
+
+func testConfiguration(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		appDir   string
+		resolver libpak.ConfigurationResolver
+	)
+
+	it.Before(func() {
+		appDir = t.TempDir()
+
+		resolver = libpak.ConfigurationResolver{
+			Configurations: []libpak.BuildpackConfiguration{
+				{Name: "BP_CARGO_FEATURES"},
+				{Name: "BP_CARGO_INSTALL_ARGS", Default: "--locked"},
+				{Name: "BP_CARGO_PROJECT_DIR"},
+				{Name: "BP_CARGO_TINI_DISABLED", Default: "false"},
+				{Name: "BP_CARGO_WORKSPACE_MEMBERS"},
+				{Name: "BP_DISABLE_SBOM", Default: "false"},
+			},
+		}
+	})
+
+	context("no Cargo.toml", func() {
+		it("uses the buildpack defaults", func() {
+			cr, err := cargo.NewProjectConfigurationResolver(resolver, appDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			value, ok := cr.Resolve("BP_CARGO_INSTALL_ARGS")
+			Expect(ok).To(BeFalse())
+			Expect(value).To(Equal("--locked"))
+			Expect(cr.ResolveBool("BP_CARGO_TINI_DISABLED")).To(BeFalse())
+		})
+	})
+
+	context("Cargo.toml with buildpack metadata", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte(`
+[package]
+name = "todo"
+
+[package.metadata.cargo-buildpack]
+install-args = "--path=./todo"
+tini-disabled = true
+
+[workspace.metadata.cargo-buildpack]
+install-args = "--bins"
+workspace-members = "basics,todo"
+`), 0644)).To(Succeed())
+		})
+
+		it("applies values from the file", func() {
+			cr, err := cargo.NewProjectConfigurationResolver(resolver, appDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			value, ok := cr.Resolve("BP_CARGO_INSTALL_ARGS")
+			Expect(ok).To(BeTrue())
+			Expect(value).To(Equal("--path=./todo"))
+
+			value, ok = cr.Resolve("BP_CARGO_WORKSPACE_MEMBERS")
+			Expect(ok).To(BeTrue())
+			Expect(value).To(Equal("basics,todo"))
+
+			Expect(cr.ResolveBool("BP_CARGO_TINI_DISABLED")).To(BeTrue())
+		})
+
+		context("environment variables are set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_INSTALL_ARGS", "--offline")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_TINI_DISABLED", "false")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_INSTALL_ARGS")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_TINI_DISABLED")).To(Succeed())
+			})
+
+			it("prefers the environment", func() {
+				cr, err := cargo.NewProjectConfigurationResolver(resolver, appDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				value, ok := cr.Resolve("BP_CARGO_INSTALL_ARGS")
+				Expect(ok).To(BeTrue())
+				Expect(value).To(Equal("--offline"))
+
+				Expect(cr.ResolveBool("BP_CARGO_TINI_DISABLED")).To(BeFalse())
+			})
+		})
+	})
+
+	context("install args per stack", func() {
+		it("parses stack ids and arguments", func() {
+			perStack, err := cargo.ParseInstallArgsPerStack("io.buildpacks.stacks.jammy.tiny=--target x86_64-unknown-linux-musl --features=a ; io.buildpacks.stacks.jammy=")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(perStack).To(Equal(map[string]string{
+				"io.buildpacks.stacks.jammy.tiny": "--target x86_64-unknown-linux-musl --features=a",
+				"io.buildpacks.stacks.jammy":      "",
+			}))
+		})
+
+		it("returns an empty map for an empty value", func() {
+			perStack, err := cargo.ParseInstallArgsPerStack("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(perStack).To(BeEmpty())
+		})
+
+		it("fails on entries without a stack id", func() {
+			_, err := cargo.ParseInstallArgsPerStack("--locked")
+			Expect(err).To(MatchError(ContainSubstring(`unable to parse "--locked"`)))
+		})
+	})
+
+	context("BP_CARGO_INSTALL_TOOLS", func() {
+		it("parses tools with and without versions", func() {
+			names, versions, err := cargo.ParseInstallTools("cargo-audit@0.17.0  wasm-pack@0.12 cargo-bloat diesel_cli@~2.1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(Equal([]string{"cargo-audit", "wasm-pack", "cargo-bloat", "diesel_cli"}))
+			Expect(versions).To(Equal(map[string]string{
+				"cargo-audit": "0.17.0",
+				"wasm-pack":   "~0.12",
+				"diesel_cli":  "~2.1",
+			}))
+		})
+
+		it("returns no tools for an empty value", func() {
+			names, versions, err := cargo.ParseInstallTools("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(BeEmpty())
+			Expect(versions).To(BeEmpty())
+		})
+
+		it("fails on entries without a name or version", func() {
+			_, _, err := cargo.ParseInstallTools("@1.0.0")
+			Expect(err).To(MatchError(ContainSubstring(`unable to parse "@1.0.0"`)))
+
+			_, _, err = cargo.ParseInstallTools("cargo-audit@")
+			Expect(err).To(MatchError(ContainSubstring(`unable to parse "cargo-audit@"`)))
+		})
+	})
+
+	it("maps disable-sbom to BP_DISABLE_SBOM", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte(`
+[package.metadata.cargo-buildpack]
+disable-sbom = true
+`), 0644)).To(Succeed())
+
+		cr, err := cargo.NewProjectConfigurationResolver(resolver, appDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cr.ResolveBool("BP_DISABLE_SBOM")).To(BeTrue())
+		Expect(cr.Project).NotTo(HaveKey("BP_CARGO_DISABLE_SBOM"))
+	})
+
+	it("ignores feature requirements", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte(`
+[package.metadata.cargo-buildpack]
+features = "tls"
+
+[package.metadata.cargo-buildpack.requires]
+tls = ["openssl"]
+`), 0644)).To(Succeed())
+
+		cr, err := cargo.NewProjectConfigurationResolver(resolver, appDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cr.Project).To(Equal(map[string]string{"BP_CARGO_FEATURES": "tls"}))
+	})
+
+	it("fails on unsupported values", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte(`
+[package.metadata.cargo-buildpack]
+install-args = ["--locked"]
+`), 0644)).To(Succeed())
+
+		_, err := cargo.NewProjectConfigurationResolver(resolver, appDir)
+		Expect(err).To(MatchError(ContainSubstring("unable to read metadata.cargo-buildpack.install-args")))
+	})
+
+	it("fails on unknown keys", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte(`
+[package.metadata.cargo-buildpack]
+instal-args = "--locked"
+`), 0644)).To(Succeed())
+
+		_, err := cargo.NewProjectConfigurationResolver(resolver, appDir)
+		Expect(err).To(MatchError(ContainSubstring("unable to read metadata.cargo-buildpack.instal-args, BP_CARGO_INSTAL_ARGS is not a configuration of the buildpack")))
+	})
+
+	it("accepts deprecated keys", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte(`
+[workspace.metadata.cargo-buildpack]
+exclude-folders = "docs"
+`), 0644)).To(Succeed())
+
+		cr, err := cargo.NewProjectConfigurationResolver(resolver, appDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cr.Project).To(Equal(map[string]string{"BP_CARGO_EXCLUDE_FOLDERS": "docs"}))
+	})
+
+	it("fails on keys which can only be set in the environment", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte(`
+[package.metadata.cargo-buildpack]
+project-dir = "backend"
+`), 0644)).To(Succeed())
+
+		_, err := cargo.NewProjectConfigurationResolver(resolver, appDir)
+		Expect(err).To(MatchError(ContainSubstring("unable to read metadata.cargo-buildpack.project-dir, BP_CARGO_PROJECT_DIR can only be set in the environment")))
+	})
+	context("configured build target", func() {
+		it("reads build.target from .cargo/config.toml", func() {
+			target, err := cargo.ConfiguredTarget("testdata/configured-target")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(Equal("x86_64-unknown-linux-musl"))
+		})
+
+		it("reads build.target from the legacy .cargo/config", func() {
+			Expect(os.MkdirAll(filepath.Join(appDir, ".cargo"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(appDir, ".cargo", "config"), []byte("[build]\ntarget = [\"aarch64-unknown-linux-gnu\"]\n"), 0644)).To(Succeed())
+
+			target, err := cargo.ConfiguredTarget(appDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(Equal("aarch64-unknown-linux-gnu"))
+		})
+
+		it("returns an empty target when none is configured", func() {
+			target, err := cargo.ConfiguredTarget(appDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(BeEmpty())
+
+			Expect(os.MkdirAll(filepath.Join(appDir, ".cargo"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(appDir, ".cargo", "config.toml"), []byte("[net]\noffline = true\n"), 0644)).To(Succeed())
+
+			target, err = cargo.ConfiguredTarget(appDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(BeEmpty())
+		})
+
+		it("fails with multiple targets", func() {
+			Expect(os.MkdirAll(filepath.Join(appDir, ".cargo"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(appDir, ".cargo", "config.toml"),
+				[]byte("[build]\ntarget = [\"x86_64-unknown-linux-gnu\", \"x86_64-unknown-linux-musl\"]\n"), 0644)).To(Succeed())
+
+			_, err := cargo.ConfiguredTarget(appDir)
+			Expect(err).To(MatchError(ContainSubstring("only a single target is supported, set BP_CARGO_TARGET to pick one")))
+		})
+	})
+}
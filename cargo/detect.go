@@ -17,23 +17,56 @@
 package cargo
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 
+	"github.com/BurntSushi/toml"
 	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak"
 )
 
 const (
+	PlanEntryCC        = "cc"
 	PlanEntryRustCargo = "rust-cargo"
 	PlanEntrySyft      = "syft"
 )
 
+// CCCrates are crates whose build scripts compile C code or look for C libraries, so they need a C compiler
+var CCCrates = []string{
+	"cc",
+	"cmake",
+	"libsqlite3-sys",
+	"libz-sys",
+	"openssl-sys",
+	"pkg-config",
+	"ring",
+	"zstd-sys",
+}
+
 type Detect struct {
+	Clock Clock
 }
 
 func (d Detect) Detect(context libcnb.DetectContext) (libcnb.DetectResult, error) {
-	found, err := d.cargoProject(context.Application.Path)
+	start := d.Clock.Now()
+
+	bcr, err := libpak.NewConfigurationResolver(context.Buildpack, nil)
+	if err != nil {
+		return libcnb.DetectResult{}, fmt.Errorf("unable to create configuration resolver\n%w", err)
+	}
+
+	// the project directory can't be configured in Cargo.toml, which is found through it
+	projectDirRaw, _ := bcr.Resolve("BP_CARGO_PROJECT_DIR")
+	projectDir, err := ProjectDir(context.Application.Path, projectDirRaw)
+	if err != nil {
+		return libcnb.DetectResult{}, fmt.Errorf("unable to use BP_CARGO_PROJECT_DIR\n%w", err)
+	}
+
+	found, err := d.cargoProject(projectDir)
 	if err != nil {
 		return libcnb.DetectResult{}, fmt.Errorf("unable to detect cargo requirements\n%w", err)
 	}
@@ -42,37 +75,159 @@ func (d Detect) Detect(context libcnb.DetectContext) (libcnb.DetectResult, error
 		return libcnb.DetectResult{Pass: false}, nil
 	}
 
-	return libcnb.DetectResult{
-		Pass: true,
-		Plans: []libcnb.BuildPlan{
-			{
-				Provides: []libcnb.BuildPlanProvide{
-					{Name: PlanEntryRustCargo},
-				},
-				Requires: []libcnb.BuildPlanRequire{
-					{Name: PlanEntrySyft},
-					{Name: PlanEntryRustCargo},
-					{Name: "rust"},
-				},
-			},
+	cr, err := NewProjectConfigurationResolver(bcr, projectDir)
+	if err != nil {
+		return libcnb.DetectResult{}, fmt.Errorf("unable to read project configuration\n%w", err)
+	}
+
+	if raw, _ := cr.Resolve("BP_CARGO_ENABLED"); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return libcnb.DetectResult{}, fmt.Errorf("unable to parse BP_CARGO_ENABLED=%q\n%w", raw, err)
+		}
+
+		if !enabled {
+			return libcnb.DetectResult{Pass: false}, nil
+		}
+	}
+
+	requires := []libcnb.BuildPlanRequire{
+		{Name: PlanEntryRustCargo},
+		{Name: "rust"},
+	}
+
+	// syft is only needed to scan the layers for an SBOM, and not by the native scanner
+	scanner, _ := cr.Resolve("BP_CARGO_SBOM_SCANNER")
+	if !cr.ResolveBool("BP_DISABLE_SBOM") && (scanner == "" || scanner == SBOMScannerSyft) {
+		requires = append([]libcnb.BuildPlanRequire{{Name: PlanEntrySyft}}, requires...)
+	}
+
+	// only Cargo.toml is read, running `cargo metadata` would be too slow and cargo may not be installed yet
+	features, _ := cr.Resolve("BP_CARGO_FEATURES")
+	entries, err := FeatureRequirements(projectDir, ParseFeatures(features))
+	if err != nil {
+		return libcnb.DetectResult{}, fmt.Errorf("unable to read feature requirements\n%w", err)
+	}
+
+	for _, entry := range entries {
+		requires = append(requires, libcnb.BuildPlanRequire{Name: entry})
+	}
+
+	// detect runs in its own process, so its span is handed to the build through the plan entry
+	if cr.ResolveBool("BP_CARGO_OTEL") {
+		for i := range requires {
+			if requires[i].Name == PlanEntryRustCargo {
+				requires[i].Metadata = DetectSpanMetadata(start, d.Clock.Now())
+			}
+		}
+	}
+
+	plan := libcnb.BuildPlan{
+		Provides: []libcnb.BuildPlanProvide{
+			{Name: PlanEntryRustCargo},
 		},
-	}, nil
+		Requires: requires,
+	}
+
+	ccPlan := plan
+	ccPlan.Requires = append(slices.Clone(requires), libcnb.BuildPlanRequire{
+		Name:     PlanEntryCC,
+		Metadata: map[string]interface{}{"build": true},
+	})
+
+	// an explicit BP_CARGO_REQUIRE_CC decides, otherwise a C compiler is only requested if a crate in Cargo.lock needs
+	// one, with a fallback plan without it for builders that don't have a C buildpack
+	if raw, _ := cr.Resolve("BP_CARGO_REQUIRE_CC"); raw != "" {
+		requireCC, err := strconv.ParseBool(raw)
+		if err != nil {
+			return libcnb.DetectResult{}, fmt.Errorf("unable to parse BP_CARGO_REQUIRE_CC=%q\n%w", raw, err)
+		}
+
+		if requireCC {
+			return libcnb.DetectResult{Pass: true, Plans: []libcnb.BuildPlan{ccPlan}}, nil
+		}
+		return libcnb.DetectResult{Pass: true, Plans: []libcnb.BuildPlan{plan}}, nil
+	}
+
+	needsCC, err := NeedsCC(projectDir)
+	if err != nil {
+		return libcnb.DetectResult{}, fmt.Errorf("unable to check if a C compiler is needed\n%w", err)
+	}
+
+	if needsCC {
+		return libcnb.DetectResult{Pass: true, Plans: []libcnb.BuildPlan{ccPlan, plan}}, nil
+	}
+
+	return libcnb.DetectResult{Pass: true, Plans: []libcnb.BuildPlan{plan}}, nil
+}
+
+// NeedsCC checks if the Cargo.lock in projectDir has one of the CCCrates. A project without a Cargo.lock, like a
+// virtual workspace with the Cargo.lock files in its members, doesn't need one.
+func NeedsCC(projectDir string) (bool, error) {
+	lockPath := filepath.Join(projectDir, "Cargo.lock")
+	if _, err := os.Stat(lockPath); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	packages, err := ParseCargoLock(lockPath)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.ContainsFunc(packages, func(p LockPackage) bool { return slices.Contains(CCCrates, p.Name) }), nil
 }
 
 func (d Detect) cargoProject(appDir string) (bool, error) {
-	_, err := os.Stat(filepath.Join(appDir, "Cargo.toml"))
+	manifest, err := os.ReadFile(filepath.Join(appDir, "Cargo.toml"))
 	if os.IsNotExist(err) {
 		return false, nil
 	} else if err != nil {
 		return false, fmt.Errorf("unable to determine if Cargo.toml exists\n%w", err)
 	}
 
-	_, err = os.Stat(filepath.Join(appDir, "Cargo.lock"))
+	m, ok := parseManifest(manifest)
+	if !ok {
+		return false, nil
+	}
+
+	lock, err := os.ReadFile(filepath.Join(appDir, "Cargo.lock"))
 	if os.IsNotExist(err) {
+		// a virtual workspace may keep its Cargo.lock files inside the members
+		if m.Package == nil && m.Workspace != nil {
+			members, err := expandMembers(appDir, m.Workspace.Members, m.Workspace.Exclude)
+			if err != nil {
+				return false, fmt.Errorf("unable to find workspace members\n%w", err)
+			}
+			return len(members) > 0, nil
+		}
 		return false, nil
 	} else if err != nil {
 		return false, fmt.Errorf("unable to determine if Cargo.lock exists\n%w", err)
 	}
 
-	return true, nil
+	// placeholder files pass the checks above, but Cargo will either regenerate an empty
+	// Cargo.lock or fail to build with an empty Cargo.toml, so don't claim those projects
+	if len(bytes.TrimSpace(lock)) == 0 {
+		return false, nil
+	}
+
+	return m.Package != nil || m.Workspace != nil, nil
+}
+
+type detectManifest struct {
+	Package   map[string]interface{} `toml:"package"`
+	Workspace *struct {
+		Members []string `toml:"members"`
+		Exclude []string `toml:"exclude"`
+	} `toml:"workspace"`
+}
+
+// parseManifest decodes the parts of a Cargo.toml that detect needs
+func parseManifest(manifest []byte) (detectManifest, bool) {
+	var m detectManifest
+	if _, err := toml.Decode(string(manifest), &m); err != nil {
+		return detectManifest{}, false
+	}
+
+	return m, true
 }
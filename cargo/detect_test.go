@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/paketo-community/cargo/cargo"
@@ -28,6 +29,19 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+const (
+	manifestFile = `[package]
+name = "hello"
+version = "0.1.0"
+`
+	lockFile = `version = 3
+
+[[package]]
+name = "hello"
+version = "0.1.0"
+`
+)
+
 func testDetect(t *testing.T, context spec.G, it spec.S) {
 	var (
 		Expect = NewWithT(t).Expect
@@ -41,6 +55,13 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 
 		ctx.Application.Path = t.TempDir()
 		Expect(err).ToNot(HaveOccurred())
+
+		ctx.Buildpack.Metadata = map[string]interface{}{
+			"configurations": []map[string]interface{}{
+				{"name": "BP_CARGO_ENABLED"},
+				{"name": "BP_DISABLE_SBOM", "default": "false"},
+			},
+		}
 	})
 
 	it.After(func() {
@@ -71,9 +92,98 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("placeholder files", func() {
+		it("fails with an empty Cargo.toml", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(lockFile), 0644)).To(Succeed())
+
+			plan, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plan).To(Equal(libcnb.DetectResult{}))
+		})
+
+		it("fails with an empty Cargo.lock", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte(manifestFile), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(" \n"), 0644)).To(Succeed())
+
+			plan, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plan).To(Equal(libcnb.DetectResult{}))
+		})
+
+		it("fails with a Cargo.toml that does not parse", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte("[package\nname = "), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(lockFile), 0644)).To(Succeed())
+
+			plan, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plan).To(Equal(libcnb.DetectResult{}))
+		})
+
+		it("fails with a Cargo.toml without package or workspace", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte("[dependencies]\nserde = \"1\"\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(lockFile), 0644)).To(Succeed())
+
+			plan, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plan).To(Equal(libcnb.DetectResult{}))
+		})
+	})
+
+	it("passes with a minimal workspace", func() {
+		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte("[workspace]\nmembers = [\"api\"]\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(lockFile), 0644)).To(Succeed())
+
+		result, err := detect.Detect(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Pass).To(BeTrue())
+	})
+
+	context("virtual workspace without a Cargo.lock", func() {
+		it.Before(func() {
+			for _, file := range []string{"Cargo.toml", "crates/api/Cargo.toml", "crates/worker/Cargo.toml"} {
+				content, err := os.ReadFile(filepath.Join("testdata/virtual-workspace", file))
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(os.MkdirAll(filepath.Dir(filepath.Join(ctx.Application.Path, file)), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(ctx.Application.Path, file), content, 0644)).To(Succeed())
+			}
+		})
+
+		it("passes when a member has a Cargo.toml", func() {
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Pass).To(BeTrue())
+		})
+
+		it("passes with an explicit member", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte("[workspace]\nmembers = [\"missing\", \"crates/api\"]\n"), 0644)).To(Succeed())
+
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Pass).To(BeTrue())
+		})
+
+		it("fails when no member has a Cargo.toml", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte("[workspace]\nmembers = [\"missing\"]\n"), 0644)).To(Succeed())
+
+			plan, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plan).To(Equal(libcnb.DetectResult{}))
+		})
+
+		it("fails when the root also has a package", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte(manifestFile+"\n[workspace]\nmembers = [\"crates/*\"]\n"), 0644)).To(Succeed())
+
+			plan, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plan).To(Equal(libcnb.DetectResult{}))
+		})
+	})
+
 	it("passes with both Cargo.toml and Cargo.lock", func() {
-		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte{}, 0644))
-		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte{}, 0644))
+		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte(manifestFile), 0644))
+		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(lockFile), 0644))
 
 		Expect(detect.Detect(ctx)).To(Equal(libcnb.DetectResult{
 			Pass: true,
@@ -91,4 +201,276 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 			},
 		}))
 	})
+	context("the project is in a subdirectory", func() {
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "backend"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "backend", "Cargo.toml"), []byte(manifestFile), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "backend", "Cargo.lock"), []byte(lockFile), 0644)).To(Succeed())
+		})
+
+		it("does not detect without BP_CARGO_PROJECT_DIR", func() {
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Pass).To(BeFalse())
+		})
+
+		it("detects the project in BP_CARGO_PROJECT_DIR", func() {
+			t.Setenv("BP_CARGO_PROJECT_DIR", "backend")
+
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Pass).To(BeTrue())
+			Expect(result.Plans[0].Provides).To(Equal([]libcnb.BuildPlanProvide{{Name: "rust-cargo"}}))
+		})
+
+		it("fails on a directory outside of the application", func() {
+			t.Setenv("BP_CARGO_PROJECT_DIR", "../backend")
+
+			_, err := detect.Detect(ctx)
+			Expect(err).To(MatchError(ContainSubstring("it is outside of the application directory")))
+		})
+	})
+
+	context("the SBOM is disabled", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(lockFile), 0644)).To(Succeed())
+		})
+
+		it("does not require syft with BP_DISABLE_SBOM", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte(manifestFile), 0644)).To(Succeed())
+			t.Setenv("BP_DISABLE_SBOM", "true")
+
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Plans[0].Requires).To(Equal([]libcnb.BuildPlanRequire{
+				{Name: "rust-cargo"},
+				{Name: "rust"},
+			}))
+		})
+
+		it("does not require syft when Cargo.toml disables the SBOM", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte(manifestFile+`
+[package.metadata.cargo-buildpack]
+disable-sbom = true
+`), 0644)).To(Succeed())
+
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Plans[0].Requires).ToNot(ContainElement(libcnb.BuildPlanRequire{Name: "syft"}))
+		})
+
+		it("does not require syft with the native or no SBOM scanner", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte(manifestFile), 0644)).To(Succeed())
+
+			for _, scanner := range []string{"native", "none"} {
+				t.Setenv("BP_CARGO_SBOM_SCANNER", scanner)
+
+				result, err := detect.Detect(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Plans[0].Requires).ToNot(ContainElement(libcnb.BuildPlanRequire{Name: "syft"}))
+			}
+		})
+
+		it("requires syft with BP_DISABLE_SBOM=false", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte(manifestFile), 0644)).To(Succeed())
+			t.Setenv("BP_DISABLE_SBOM", "false")
+
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Plans[0].Requires).To(ContainElement(libcnb.BuildPlanRequire{Name: "syft"}))
+		})
+	})
+
+	context("features require build plan entries", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte(manifestFile+`
+[features]
+tls = ["dep:openssl"]
+
+[package.metadata.cargo-buildpack.requires]
+tls = ["openssl"]
+`), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(lockFile), 0644)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_FEATURES")).To(Succeed())
+		})
+
+		it("does not require entries of disabled features", func() {
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Plans[0].Requires).ToNot(ContainElement(libcnb.BuildPlanRequire{Name: "openssl"}))
+		})
+
+		it("requires entries of enabled features", func() {
+			Expect(os.Setenv("BP_CARGO_FEATURES", "tls")).To(Succeed())
+
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Plans[0].Requires).To(Equal([]libcnb.BuildPlanRequire{
+				{Name: "syft"},
+				{Name: "rust-cargo"},
+				{Name: "rust"},
+				{Name: "openssl"},
+			}))
+		})
+	})
+
+	context("BP_CARGO_OTEL", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte(manifestFile), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(lockFile), 0644)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_OTEL")).To(Succeed())
+		})
+
+		it("hands the detect span to the build through the plan entry", func() {
+			Expect(os.Setenv("BP_CARGO_OTEL", "true")).To(Succeed())
+
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			detect.Clock = func() time.Time {
+				now = now.Add(time.Second)
+				return now
+			}
+
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Plans[0].Requires[1]).To(Equal(libcnb.BuildPlanRequire{
+				Name: "rust-cargo",
+				Metadata: map[string]interface{}{
+					"detect-start": "2024-01-01T00:00:01Z",
+					"detect-end":   "2024-01-01T00:00:02Z",
+				},
+			}))
+
+			span, ok := cargo.DetectSpan(result.Plans[0].Requires[1].Metadata)
+			Expect(ok).To(BeTrue())
+			Expect(span.End.Sub(span.Start)).To(Equal(time.Second))
+		})
+
+		it("does not add metadata by default", func() {
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Plans[0].Requires[1]).To(Equal(libcnb.BuildPlanRequire{Name: "rust-cargo"}))
+		})
+	})
+
+	context("BP_CARGO_ENABLED", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte(manifestFile), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(lockFile), 0644)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_ENABLED")).To(Succeed())
+		})
+
+		it("passes by default", func() {
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Pass).To(BeTrue())
+		})
+
+		it("passes when enabled", func() {
+			Expect(os.Setenv("BP_CARGO_ENABLED", "true")).To(Succeed())
+
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Pass).To(BeTrue())
+		})
+
+		it("fails when disabled", func() {
+			Expect(os.Setenv("BP_CARGO_ENABLED", "false")).To(Succeed())
+
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(libcnb.DetectResult{}))
+		})
+
+		it("fails when disabled in Cargo.toml", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"),
+				[]byte(manifestFile+"\n[package.metadata.cargo-buildpack]\nenabled = false\n"), 0644)).To(Succeed())
+
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(libcnb.DetectResult{}))
+		})
+
+		it("returns an error when the value is invalid", func() {
+			Expect(os.Setenv("BP_CARGO_ENABLED", "nope")).To(Succeed())
+
+			_, err := detect.Detect(ctx)
+			Expect(err).To(MatchError(ContainSubstring(`unable to parse BP_CARGO_ENABLED="nope"`)))
+		})
+	})
+	context("a C compiler is needed", func() {
+		var ccRequire = libcnb.BuildPlanRequire{Name: "cc", Metadata: map[string]interface{}{"build": true}}
+
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.toml"), []byte(manifestFile), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(lockFile+`
+[[package]]
+name = "openssl-sys"
+version = "0.9.102"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+`), 0644)).To(Succeed())
+		})
+
+		it("finds crates with C build scripts in Cargo.lock", func() {
+			Expect(cargo.NeedsCC(ctx.Application.Path)).To(BeTrue())
+
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(lockFile), 0644)).To(Succeed())
+			Expect(cargo.NeedsCC(ctx.Application.Path)).To(BeFalse())
+
+			Expect(os.Remove(filepath.Join(ctx.Application.Path, "Cargo.lock"))).To(Succeed())
+			Expect(cargo.NeedsCC(ctx.Application.Path)).To(BeFalse())
+		})
+
+		it("requires cc with a fallback plan without it", func() {
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Plans).To(HaveLen(2))
+			Expect(result.Plans[0].Requires).To(ContainElement(ccRequire))
+			Expect(result.Plans[1].Requires).ToNot(ContainElement(ccRequire))
+			Expect(result.Plans[1].Requires).To(Equal([]libcnb.BuildPlanRequire{
+				{Name: "syft"},
+				{Name: "rust-cargo"},
+				{Name: "rust"},
+			}))
+		})
+
+		it("only requires cc with BP_CARGO_REQUIRE_CC=true", func() {
+			t.Setenv("BP_CARGO_REQUIRE_CC", "true")
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "Cargo.lock"), []byte(lockFile), 0644)).To(Succeed())
+
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Plans).To(HaveLen(1))
+			Expect(result.Plans[0].Requires).To(Equal([]libcnb.BuildPlanRequire{
+				{Name: "syft"},
+				{Name: "rust-cargo"},
+				{Name: "rust"},
+				ccRequire,
+			}))
+		})
+
+		it("does not require cc with BP_CARGO_REQUIRE_CC=false", func() {
+			t.Setenv("BP_CARGO_REQUIRE_CC", "false")
+
+			result, err := detect.Detect(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Plans).To(HaveLen(1))
+			Expect(result.Plans[0].Requires).ToNot(ContainElement(ccRequire))
+		})
+
+		it("returns an error when the value is invalid", func() {
+			t.Setenv("BP_CARGO_REQUIRE_CC", "sometimes")
+
+			_, err := detect.Detect(ctx)
+			Expect(err).To(MatchError(ContainSubstring(`unable to parse BP_CARGO_REQUIRE_CC="sometimes"`)))
+		})
+	})
 }
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Event is a key event of the build, written as a JSON line when BP_LOG_FORMAT is json
+type Event struct {
+	Time   time.Time              `json:"time"`
+	Name   string                 `json:"event"`
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Events writes build events as JSON lines next to the regular log output, a nil *Events writes nothing
+type Events struct {
+	Clock  Clock
+	Writer io.Writer
+}
+
+// NewEvents returns the Events for format, `text` or `json`, or nil for `text`. An empty format selects `text`.
+func NewEvents(format string, writer io.Writer, clock Clock) (*Events, error) {
+	switch format {
+	case "", LogFormatText:
+		return nil, nil
+	case LogFormatJSON:
+		return &Events{Clock: clock, Writer: writer}, nil
+	default:
+		return nil, fmt.Errorf("unable to use log format %q, must be %q or %q", format, LogFormatText, LogFormatJSON)
+	}
+}
+
+// Emit writes the event name with fields. Events are informational, so an event which can't be written is dropped.
+func (e *Events) Emit(name string, fields map[string]interface{}) {
+	if e == nil {
+		return
+	}
+
+	line, err := json.Marshal(Event{Time: e.Clock.Now().UTC(), Name: name, Fields: fields})
+	if err != nil {
+		return
+	}
+
+	_, _ = e.Writer.Write(append(line, '\n'))
+}
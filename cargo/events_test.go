@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-community/cargo/cargo"
+	"github.com/sclevine/spec"
+)
+
+func testEvents(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		clock cargo.Clock
+	)
+
+	it.Before(func() {
+		clock = func() time.Time {
+			return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		}
+	})
+
+	context("selecting a log format", func() {
+		it("writes no events as text", func() {
+			Expect(cargo.NewEvents("", &bytes.Buffer{}, clock)).To(BeNil())
+			Expect(cargo.NewEvents("text", &bytes.Buffer{}, clock)).To(BeNil())
+		})
+
+		it("writes events as json", func() {
+			events, err := cargo.NewEvents("json", &bytes.Buffer{}, clock)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).NotTo(BeNil())
+		})
+
+		it("fails on an unknown format", func() {
+			_, err := cargo.NewEvents("logfmt", &bytes.Buffer{}, clock)
+			Expect(err).To(MatchError(`unable to use log format "logfmt", must be "text" or "json"`))
+		})
+	})
+
+	it("writes one JSON line per event", func() {
+		out := &bytes.Buffer{}
+		events := &cargo.Events{Clock: clock, Writer: out}
+
+		events.Emit("toolchain", map[string]interface{}{"rust-version": "1.75.0"})
+		events.Emit("compiled", nil)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+
+		var event cargo.Event
+		Expect(json.Unmarshal([]byte(lines[0]), &event)).To(Succeed())
+		Expect(event).To(Equal(cargo.Event{
+			Time:   clock(),
+			Name:   "toolchain",
+			Fields: map[string]interface{}{"rust-version": "1.75.0"},
+		}))
+		Expect(lines[1]).To(Equal(`{"time":"2024-01-01T00:00:00Z","event":"compiled"}`))
+	})
+
+	it("writes nothing when nil", func() {
+		var events *cargo.Events
+		events.Emit("compiled", nil)
+	})
+}
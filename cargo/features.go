@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ProjectRequiresKey is the key under `[package.metadata.cargo-buildpack]` in Cargo.toml which maps features to the
+// build plan entries they require, like `tls = ["openssl"]`
+const ProjectRequiresKey = "requires"
+
+// ParseFeatures parses a comma or space separated list of features, like the value of `--features`
+func ParseFeatures(raw string) []string {
+	return strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// ActiveFeatures returns the features which are enabled by selected and, transitively, by the features they enable.
+// The `default` feature is always included, just like cargo does without `--no-default-features`.
+func ActiveFeatures(features map[string][]string, selected []string) []string {
+	active := map[string]bool{}
+
+	var enable func(name string)
+	enable = func(name string) {
+		if active[name] {
+			return
+		}
+
+		if _, ok := features[name]; !ok {
+			return
+		}
+
+		active[name] = true
+		for _, value := range features[name] {
+			// `dep:name` and `name/feature` enable dependencies, not features of this package
+			if strings.HasPrefix(value, "dep:") || strings.Contains(value, "/") {
+				continue
+			}
+			enable(value)
+		}
+	}
+
+	enable("default")
+	for _, name := range selected {
+		enable(name)
+	}
+
+	var names []string
+	for name := range active {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// FeatureRequirements returns the build plan entries required by the active features of the package in the
+// project's Cargo.toml and of the workspace members it declares, as configured in
+// `[package.metadata.cargo-buildpack.requires]`
+func FeatureRequirements(applicationPath string, selected []string) ([]string, error) {
+	members, err := ManifestWorkspaceMembers(applicationPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find workspace members\n%w", err)
+	}
+
+	// the root package may be listed as a member too
+	dirs := []string{"."}
+	for _, member := range members {
+		if member != "." {
+			dirs = append(dirs, member)
+		}
+	}
+
+	seen := map[string]bool{}
+	var entries []string
+	for _, dir := range dirs {
+		required, err := manifestRequirements(filepath.Join(applicationPath, dir, "Cargo.toml"), selected)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range required {
+			if !seen[entry] {
+				seen[entry] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	sort.Strings(entries)
+
+	return entries, nil
+}
+
+// manifestRequirements returns the build plan entries required by the active features of the package in manifestPath
+func manifestRequirements(manifestPath string, selected []string) ([]string, error) {
+	var manifest struct {
+		Package struct {
+			Metadata struct {
+				Buildpack struct {
+					Requires map[string][]string `toml:"requires"`
+				} `toml:"cargo-buildpack"`
+			} `toml:"metadata"`
+		} `toml:"package"`
+		Features map[string][]string `toml:"features"`
+	}
+
+	if _, err := toml.DecodeFile(manifestPath, &manifest); err != nil {
+		return nil, fmt.Errorf("unable to decode %s\n%w", manifestPath, err)
+	}
+
+	requires := manifest.Package.Metadata.Buildpack.Requires
+	if len(requires) == 0 {
+		return nil, nil
+	}
+
+	// features without a [features] entry may still be configured, like the implicit features of optional dependencies
+	features := manifest.Features
+	if features == nil {
+		features = map[string][]string{}
+	}
+	for name := range requires {
+		if _, ok := features[name]; !ok {
+			features[name] = nil
+		}
+	}
+
+	var entries []string
+	for _, feature := range ActiveFeatures(features, selected) {
+		entries = append(entries, requires[feature]...)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-community/cargo/cargo"
+	"github.com/sclevine/spec"
+)
+
+func testFeatures(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		appDir string
+	)
+
+	it.Before(func() {
+		appDir = t.TempDir()
+	})
+
+	it("parses comma and space separated features", func() {
+		Expect(cargo.ParseFeatures("tls, metrics json")).To(Equal([]string{"tls", "metrics", "json"}))
+		Expect(cargo.ParseFeatures("")).To(BeEmpty())
+	})
+
+	it("enables features transitively", func() {
+		features := map[string][]string{
+			"default": {"json"},
+			"json":    {"dep:serde_json"},
+			"full":    {"tls", "metrics", "tokio/full"},
+			"tls":     {"dep:openssl"},
+			"metrics": {},
+			"unused":  {},
+		}
+
+		Expect(cargo.ActiveFeatures(features, nil)).To(Equal([]string{"default", "json"}))
+		Expect(cargo.ActiveFeatures(features, []string{"full", "missing"})).To(Equal([]string{"default", "full", "json", "metrics", "tls"}))
+	})
+
+	it("returns the requirements of active features", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte(`
+[package]
+name = "app"
+
+[features]
+default = ["metrics"]
+metrics = []
+tls = ["dep:openssl"]
+full = ["tls"]
+
+[package.metadata.cargo-buildpack.requires]
+metrics = ["statsd"]
+tls = ["openssl"]
+`), 0644)).To(Succeed())
+
+		Expect(cargo.FeatureRequirements(appDir, nil)).To(Equal([]string{"statsd"}))
+		Expect(cargo.FeatureRequirements(appDir, []string{"full"})).To(Equal([]string{"openssl", "statsd"}))
+	})
+
+	it("returns nothing without configured requirements", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\n\n[features]\ntls = []\n"), 0644)).To(Succeed())
+
+		Expect(cargo.FeatureRequirements(appDir, []string{"tls"})).To(BeEmpty())
+	})
+
+	it("returns the requirements of globbed workspace members", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte("[workspace]\nmembers = [\"crates/*\"]\n"), 0644)).To(Succeed())
+
+		for member, requires := range map[string]string{"api": "tls = [\"openssl\"]", "worker": "default = [\"libpq\"]"} {
+			Expect(os.MkdirAll(filepath.Join(appDir, "crates", member), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(appDir, "crates", member, "Cargo.toml"), []byte(`
+[package]
+name = "`+member+`"
+
+[features]
+default = []
+tls = []
+
+[package.metadata.cargo-buildpack.requires]
+`+requires+"\n"), 0644)).To(Succeed())
+		}
+
+		Expect(cargo.FeatureRequirements(appDir, nil)).To(Equal([]string{"libpq"}))
+		Expect(cargo.FeatureRequirements(appDir, []string{"tls"})).To(Equal([]string{"libpq", "openssl"}))
+	})
+}
@@ -29,5 +29,17 @@ func TestUnitRustCargo(t *testing.T) {
 	suite("Detect", testDetect)
 	suite("Cargo", testCargo)
 	suite("Cache", testCache)
+	suite("Checksums", testChecksums)
+	suite("Configuration", testConfiguration)
+	suite("Events", testEvents)
+	suite("Features", testFeatures)
+	suite("LockDiff", testLockDiff)
+	suite("Procfile", testProcfile)
+	suite("Registry", testRegistry)
+	suite("Removal", testRemoval)
+	suite("SBOM", testSBOM)
+	suite("Scanner", testScanner)
+	suite("Spans", testSpans)
+	suite("Workspace", testWorkspace)
 	suite.Run(t)
 }
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// LockChange is a package whose version changed between two Cargo.lock files
+type LockChange struct {
+	Name string
+	From string
+	To   string
+}
+
+// LockDiff are the changes between two Cargo.lock files
+type LockDiff struct {
+	Added   []LockPackage
+	Removed []LockPackage
+	Updated []LockChange
+}
+
+// DiffCargoLocks compares the packages of two Cargo.lock files. Packages are matched by name, a removed and an added
+// version of the same package are reported as an update. Cargo.lock can contain several versions of a package, the
+// remaining versions of those are reported as added or removed.
+func DiffCargoLocks(previous []LockPackage, current []LockPackage) LockDiff {
+	before, after := versionsByName(previous), versionsByName(current)
+
+	names := map[string]bool{}
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var diff LockDiff
+	for _, name := range sorted {
+		var removed, added []string
+		for _, version := range before[name] {
+			if !slices.Contains(after[name], version) {
+				removed = append(removed, version)
+			}
+		}
+		for _, version := range after[name] {
+			if !slices.Contains(before[name], version) {
+				added = append(added, version)
+			}
+		}
+
+		for len(removed) > 0 && len(added) > 0 {
+			diff.Updated = append(diff.Updated, LockChange{Name: name, From: removed[0], To: added[0]})
+			removed, added = removed[1:], added[1:]
+		}
+		for _, version := range removed {
+			diff.Removed = append(diff.Removed, LockPackage{Name: name, Version: version})
+		}
+		for _, version := range added {
+			diff.Added = append(diff.Added, LockPackage{Name: name, Version: version})
+		}
+	}
+
+	return diff
+}
+
+// Empty returns true if both Cargo.lock files contain the same packages
+func (d LockDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Updated) == 0
+}
+
+// Lines formats the changes for the build log, added packages first, then updated and removed packages
+func (d LockDiff) Lines() []string {
+	var lines []string
+	for _, p := range d.Added {
+		lines = append(lines, fmt.Sprintf("+ %s %s", p.Name, p.Version))
+	}
+	for _, c := range d.Updated {
+		lines = append(lines, fmt.Sprintf("~ %s %s -> %s", c.Name, c.From, c.To))
+	}
+	for _, p := range d.Removed {
+		lines = append(lines, fmt.Sprintf("- %s %s", p.Name, p.Version))
+	}
+	return lines
+}
+
+func versionsByName(packages []LockPackage) map[string][]string {
+	versions := map[string][]string{}
+	for _, p := range packages {
+		versions[p.Name] = append(versions[p.Name], p.Version)
+	}
+
+	for name := range versions {
+		sort.Strings(versions[name])
+	}
+
+	return versions
+}
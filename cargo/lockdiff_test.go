@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-community/cargo/cargo"
+	"github.com/sclevine/spec"
+)
+
+func testLockDiff(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	it("finds added, removed and updated packages", func() {
+		previous := []cargo.LockPackage{
+			{Name: "app", Version: "0.1.0"},
+			{Name: "log", Version: "0.4.21"},
+			{Name: "serde", Version: "1.0.190"},
+		}
+		current := []cargo.LockPackage{
+			{Name: "app", Version: "0.1.0"},
+			{Name: "serde", Version: "1.0.200"},
+			{Name: "tokio", Version: "1.38.0"},
+		}
+
+		diff := cargo.DiffCargoLocks(previous, current)
+		Expect(diff.Empty()).To(BeFalse())
+		Expect(diff.Added).To(Equal([]cargo.LockPackage{{Name: "tokio", Version: "1.38.0"}}))
+		Expect(diff.Removed).To(Equal([]cargo.LockPackage{{Name: "log", Version: "0.4.21"}}))
+		Expect(diff.Updated).To(Equal([]cargo.LockChange{{Name: "serde", From: "1.0.190", To: "1.0.200"}}))
+		Expect(diff.Lines()).To(Equal([]string{
+			"+ tokio 1.38.0",
+			"~ serde 1.0.190 -> 1.0.200",
+			"- log 0.4.21",
+		}))
+	})
+
+	it("keeps unchanged versions of packages with several versions", func() {
+		previous := []cargo.LockPackage{
+			{Name: "syn", Version: "1.0.109"},
+			{Name: "syn", Version: "2.0.60"},
+		}
+		current := []cargo.LockPackage{
+			{Name: "syn", Version: "1.0.109"},
+			{Name: "syn", Version: "2.0.66"},
+			{Name: "syn", Version: "2.0.70"},
+		}
+
+		diff := cargo.DiffCargoLocks(previous, current)
+		Expect(diff.Updated).To(Equal([]cargo.LockChange{{Name: "syn", From: "2.0.60", To: "2.0.66"}}))
+		Expect(diff.Added).To(Equal([]cargo.LockPackage{{Name: "syn", Version: "2.0.70"}}))
+		Expect(diff.Removed).To(BeEmpty())
+	})
+
+	it("is empty for the same packages", func() {
+		packages := []cargo.LockPackage{{Name: "serde", Version: "1.0.200"}}
+
+		diff := cargo.DiffCargoLocks(packages, packages)
+		Expect(diff.Empty()).To(BeTrue())
+		Expect(diff.Lines()).To(BeEmpty())
+	})
+}
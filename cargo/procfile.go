@@ -0,0 +1,54 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+)
+
+// Procfile renders process types as a Procfile, the default process type is listed in a leading comment
+func Procfile(procs []libcnb.Process) string {
+	sb := strings.Builder{}
+
+	for _, proc := range procs {
+		if proc.Default {
+			sb.WriteString(fmt.Sprintf("# default: %s\n", proc.Type))
+			break
+		}
+	}
+
+	for _, proc := range procs {
+		command := []string{quoteProcfileArg(proc.Command)}
+		for _, arg := range proc.Arguments {
+			command = append(command, quoteProcfileArg(arg))
+		}
+		sb.WriteString(fmt.Sprintf("%s: %s\n", proc.Type, strings.Join(command, " ")))
+	}
+
+	return sb.String()
+}
+
+func quoteProcfileArg(arg string) string {
+	if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\") {
+		return strconv.Quote(arg)
+	}
+	return arg
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo_test
+
+import (
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-community/cargo/cargo"
+	"github.com/sclevine/spec"
+)
+
+func testProcfile(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	it("lists the process types and marks the default", func() {
+		Expect(cargo.Procfile([]libcnb.Process{
+			{Type: "api", Command: "tini", Arguments: []string{"-g", "--", "/workspace/bin/api"}},
+			{Type: "web", Command: "tini", Arguments: []string{"-g", "--", "/workspace/bin/web"}, Default: true},
+		})).To(Equal(`# default: web
+api: tini -g -- /workspace/bin/api
+web: tini -g -- /workspace/bin/web
+`))
+	})
+
+	it("quotes arguments with spaces", func() {
+		Expect(cargo.Procfile([]libcnb.Process{
+			{Type: "web", Command: "/workspace/bin/web", Arguments: []string{"--greeting", "hello world"}, Default: true},
+		})).To(Equal(`# default: web
+web: /workspace/bin/web --greeting "hello world"
+`))
+	})
+
+	it("is empty without process types", func() {
+		Expect(cargo.Procfile([]libcnb.Process{})).To(BeEmpty())
+	})
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak/bindings"
+)
+
+// BindingTypeCargoRegistry is the type of the service bindings that describe a private Cargo registry
+const BindingTypeCargoRegistry = "cargo-registry"
+
+// Registry is a private Cargo registry read from a service binding
+type Registry struct {
+	// Name is the name dependencies use to refer to the registry
+	Name string
+
+	// Index is the URL of the registry index
+	Index string
+
+	// Token is the token used to authenticate with the registry
+	Token string
+}
+
+// IndexEnv returns the name of the environment variable Cargo reads the registry index from
+func (r Registry) IndexEnv() string {
+	return fmt.Sprintf("CARGO_REGISTRIES_%s_INDEX", r.envName())
+}
+
+// TokenEnv returns the name of the environment variable Cargo reads the registry token from
+func (r Registry) TokenEnv() string {
+	return fmt.Sprintf("CARGO_REGISTRIES_%s_TOKEN", r.envName())
+}
+
+func (r Registry) envName() string {
+	return strings.ToUpper(strings.ReplaceAll(r.Name, "-", "_"))
+}
+
+// RegistryFromBinding reads a registry from a `cargo-registry` binding. The binding must contain a `token` and an
+// `index` (or `url`) entry, the registry name is taken from the `name` entry and defaults to the binding name.
+func RegistryFromBinding(binding libcnb.Binding) (Registry, error) {
+	registry := Registry{
+		Name:  strings.TrimSpace(binding.Secret["name"]),
+		Index: strings.TrimSpace(binding.Secret["index"]),
+		Token: strings.TrimSpace(binding.Secret["token"]),
+	}
+
+	if registry.Name == "" {
+		registry.Name = binding.Name
+	}
+	if registry.Index == "" {
+		registry.Index = strings.TrimSpace(binding.Secret["url"])
+	}
+
+	if registry.Name == "" {
+		return Registry{}, fmt.Errorf("unable to read binding %s, no registry name", binding.Path)
+	}
+	if registry.Index == "" {
+		return Registry{}, fmt.Errorf("unable to read binding %s, must contain an index or url", binding.Name)
+	}
+	if registry.Token == "" {
+		return Registry{}, fmt.Errorf("unable to read binding %s, must contain a token", binding.Name)
+	}
+
+	return registry, nil
+}
+
+// ConfigureRegistries writes the index of every `cargo-registry` binding to `config.toml` in the Cargo home,
+// keeping any other configuration already present. Tokens are not written to disk, the caller is expected to
+// expose them to Cargo with ExportRegistries.
+func ConfigureRegistries(cargoHome string, binds libcnb.Bindings) ([]Registry, error) {
+	var registries []Registry
+	for _, binding := range bindings.Resolve(binds, bindings.OfType(BindingTypeCargoRegistry)) {
+		registry, err := RegistryFromBinding(binding)
+		if err != nil {
+			return nil, err
+		}
+		registries = append(registries, registry)
+	}
+
+	if len(registries) == 0 {
+		return nil, nil
+	}
+
+	configPath := filepath.Join(cargoHome, "config.toml")
+
+	config := map[string]interface{}{}
+	if _, err := toml.DecodeFile(configPath, &config); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unable to decode %s\n%w", configPath, err)
+	}
+
+	entries, ok := config["registries"].(map[string]interface{})
+	if !ok {
+		entries = map[string]interface{}{}
+	}
+	for _, registry := range registries {
+		entries[registry.Name] = map[string]interface{}{"index": registry.Index}
+	}
+	config["registries"] = entries
+
+	if err := os.MkdirAll(cargoHome, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create %s\n%w", cargoHome, err)
+	}
+
+	out, err := os.Create(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s\n%w", configPath, err)
+	}
+	defer out.Close()
+
+	if err := toml.NewEncoder(out).Encode(config); err != nil {
+		return nil, fmt.Errorf("unable to encode %s\n%w", configPath, err)
+	}
+
+	return registries, nil
+}
+
+// ExportRegistries sets the index and token of every registry in the environment of the build process. The index is
+// set as well because `config.toml` does not survive cleaning the Cargo home after each workspace member is installed.
+func ExportRegistries(registries []Registry) error {
+	for _, registry := range registries {
+		if err := os.Setenv(registry.IndexEnv(), registry.Index); err != nil {
+			return fmt.Errorf("unable to set %s\n%w", registry.IndexEnv(), err)
+		}
+		if err := os.Setenv(registry.TokenEnv(), registry.Token); err != nil {
+			return fmt.Errorf("unable to set %s\n%w", registry.TokenEnv(), err)
+		}
+	}
+
+	return nil
+}
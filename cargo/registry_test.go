@@ -0,0 +1,199 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/effect/mocks"
+	"github.com/paketo-community/cargo/cargo"
+	"github.com/paketo-community/cargo/runner"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+)
+
+func testRegistry(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		cargoHome string
+		bindings  libcnb.Bindings
+	)
+
+	writeBinding := func(name string, entries map[string]string) libcnb.Binding {
+		path := filepath.Join(t.TempDir(), name)
+		Expect(os.MkdirAll(path, 0755)).To(Succeed())
+		for key, value := range entries {
+			Expect(os.WriteFile(filepath.Join(path, key), []byte(value), 0644)).To(Succeed())
+		}
+
+		binding, err := libcnb.NewBindingFromPath(path)
+		Expect(err).NotTo(HaveOccurred())
+		return binding
+	}
+
+	it.Before(func() {
+		cargoHome = t.TempDir()
+	})
+
+	it("reads a registry from a binding", func() {
+		binding := writeBinding("my-registry", map[string]string{
+			"type":  "cargo-registry",
+			"index": "sparse+https://cargo.example.com/index/\n",
+			"token": "secret-token\n",
+		})
+
+		registry, err := cargo.RegistryFromBinding(binding)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registry).To(Equal(cargo.Registry{
+			Name:  "my-registry",
+			Index: "sparse+https://cargo.example.com/index/",
+			Token: "secret-token",
+		}))
+		Expect(registry.IndexEnv()).To(Equal("CARGO_REGISTRIES_MY_REGISTRY_INDEX"))
+		Expect(registry.TokenEnv()).To(Equal("CARGO_REGISTRIES_MY_REGISTRY_TOKEN"))
+	})
+
+	it("prefers the name and falls back to the url of a binding", func() {
+		binding := writeBinding("binding", map[string]string{
+			"type":  "cargo-registry",
+			"name":  "internal",
+			"url":   "https://cargo.example.com/index.git",
+			"token": "secret-token",
+		})
+
+		registry, err := cargo.RegistryFromBinding(binding)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registry.Name).To(Equal("internal"))
+		Expect(registry.Index).To(Equal("https://cargo.example.com/index.git"))
+	})
+
+	it("fails without a token", func() {
+		binding := writeBinding("my-registry", map[string]string{
+			"type":  "cargo-registry",
+			"index": "sparse+https://cargo.example.com/index/",
+		})
+
+		_, err := cargo.RegistryFromBinding(binding)
+		Expect(err).To(MatchError("unable to read binding my-registry, must contain a token"))
+	})
+
+	it("fails without an index", func() {
+		binding := writeBinding("my-registry", map[string]string{
+			"type":  "cargo-registry",
+			"token": "secret-token",
+		})
+
+		_, err := cargo.RegistryFromBinding(binding)
+		Expect(err).To(MatchError("unable to read binding my-registry, must contain an index or url"))
+	})
+
+	context("ConfigureRegistries", func() {
+		it.Before(func() {
+			bindings = libcnb.Bindings{
+				writeBinding("my-registry", map[string]string{
+					"type":  "cargo-registry",
+					"index": "sparse+https://cargo.example.com/index/",
+					"token": "secret-token",
+				}),
+				writeBinding("other", map[string]string{
+					"type":  "maven",
+					"token": "unrelated",
+				}),
+			}
+		})
+
+		it("does nothing without registry bindings", func() {
+			registries, err := cargo.ConfigureRegistries(cargoHome, bindings[1:])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registries).To(BeEmpty())
+			Expect(filepath.Join(cargoHome, "config.toml")).NotTo(BeAnExistingFile())
+		})
+
+		it("writes the registry index without the token", func() {
+			registries, err := cargo.ConfigureRegistries(cargoHome, bindings)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registries).To(HaveLen(1))
+			Expect(registries[0].Name).To(Equal("my-registry"))
+
+			config, err := os.ReadFile(filepath.Join(cargoHome, "config.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(config)).To(ContainSubstring("[registries.my-registry]"))
+			Expect(string(config)).To(ContainSubstring(`index = "sparse+https://cargo.example.com/index/"`))
+			Expect(string(config)).NotTo(ContainSubstring("secret-token"))
+		})
+
+		it("keeps existing configuration", func() {
+			Expect(os.WriteFile(filepath.Join(cargoHome, "config.toml"),
+				[]byte("[net]\ngit-fetch-with-cli = true\n\n[registries.existing]\nindex = \"https://existing.example.com/index\"\n"), 0644)).To(Succeed())
+
+			_, err := cargo.ConfigureRegistries(cargoHome, bindings)
+			Expect(err).NotTo(HaveOccurred())
+
+			config, err := os.ReadFile(filepath.Join(cargoHome, "config.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(config)).To(ContainSubstring("git-fetch-with-cli = true"))
+			Expect(string(config)).To(ContainSubstring("[registries.existing]"))
+			Expect(string(config)).To(ContainSubstring("[registries.my-registry]"))
+		})
+
+		it("fails with an invalid binding", func() {
+			bindings = append(bindings, writeBinding("broken", map[string]string{"type": "cargo-registry"}))
+
+			_, err := cargo.ConfigureRegistries(cargoHome, bindings)
+			Expect(err).To(MatchError(ContainSubstring("unable to read binding broken")))
+		})
+
+		it("keeps the registry configured for every member installed", func() {
+			registries, err := cargo.ConfigureRegistries(cargoHome, bindings)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(cargo.ExportRegistries(registries)).To(Succeed())
+			defer os.Unsetenv("CARGO_REGISTRIES_MY_REGISTRY_INDEX")
+			defer os.Unsetenv("CARGO_REGISTRIES_MY_REGISTRY_TOKEN")
+
+			executor := &mocks.Executor{}
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			r := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			layer := libcnb.Layer{Path: t.TempDir()}
+			Expect(r.InstallMember("/workspace/api", "/workspace", layer)).To(Succeed())
+			Expect(r.InstallMember("/workspace/worker", "/workspace", layer)).To(Succeed())
+
+			// cleaning the Cargo home after the first member removed config.toml
+			Expect(filepath.Join(cargoHome, "config.toml")).NotTo(BeAnExistingFile())
+
+			Expect(executor.Calls).To(HaveLen(2))
+			for _, call := range executor.Calls {
+				env := call.Arguments[0].(effect.Execution).Env
+				Expect(env).To(ContainElement("CARGO_REGISTRIES_MY_REGISTRY_INDEX=sparse+https://cargo.example.com/index/"))
+				Expect(env).To(ContainElement("CARGO_REGISTRIES_MY_REGISTRY_TOKEN=secret-token"))
+			}
+		})
+	})
+}
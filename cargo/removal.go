@@ -0,0 +1,284 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile is the file in the application root with patterns of source files to remove or keep. Like in a
+// `.dockerignore`, the lines are applied in order and the last matching line wins.
+const IgnoreFile = ".cargoignore"
+
+// rule is a pattern split into its path segments and if the files it matches are kept or removed
+type rule struct {
+	pattern []string
+	keep    bool
+}
+
+// RemoveSource removes the source code from appDir. include is an allow-list of colon separated glob patterns for the
+// files to keep, everything else is removed. exclude is a deny-list of patterns for files which are removed even if
+// include keeps them, so exclude wins on conflict. ignore are the lines of an IgnoreFile, which are applied after both
+// in order, so the last matching line wins like in a `.dockerignore`.
+func RemoveSource(appDir string, include string, exclude string, ignore []string) error {
+	keep, err := splitPatterns(include)
+	if err != nil {
+		return fmt.Errorf("unable to remove files not matching %q\n%w", include, err)
+	}
+
+	remove, err := splitPatterns(exclude)
+	if err != nil {
+		return fmt.Errorf("unable to remove files matching %q\n%w", exclude, err)
+	}
+
+	rules := append(newRules(keep, true), newRules(remove, false)...)
+	for _, line := range ignore {
+		pattern, ok := strings.CutPrefix(line, "!")
+
+		split, err := splitPatterns(pattern)
+		if err != nil {
+			return fmt.Errorf("unable to remove files with %s line %q\n%w", IgnoreFile, line, err)
+		}
+		rules = append(rules, newRules(split, ok)...)
+	}
+
+	if _, err := removeDir(appDir, nil, rules, false, -1); err != nil {
+		return fmt.Errorf("unable to remove source code from %s\n%w", appDir, err)
+	}
+
+	return nil
+}
+
+// ReadIgnoreFile reads the IgnoreFile in appDir and returns its patterns in order. Each line is a pattern of files to
+// remove, a line starting with `!` is a pattern of files to keep, blank lines and lines starting with `#` are ignored.
+// There are no patterns if there is no IgnoreFile.
+func ReadIgnoreFile(appDir string) ([]string, error) {
+	file := filepath.Join(appDir, IgnoreFile)
+
+	in, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to open %s\n%w", file, err)
+	}
+	defer in.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if pattern, ok := strings.CutPrefix(line, "!"); ok {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				patterns = append(patterns, "!"+pattern)
+			}
+			continue
+		}
+
+		patterns = append(patterns, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	return patterns, nil
+}
+
+// joinPatterns joins colon separated patterns, skipping empty ones
+func joinPatterns(patterns ...string) string {
+	var joined []string
+	for _, p := range patterns {
+		if p != "" {
+			joined = append(joined, p)
+		}
+	}
+
+	return strings.Join(joined, ":")
+}
+
+// IncludeFiles removes everything from appDir which does not match one of the colon separated glob patterns. Patterns
+// are matched against paths relative to appDir, `*` matches within a path segment and `**` matches any number of
+// segments, so both `config/*.toml` and `**/assets` retain nested files. A plain folder name retains the whole folder.
+// A malformed pattern fails before anything is removed.
+func IncludeFiles(appDir string, patterns string) error {
+	include, err := splitPatterns(patterns)
+	if err != nil {
+		return fmt.Errorf("unable to remove files not matching %q\n%w", patterns, err)
+	}
+
+	if _, err := removeDir(appDir, nil, newRules(include, true), false, -1); err != nil {
+		return fmt.Errorf("unable to remove files not matching %q\n%w", patterns, err)
+	}
+
+	return nil
+}
+
+// ExcludeFiles removes everything from appDir which matches one of the colon separated glob patterns, patterns are
+// matched like for IncludeFiles
+func ExcludeFiles(appDir string, patterns string) error {
+	exclude, err := splitPatterns(patterns)
+	if err != nil {
+		return fmt.Errorf("unable to remove files matching %q\n%w", patterns, err)
+	} else if len(exclude) == 0 {
+		return nil
+	}
+
+	if _, err := removeDir(appDir, nil, newRules(exclude, false), true, -1); err != nil {
+		return fmt.Errorf("unable to remove files matching %q\n%w", patterns, err)
+	}
+
+	return nil
+}
+
+func newRules(patterns [][]string, keep bool) []rule {
+	var rules []rule
+	for _, pattern := range patterns {
+		rules = append(rules, rule{pattern: pattern, keep: keep})
+	}
+
+	return rules
+}
+
+// removeDir removes the entries of dir which are not kept and returns if anything was kept. An entry is kept by the
+// last rule which matches it or one of its parents, keep and last are the decision and the index of that rule for dir.
+// A directory is only walked if a later rule could change the decision for something in it.
+func removeDir(dir string, rel []string, rules []rule, keep bool, last int) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+
+	kept := false
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		segments := append(append([]string{}, rel...), entry.Name())
+
+		entryKeep, entryLast := keep, last
+		for i := last + 1; i < len(rules); i++ {
+			if matchSegments(rules[i].pattern, segments) {
+				entryKeep, entryLast = rules[i].keep, i
+			}
+		}
+
+		// symlinks are not followed, a linked directory is removed like a file
+		if entry.IsDir() && changesBelow(rules[entryLast+1:], segments, entryKeep) {
+			found, err := removeDir(path, segments, rules, entryKeep, entryLast)
+			if err != nil {
+				return false, err
+			}
+
+			if found || entryKeep {
+				kept = true
+				continue
+			}
+		} else if entryKeep {
+			kept = true
+			continue
+		}
+
+		if err := os.RemoveAll(path); err != nil {
+			return false, err
+		}
+	}
+
+	return kept, nil
+}
+
+// changesBelow checks if one of rules could match a path below dir and change the decision keep for it
+func changesBelow(rules []rule, dir []string, keep bool) bool {
+	for _, r := range rules {
+		if r.keep != keep && containsMatch(r.pattern, dir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitPatterns splits colon separated patterns into their path segments. The segments are checked up front because
+// matching ignores filepath.ErrBadPattern, so a malformed pattern would otherwise match nothing.
+func splitPatterns(patterns string) ([][]string, error) {
+	var split [][]string
+	for _, pattern := range strings.Split(patterns, ":") {
+		pattern = strings.Trim(filepath.ToSlash(filepath.Clean(strings.TrimSpace(pattern))), "/")
+		if pattern == "" || pattern == "." {
+			continue
+		}
+
+		segments := strings.Split(pattern, "/")
+		for _, segment := range segments {
+			if _, err := filepath.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q\n%w", pattern, err)
+			}
+		}
+
+		split = append(split, segments)
+	}
+
+	return split, nil
+}
+
+// matchSegments checks if path matches pattern, `**` matches zero or more segments
+func matchSegments(pattern []string, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+
+	if pattern[0] == "**" {
+		return matchSegments(pattern[1:], path) || (len(path) > 0 && matchSegments(pattern, path[1:]))
+	}
+
+	if len(path) == 0 {
+		return false
+	}
+
+	if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+		return false
+	}
+
+	return matchSegments(pattern[1:], path[1:])
+}
+
+// containsMatch checks if a path below dir can match pattern
+func containsMatch(pattern []string, dir []string) bool {
+	if len(dir) == 0 {
+		return len(pattern) > 0
+	}
+
+	if len(pattern) == 0 {
+		return false
+	}
+
+	if pattern[0] == "**" {
+		return true
+	}
+
+	if ok, _ := filepath.Match(pattern[0], dir[0]); !ok {
+		return false
+	}
+
+	return containsMatch(pattern[1:], dir[1:])
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-community/cargo/cargo"
+	"github.com/sclevine/spec"
+)
+
+func testRemoval(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		appDir string
+	)
+
+	it.Before(func() {
+		appDir = t.TempDir()
+
+		for _, file := range []string{
+			"Cargo.toml",
+			"src/main.rs",
+			"config/app.toml",
+			"config/app.yaml",
+			"static/index.html",
+			"web/ui/assets/logo.png",
+			"web/ui/index.html",
+		} {
+			Expect(os.MkdirAll(filepath.Dir(filepath.Join(appDir, file)), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(appDir, file), []byte{}, 0644)).To(Succeed())
+		}
+	})
+
+	context("IncludeFiles", func() {
+		it("keeps top-level folders by name", func() {
+			Expect(cargo.IncludeFiles(appDir, "static")).To(Succeed())
+
+			Expect(filepath.Join(appDir, "static", "index.html")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "Cargo.toml")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "src")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "web")).NotTo(BeAnExistingFile())
+		})
+
+		it("keeps nested files matching a glob", func() {
+			Expect(cargo.IncludeFiles(appDir, "config/*.toml:static/*")).To(Succeed())
+
+			Expect(filepath.Join(appDir, "config", "app.toml")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "config", "app.yaml")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "static", "index.html")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "src")).NotTo(BeAnExistingFile())
+		})
+
+		it("keeps folders matching ** at any depth", func() {
+			Expect(cargo.IncludeFiles(appDir, "**/assets")).To(Succeed())
+
+			Expect(filepath.Join(appDir, "web", "ui", "assets", "logo.png")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "web", "ui", "index.html")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "config")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "static")).NotTo(BeAnExistingFile())
+		})
+
+		it("removes everything without patterns", func() {
+			Expect(cargo.IncludeFiles(appDir, "")).To(Succeed())
+
+			entries, err := os.ReadDir(appDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+		})
+
+		it("removes symlinks without following them", func() {
+			outside := t.TempDir()
+			Expect(os.WriteFile(filepath.Join(outside, "keep"), []byte{}, 0644)).To(Succeed())
+			Expect(os.Symlink(outside, filepath.Join(appDir, "target"))).To(Succeed())
+
+			Expect(cargo.IncludeFiles(appDir, "**/keep")).To(Succeed())
+
+			Expect(filepath.Join(appDir, "target")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(outside, "keep")).To(BeARegularFile())
+		})
+
+		it("fails on a malformed pattern without removing anything", func() {
+			err := cargo.IncludeFiles(appDir, "static:config/[a")
+			Expect(err).To(MatchError(filepath.ErrBadPattern))
+			Expect(err).To(MatchError(ContainSubstring(`invalid pattern "config/[a"`)))
+
+			Expect(filepath.Join(appDir, "Cargo.toml")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "config", "app.toml")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "src", "main.rs")).To(BeARegularFile())
+		})
+	})
+
+	context("ExcludeFiles", func() {
+		it("removes top-level folders by name", func() {
+			Expect(cargo.ExcludeFiles(appDir, "src")).To(Succeed())
+
+			Expect(filepath.Join(appDir, "src")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "Cargo.toml")).To(BeARegularFile())
+		})
+
+		it("removes nested files matching a glob", func() {
+			Expect(cargo.ExcludeFiles(appDir, "config/*.yaml:**/index.html")).To(Succeed())
+
+			Expect(filepath.Join(appDir, "config", "app.yaml")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "config", "app.toml")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "static", "index.html")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "web", "ui", "index.html")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "web", "ui", "assets", "logo.png")).To(BeARegularFile())
+		})
+	})
+
+	context("ReadIgnoreFile", func() {
+		it("returns the patterns in order", func() {
+			Expect(os.WriteFile(filepath.Join(appDir, cargo.IgnoreFile), []byte(`# generated assets
+target
+
+  config/*.yaml
+!static/*
+! web/**/assets
+#!src
+`), 0644)).To(Succeed())
+
+			patterns, err := cargo.ReadIgnoreFile(appDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(patterns).To(Equal([]string{"target", "config/*.yaml", "!static/*", "!web/**/assets"}))
+		})
+
+		it("returns no patterns without a file", func() {
+			patterns, err := cargo.ReadIgnoreFile(appDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(patterns).To(BeEmpty())
+		})
+	})
+
+	context("RemoveSource", func() {
+		it("keeps only included files", func() {
+			Expect(cargo.RemoveSource(appDir, "config:static/*", "", nil)).To(Succeed())
+
+			Expect(filepath.Join(appDir, "config", "app.toml")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "config", "app.yaml")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "static", "index.html")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "src")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "Cargo.toml")).NotTo(BeAnExistingFile())
+		})
+
+		it("removes excluded files when nothing is included", func() {
+			Expect(os.WriteFile(filepath.Join(appDir, "keep.txt"), []byte{}, 0644)).To(Succeed())
+
+			Expect(cargo.RemoveSource(appDir, "", "config", nil)).To(Succeed())
+
+			entries, err := os.ReadDir(appDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+		})
+
+		it("removes excluded files even if they are included", func() {
+			Expect(cargo.RemoveSource(appDir, "config:web", "config/*.yaml:**/assets", nil)).To(Succeed())
+
+			Expect(filepath.Join(appDir, "config", "app.toml")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "config", "app.yaml")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "web", "ui", "index.html")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "web", "ui", "assets")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "static")).NotTo(BeAnExistingFile())
+		})
+
+		it("fails on a malformed exclude pattern before removing anything", func() {
+			Expect(cargo.RemoveSource(appDir, "config", "config/[a", nil)).To(MatchError(filepath.ErrBadPattern))
+
+			Expect(filepath.Join(appDir, "Cargo.toml")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "src", "main.rs")).To(BeARegularFile())
+		})
+
+		it("applies the ignore patterns in order with the last match winning", func() {
+			Expect(cargo.RemoveSource(appDir, "Cargo.toml:config", "", []string{"**/*.toml", "!config/app.toml"})).To(Succeed())
+
+			Expect(filepath.Join(appDir, "config", "app.toml")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "config", "app.yaml")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "Cargo.toml")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "src")).NotTo(BeAnExistingFile())
+		})
+
+		it("re-includes files in an excluded folder", func() {
+			Expect(cargo.RemoveSource(appDir, "", "", []string{"!web", "web/ui", "!web/ui/assets/logo.png"})).To(Succeed())
+
+			Expect(filepath.Join(appDir, "web", "ui", "assets", "logo.png")).To(BeARegularFile())
+			Expect(filepath.Join(appDir, "web", "ui", "index.html")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(appDir, "config")).NotTo(BeAnExistingFile())
+		})
+
+		it("lets an earlier ignore pattern be overridden by a later one", func() {
+			Expect(cargo.RemoveSource(appDir, "", "", []string{"!config/app.toml", "config"})).To(Succeed())
+
+			Expect(filepath.Join(appDir, "config")).NotTo(BeAnExistingFile())
+		})
+
+		it("fails on a malformed ignore pattern before removing anything", func() {
+			Expect(cargo.RemoveSource(appDir, "config", "", []string{"!config/[a"})).To(MatchError(filepath.ErrBadPattern))
+
+			Expect(filepath.Join(appDir, "src", "main.rs")).To(BeARegularFile())
+		})
+	})
+}
@@ -0,0 +1,266 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/buildpacks/libcnb"
+)
+
+// LockPackage is a single `[[package]]` entry from Cargo.lock
+type LockPackage struct {
+	Name     string `toml:"name"`
+	Version  string `toml:"version"`
+	Source   string `toml:"source"`
+	Checksum string `toml:"checksum"`
+}
+
+// SourceType returns where a package comes from, `registry`, `git` or `path` for packages without a source. Packages
+// from sparse registries are reported as `registry` too.
+func (l LockPackage) SourceType() string {
+	if l.Source == "" {
+		return "path"
+	}
+
+	sourceType, _, _ := strings.Cut(l.Source, "+")
+	if sourceType == "sparse" {
+		return "registry"
+	}
+	return sourceType
+}
+
+// PURL returns the package URL for the package
+func (l LockPackage) PURL() string {
+	return fmt.Sprintf("pkg:cargo/%s@%s", l.Name, l.Version)
+}
+
+// lockFile is the part of Cargo.lock the buildpack reads, other keys are skipped so newer formats can still be read
+type lockFile struct {
+	Version  int           `toml:"version"`
+	Packages []LockPackage `toml:"package"`
+}
+
+// CycloneDXHash is a CycloneDX component hash
+type CycloneDXHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+// CycloneDXProperty is a CycloneDX name/value property
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CycloneDXExternalReference is a CycloneDX external reference
+type CycloneDXExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// CycloneDXComponent is a CycloneDX library component
+type CycloneDXComponent struct {
+	Type               string                       `json:"type"`
+	Name               string                       `json:"name"`
+	Version            string                       `json:"version"`
+	PURL               string                       `json:"purl"`
+	Hashes             []CycloneDXHash              `json:"hashes,omitempty"`
+	ExternalReferences []CycloneDXExternalReference `json:"externalReferences,omitempty"`
+	Properties         []CycloneDXProperty          `json:"properties,omitempty"`
+}
+
+// ParseCargoLock reads all packages from a Cargo.lock file. Any lock format version is accepted, the git sources of
+// version 4 and newer are decoded so the packages are the same as those of version 3.
+func ParseCargoLock(path string) ([]LockPackage, error) {
+	var lock lockFile
+	if _, err := toml.DecodeFile(path, &lock); err != nil {
+		return nil, fmt.Errorf("unable to decode %s\n%w", path, err)
+	}
+
+	if lock.Version >= 4 {
+		for i := range lock.Packages {
+			lock.Packages[i].Source = decodeGitSource(lock.Packages[i].Source)
+		}
+	}
+
+	return lock.Packages, nil
+}
+
+// decodeGitSource decodes the query of a git source, like `?branch=feature%2Ffast`, which Cargo.lock version 4
+// percent-encodes. The source is returned as is if it isn't a git source or can't be decoded.
+func decodeGitSource(source string) string {
+	if !strings.HasPrefix(source, "git+") {
+		return source
+	}
+
+	base, query, found := strings.Cut(source, "?")
+	if !found {
+		return source
+	}
+
+	query, fragment, hasFragment := strings.Cut(query, "#")
+	decoded, err := url.PathUnescape(query)
+	if err != nil {
+		return source
+	}
+
+	if hasFragment {
+		return fmt.Sprintf("%s?%s#%s", base, decoded, fragment)
+	}
+	return fmt.Sprintf("%s?%s", base, decoded)
+}
+
+// CargoLockComponents converts the dependencies listed in a Cargo.lock file into CycloneDX components. Packages without
+// a source are part of the project itself and are skipped.
+func CargoLockComponents(path string) ([]CycloneDXComponent, error) {
+	packages, err := ParseCargoLock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	components := []CycloneDXComponent{}
+	for _, pkg := range packages {
+		if pkg.Source == "" {
+			continue
+		}
+
+		component := CycloneDXComponent{
+			Type:    "library",
+			Name:    pkg.Name,
+			Version: pkg.Version,
+			PURL:    pkg.PURL(),
+			Properties: []CycloneDXProperty{
+				{Name: "cargo:source-type", Value: pkg.SourceType()},
+				{Name: "cargo:source", Value: pkg.Source},
+			},
+		}
+
+		if pkg.Checksum != "" {
+			component.Hashes = append(component.Hashes, CycloneDXHash{Algorithm: "SHA-256", Content: pkg.Checksum})
+		}
+
+		if pkg.SourceType() == "git" {
+			component.ExternalReferences = append(component.ExternalReferences, CycloneDXExternalReference{
+				Type: "vcs",
+				URL:  strings.TrimPrefix(pkg.Source, "git+"),
+			})
+		}
+
+		components = append(components, component)
+	}
+
+	return components, nil
+}
+
+// ToolchainComponents returns CycloneDX components for the Rust toolchain used to build the application
+func ToolchainComponents(rustVersion string, cargoVersion string) []CycloneDXComponent {
+	return []CycloneDXComponent{
+		{
+			Type:    "application",
+			Name:    "rust",
+			Version: rustVersion,
+			PURL:    fmt.Sprintf("pkg:generic/rust@%s", rustVersion),
+		},
+		{
+			Type:    "application",
+			Name:    "cargo",
+			Version: cargoVersion,
+			PURL:    fmt.Sprintf("pkg:generic/cargo@%s", cargoVersion),
+		},
+	}
+}
+
+// ToolchainBOMEntry returns a build Bill of Materials entry recording the Rust toolchain the application is built with
+func ToolchainBOMEntry(rustVersion string, cargoVersion string) libcnb.BOMEntry {
+	return libcnb.BOMEntry{
+		Name: "rust-toolchain",
+		Metadata: map[string]interface{}{
+			"cargo-version": cargoVersion,
+			"rust-version":  rustVersion,
+		},
+		Build: true,
+	}
+}
+
+// WriteCargoLockSBOM adds the components from the Cargo.lock file at lockPath to the CycloneDX SBOM at sbomPath
+func WriteCargoLockSBOM(lockPath string, sbomPath string) error {
+	components, err := CargoLockComponents(lockPath)
+	if err != nil {
+		return fmt.Errorf("unable to read components from %s\n%w", lockPath, err)
+	}
+
+	return AddSBOMComponents(sbomPath, components)
+}
+
+// AddSBOMComponents adds components to the CycloneDX SBOM at sbomPath. If the SBOM already exists, like when it has
+// been written by Syft, components which are not already present are appended to it, otherwise a new SBOM is created.
+func AddSBOMComponents(sbomPath string, components []CycloneDXComponent) error {
+	bom := map[string]interface{}{
+		"bomFormat":   "CycloneDX",
+		"specVersion": "1.4",
+		"version":     1,
+	}
+
+	existing := map[string]bool{}
+	var bomComponents []interface{}
+
+	if in, err := os.ReadFile(sbomPath); err == nil {
+		if err := json.Unmarshal(in, &bom); err != nil {
+			return fmt.Errorf("unable to decode SBOM %s\n%w", sbomPath, err)
+		}
+
+		if raw, ok := bom["components"].([]interface{}); ok {
+			bomComponents = raw
+		}
+
+		for _, raw := range bomComponents {
+			if c, ok := raw.(map[string]interface{}); ok {
+				if purl, ok := c["purl"].(string); ok {
+					existing[purl] = true
+				}
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to read SBOM %s\n%w", sbomPath, err)
+	}
+
+	for _, component := range components {
+		if existing[component.PURL] {
+			continue
+		}
+		bomComponents = append(bomComponents, component)
+	}
+	bom["components"] = bomComponents
+
+	out, err := json.Marshal(bom)
+	if err != nil {
+		return fmt.Errorf("unable to encode SBOM\n%w", err)
+	}
+
+	if err := os.WriteFile(sbomPath, out, 0644); err != nil {
+		return fmt.Errorf("unable to write SBOM %s\n%w", sbomPath, err)
+	}
+
+	return nil
+}
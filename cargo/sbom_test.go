@@ -0,0 +1,157 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-community/cargo/cargo"
+	"github.com/sclevine/spec"
+)
+
+func testSBOM(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		sbomPath string
+	)
+
+	it.Before(func() {
+		sbomPath = filepath.Join(t.TempDir(), "layer.sbom.cdx.json")
+	})
+
+	it("creates components for each dependency in Cargo.lock", func() {
+		components, err := cargo.CargoLockComponents("testdata/Cargo.lock")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(components).To(HaveLen(3))
+		Expect(components[0].PURL).To(Equal("pkg:cargo/my-fork@0.2.1"))
+		Expect(components[0].Properties).To(ContainElement(cargo.CycloneDXProperty{Name: "cargo:source-type", Value: "git"}))
+		Expect(components[0].ExternalReferences).To(ConsistOf(cargo.CycloneDXExternalReference{
+			Type: "vcs",
+			URL:  "https://github.com/example/my-fork?branch=main#4f2c3b1a9d0e8f7a6b5c4d3e2f1a0b9c8d7e6f5a",
+		}))
+
+		Expect(components[1].Name).To(Equal("serde"))
+		Expect(components[1].Version).To(Equal("1.0.0"))
+		Expect(components[1].PURL).To(Equal("pkg:cargo/serde@1.0.0"))
+		Expect(components[1].Properties).To(ContainElement(cargo.CycloneDXProperty{Name: "cargo:source-type", Value: "registry"}))
+		Expect(components[1].Hashes).To(ConsistOf(cargo.CycloneDXHash{
+			Algorithm: "SHA-256",
+			Content:   "9dad3f759919b92c3068c696c15c3d17238234498bbdcc80f2c469606f948ac8",
+		}))
+
+		Expect(components[2].PURL).To(Equal("pkg:cargo/serde_derive@1.0.0"))
+	})
+
+	it("reports the source type of packages", func() {
+		Expect(cargo.LockPackage{}.SourceType()).To(Equal("path"))
+		Expect(cargo.LockPackage{Source: "registry+https://github.com/rust-lang/crates.io-index"}.SourceType()).To(Equal("registry"))
+		Expect(cargo.LockPackage{Source: "sparse+https://index.crates.io/"}.SourceType()).To(Equal("registry"))
+		Expect(cargo.LockPackage{Source: "git+https://github.com/example/my-fork?branch=main#4f2c3b1a"}.SourceType()).To(Equal("git"))
+	})
+
+	it("writes a new SBOM", func() {
+		Expect(cargo.WriteCargoLockSBOM("testdata/Cargo.lock", sbomPath)).To(Succeed())
+
+		bom := readBOM(t, sbomPath)
+		Expect(bom["bomFormat"]).To(Equal("CycloneDX"))
+		Expect(bom["components"]).To(HaveLen(3))
+	})
+
+	it("merges into an existing SBOM", func() {
+		Expect(os.WriteFile(sbomPath, []byte(`{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.4",
+  "metadata": {"tools": [{"name": "syft"}]},
+  "components": [{"type": "library", "name": "serde", "version": "1.0.0", "purl": "pkg:cargo/serde@1.0.0"}]
+}`), 0644)).To(Succeed())
+
+		Expect(cargo.WriteCargoLockSBOM("testdata/Cargo.lock", sbomPath)).To(Succeed())
+
+		bom := readBOM(t, sbomPath)
+		Expect(bom).To(HaveKey("metadata"))
+		Expect(bom["components"]).To(HaveLen(3))
+	})
+
+	it("adds the Rust toolchain", func() {
+		Expect(cargo.AddSBOMComponents(sbomPath, cargo.ToolchainComponents("1.80.0", "1.80.1"))).To(Succeed())
+
+		bom := readBOM(t, sbomPath)
+		Expect(bom["components"]).To(ConsistOf(
+			And(HaveKeyWithValue("name", "rust"), HaveKeyWithValue("version", "1.80.0"), HaveKeyWithValue("purl", "pkg:generic/rust@1.80.0")),
+			And(HaveKeyWithValue("name", "cargo"), HaveKeyWithValue("version", "1.80.1"), HaveKeyWithValue("purl", "pkg:generic/cargo@1.80.1")),
+		))
+	})
+
+	context("lock file formats", func() {
+		it("reads the same packages from version 3 and 4", func() {
+			v3, err := cargo.ParseCargoLock("testdata/lock-formats/v3/Cargo.lock")
+			Expect(err).ToNot(HaveOccurred())
+
+			v4, err := cargo.ParseCargoLock("testdata/lock-formats/v4/Cargo.lock")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(v4).To(Equal(v3))
+			Expect(v4).To(HaveLen(3))
+			Expect(v4[2].Source).To(Equal("git+https://github.com/example/widgets?branch=feature/fast#4f2c3b1a9d0e8f7a6b5c4d3e2f1a0b9c8d7e6f5a"))
+		})
+
+		it("reads inline package tables and skips unknown keys of newer versions", func() {
+			lockPath := filepath.Join(t.TempDir(), "Cargo.lock")
+			Expect(os.WriteFile(lockPath, []byte(`version = 5
+package = [
+  { name = "serde", version = "1.0.0", source = "registry+https://github.com/rust-lang/crates.io-index", yanked = false },
+  { name = "hello", version = "0.1.0", dependencies = ["serde"] },
+]
+
+[metadata]
+generator = "cargo 2.0"
+`), 0644)).To(Succeed())
+
+			packages, err := cargo.ParseCargoLock(lockPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(packages).To(Equal([]cargo.LockPackage{
+				{Name: "serde", Version: "1.0.0", Source: "registry+https://github.com/rust-lang/crates.io-index"},
+				{Name: "hello", Version: "0.1.0"},
+			}))
+		})
+	})
+
+	it("fails on an invalid lock file", func() {
+		lockPath := filepath.Join(t.TempDir(), "Cargo.lock")
+		Expect(os.WriteFile(lockPath, []byte("[[package]"), 0644)).To(Succeed())
+
+		Expect(cargo.WriteCargoLockSBOM(lockPath, sbomPath)).To(MatchError(ContainSubstring("unable to read components")))
+	})
+}
+
+func readBOM(t *testing.T, path string) map[string]interface{} {
+	Expect := NewWithT(t).Expect
+
+	in, err := os.ReadFile(path)
+	Expect(err).ToNot(HaveOccurred())
+
+	bom := map[string]interface{}{}
+	Expect(json.Unmarshal(in, &bom)).To(Succeed())
+
+	return bom
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/sbom"
+)
+
+const (
+	SBOMScannerSyft   = "syft"
+	SBOMScannerNative = "native"
+	SBOMScannerNone   = "none"
+)
+
+// NewSBOMScanner returns the SBOM scanner selected by name, one of `syft`, `native` or `none`, or nil for `none`.
+// An empty name selects `syft`.
+func NewSBOMScanner(name string, layers libcnb.Layers, executor effect.Executor, logger bard.Logger) (sbom.SBOMScanner, error) {
+	switch name {
+	case "", SBOMScannerSyft:
+		return sbom.NewSyftCLISBOMScanner(layers, executor, logger), nil
+	case SBOMScannerNative:
+		return NewCargoLockSBOMScanner(layers, logger), nil
+	case SBOMScannerNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unable to use SBOM scanner %q, must be %q, %q or %q", name, SBOMScannerSyft, SBOMScannerNative, SBOMScannerNone)
+	}
+}
+
+const (
+	SBOMFormatCycloneDX = "cyclonedx"
+	SBOMFormatSyft      = "syft"
+	SBOMFormatSPDX      = "spdx"
+)
+
+// ParseSBOMFormats parses a comma separated list of SBOM formats, `cyclonedx`, `syft` or `spdx`. An empty list returns
+// nil, so the default formats are used.
+func ParseSBOMFormats(raw string) ([]libcnb.SBOMFormat, error) {
+	var formats []libcnb.SBOMFormat
+	for _, name := range strings.Split(raw, ",") {
+		var format libcnb.SBOMFormat
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+			continue
+		case SBOMFormatCycloneDX:
+			format = libcnb.CycloneDXJSON
+		case SBOMFormatSyft:
+			format = libcnb.SyftJSON
+		case SBOMFormatSPDX:
+			format = libcnb.SPDXJSON
+		default:
+			return nil, fmt.Errorf("unable to use SBOM format %q, must be %q, %q or %q",
+				strings.TrimSpace(name), SBOMFormatCycloneDX, SBOMFormatSyft, SBOMFormatSPDX)
+		}
+
+		if !slices.Contains(formats, format) {
+			formats = append(formats, format)
+		}
+	}
+
+	return formats, nil
+}
+
+// CargoLockSBOMScanner writes a CycloneDX SBOM with the dependencies listed in the Cargo.lock file of the scanned
+// directory, without any external tools. Other SBOM formats are not supported and are skipped.
+type CargoLockSBOMScanner struct {
+	Layers libcnb.Layers
+	Logger bard.Logger
+}
+
+// NewCargoLockSBOMScanner creates a new CargoLockSBOMScanner
+func NewCargoLockSBOMScanner(layers libcnb.Layers, logger bard.Logger) CargoLockSBOMScanner {
+	return CargoLockSBOMScanner{Layers: layers, Logger: logger}
+}
+
+// Formats returns the SBOM formats the scanner writes
+func (s CargoLockSBOMScanner) Formats() []libcnb.SBOMFormat {
+	return []libcnb.SBOMFormat{libcnb.CycloneDXJSON}
+}
+
+// ScanLayer writes the SBOM of layer from the Cargo.lock file in scanDir
+func (s CargoLockSBOMScanner) ScanLayer(layer libcnb.Layer, scanDir string, formats ...libcnb.SBOMFormat) error {
+	return s.scan(scanDir, layer.SBOMPath, formats)
+}
+
+// ScanBuild writes the build SBOM from the Cargo.lock file in scanDir
+func (s CargoLockSBOMScanner) ScanBuild(scanDir string, formats ...libcnb.SBOMFormat) error {
+	return s.scan(scanDir, s.Layers.BuildSBOMPath, formats)
+}
+
+// ScanLaunch writes the launch SBOM from the Cargo.lock file in scanDir
+func (s CargoLockSBOMScanner) ScanLaunch(scanDir string, formats ...libcnb.SBOMFormat) error {
+	return s.scan(scanDir, s.Layers.LaunchSBOMPath, formats)
+}
+
+func (s CargoLockSBOMScanner) scan(scanDir string, sbomPath func(libcnb.SBOMFormat) string, formats []libcnb.SBOMFormat) error {
+	lockPath := filepath.Join(scanDir, "Cargo.lock")
+
+	var components []CycloneDXComponent
+	if _, err := os.Stat(lockPath); err == nil {
+		components, err = CargoLockComponents(lockPath)
+		if err != nil {
+			return fmt.Errorf("unable to read components from %s\n%w", lockPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("unable to read %s\n%w", lockPath, err)
+	}
+
+	for _, format := range formats {
+		if format != libcnb.CycloneDXJSON {
+			s.Logger.Bodyf("Skipping the %s SBOM, the native scanner only writes CycloneDX", format)
+			continue
+		}
+
+		if err := AddSBOMComponents(sbomPath(format), components); err != nil {
+			return fmt.Errorf("unable to write %s SBOM\n%w", format, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/sbom"
+	"github.com/paketo-community/cargo/cargo"
+	"github.com/sclevine/spec"
+)
+
+func testScanner(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layers libcnb.Layers
+		logger bard.Logger
+	)
+
+	it.Before(func() {
+		layers = libcnb.Layers{Path: t.TempDir()}
+		logger = bard.NewLogger(os.Stdout)
+	})
+
+	context("selecting a scanner", func() {
+		it("selects syft by default", func() {
+			for _, name := range []string{"", "syft"} {
+				scanner, err := cargo.NewSBOMScanner(name, layers, effect.NewExecutor(), logger)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(scanner).To(BeAssignableToTypeOf(sbom.SyftCLISBOMScanner{}))
+			}
+		})
+
+		it("selects the native scanner", func() {
+			scanner, err := cargo.NewSBOMScanner("native", layers, effect.NewExecutor(), logger)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scanner).To(BeAssignableToTypeOf(cargo.CargoLockSBOMScanner{}))
+		})
+
+		it("selects no scanner", func() {
+			scanner, err := cargo.NewSBOMScanner("none", layers, effect.NewExecutor(), logger)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scanner).To(BeNil())
+		})
+
+		it("fails on an unknown scanner", func() {
+			_, err := cargo.NewSBOMScanner("trivy", layers, effect.NewExecutor(), logger)
+			Expect(err).To(MatchError(`unable to use SBOM scanner "trivy", must be "syft", "native" or "none"`))
+		})
+	})
+
+	context("parsing SBOM formats", func() {
+		it("parses a comma separated list", func() {
+			formats, err := cargo.ParseSBOMFormats(" SPDX, cyclonedx,,spdx ")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(formats).To(Equal([]libcnb.SBOMFormat{libcnb.SPDXJSON, libcnb.CycloneDXJSON}))
+		})
+
+		it("returns nil for the default formats", func() {
+			Expect(cargo.ParseSBOMFormats("")).To(BeNil())
+		})
+
+		it("fails on an unknown format", func() {
+			_, err := cargo.ParseSBOMFormats("cyclonedx,swid")
+			Expect(err).To(MatchError(`unable to use SBOM format "swid", must be "cyclonedx", "syft" or "spdx"`))
+		})
+	})
+
+	context("the native scanner", func() {
+		var (
+			appDir  string
+			layer   libcnb.Layer
+			scanner cargo.CargoLockSBOMScanner
+		)
+
+		it.Before(func() {
+			appDir = t.TempDir()
+			lock, err := os.ReadFile("testdata/Cargo.lock")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(appDir, "Cargo.lock"), lock, 0644)).To(Succeed())
+
+			layer, err = layers.Layer("cargo")
+			Expect(err).ToNot(HaveOccurred())
+
+			scanner = cargo.NewCargoLockSBOMScanner(layers, logger)
+		})
+
+		it("writes a CycloneDX SBOM of the layer from Cargo.lock", func() {
+			Expect(scanner.ScanLayer(layer, appDir, libcnb.CycloneDXJSON, libcnb.SyftJSON)).To(Succeed())
+
+			bom := readBOM(t, layer.SBOMPath(libcnb.CycloneDXJSON))
+			Expect(bom["bomFormat"]).To(Equal("CycloneDX"))
+			Expect(bom["components"]).To(HaveLen(3))
+			Expect(layer.SBOMPath(libcnb.SyftJSON)).ToNot(BeAnExistingFile())
+		})
+
+		it("writes the launch SBOM", func() {
+			Expect(scanner.ScanLaunch(appDir, libcnb.CycloneDXJSON)).To(Succeed())
+
+			Expect(readBOM(t, layers.LaunchSBOMPath(libcnb.CycloneDXJSON))["components"]).To(HaveLen(3))
+		})
+
+		it("writes an empty SBOM without Cargo.lock", func() {
+			Expect(scanner.ScanLayer(layer, t.TempDir(), libcnb.CycloneDXJSON)).To(Succeed())
+
+			Expect(readBOM(t, layer.SBOMPath(libcnb.CycloneDXJSON))["components"]).To(BeNil())
+		})
+
+		it("only writes CycloneDX", func() {
+			Expect(scanner.Formats()).To(Equal([]libcnb.SBOMFormat{libcnb.CycloneDXJSON}))
+		})
+	})
+}
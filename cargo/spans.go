@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// SpansFile is the file in the application layer the build spans are written to, one JSON record per line
+	SpansFile = "build-spans.jsonl"
+
+	planEntryDetectStart = "detect-start"
+	planEntryDetectEnd   = "detect-end"
+)
+
+// Span is a timed phase of the build, modeled after an OpenTelemetry span
+type Span struct {
+	Name       string            `json:"name"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Spans records the phases of a build, a nil *Spans records nothing
+type Spans struct {
+	Clock   Clock
+	Records []Span
+}
+
+// Start starts a span and returns the function that ends it
+func (s *Spans) Start(name string, attributes map[string]string) func() {
+	if s == nil {
+		return func() {}
+	}
+
+	start := s.Clock.Now()
+	return func() {
+		s.Records = append(s.Records, Span{Name: name, Start: start, End: s.Clock.Now(), Attributes: attributes})
+	}
+}
+
+// Add records a span which was timed elsewhere
+func (s *Spans) Add(span Span) {
+	if s == nil {
+		return
+	}
+
+	s.Records = append(s.Records, span)
+}
+
+// Write writes the recorded spans to path, one JSON record per line
+func (s *Spans) Write(path string) error {
+	if s == nil {
+		return nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create %s\n%w", path, err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, span := range s.Records {
+		if err := encoder.Encode(span); err != nil {
+			return fmt.Errorf("unable to write span %s\n%w", span.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// DetectSpanMetadata returns the plan entry metadata which carries the timing of the detect phase into the build
+func DetectSpanMetadata(start time.Time, end time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		planEntryDetectStart: start.Format(time.RFC3339Nano),
+		planEntryDetectEnd:   end.Format(time.RFC3339Nano),
+	}
+}
+
+// DetectSpan reads the timing of the detect phase from the plan entry metadata
+func DetectSpan(metadata map[string]interface{}) (Span, bool) {
+	rawStart, ok := metadata[planEntryDetectStart].(string)
+	if !ok {
+		return Span{}, false
+	}
+
+	rawEnd, ok := metadata[planEntryDetectEnd].(string)
+	if !ok {
+		return Span{}, false
+	}
+
+	start, err := time.Parse(time.RFC3339Nano, rawStart)
+	if err != nil {
+		return Span{}, false
+	}
+
+	end, err := time.Parse(time.RFC3339Nano, rawEnd)
+	if err != nil {
+		return Span{}, false
+	}
+
+	return Span{Name: "detect", Start: start, End: end}, true
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-community/cargo/cargo"
+	"github.com/sclevine/spec"
+)
+
+func testSpans(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		start time.Time
+		clock cargo.Clock
+	)
+
+	it.Before(func() {
+		start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		now := start
+		clock = func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}
+	})
+
+	it("records spans with the clock", func() {
+		spans := &cargo.Spans{Clock: clock}
+
+		endFetch := spans.Start("fetch", nil)
+		endInstall := spans.Start("install", map[string]string{"member": "/workspace"})
+		endInstall()
+		endFetch()
+
+		Expect(spans.Records).To(Equal([]cargo.Span{
+			{Name: "install", Start: start.Add(2 * time.Second), End: start.Add(3 * time.Second), Attributes: map[string]string{"member": "/workspace"}},
+			{Name: "fetch", Start: start.Add(time.Second), End: start.Add(4 * time.Second)},
+		}))
+	})
+
+	it("records nothing without spans", func() {
+		var spans *cargo.Spans
+
+		spans.Start("fetch", nil)()
+		spans.Add(cargo.Span{Name: "detect"})
+		Expect(spans.Write(filepath.Join(t.TempDir(), cargo.SpansFile))).To(Succeed())
+	})
+
+	it("writes one record per line", func() {
+		spans := &cargo.Spans{Clock: clock}
+		spans.Start("fetch", nil)()
+		spans.Start("install", map[string]string{"member": "/workspace"})()
+
+		path := filepath.Join(t.TempDir(), cargo.SpansFile)
+		Expect(spans.Write(path)).To(Succeed())
+
+		Expect(os.ReadFile(path)).To(Equal([]byte(
+			`{"name":"fetch","start":"2024-01-01T00:00:01Z","end":"2024-01-01T00:00:02Z"}` + "\n" +
+				`{"name":"install","start":"2024-01-01T00:00:03Z","end":"2024-01-01T00:00:04Z","attributes":{"member":"/workspace"}}` + "\n")))
+	})
+
+	it("reads the detect span from plan entry metadata", func() {
+		span, ok := cargo.DetectSpan(cargo.DetectSpanMetadata(start, start.Add(time.Second)))
+		Expect(ok).To(BeTrue())
+		Expect(span).To(Equal(cargo.Span{Name: "detect", Start: start, End: start.Add(time.Second)}))
+
+		_, ok = cargo.DetectSpan(map[string]interface{}{})
+		Expect(ok).To(BeFalse())
+
+		_, ok = cargo.DetectSpan(map[string]interface{}{"detect-start": "yesterday", "detect-end": "today"})
+		Expect(ok).To(BeFalse())
+	})
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ManifestWorkspaceMembers returns the directories of the workspace members declared in the `[workspace]` table of the
+// project's Cargo.toml, relative to applicationPath. Members can be globs like `crates/*`, which are expanded against
+// the filesystem, only directories with a Cargo.toml are members and directories in `exclude` are skipped. Cargo does
+// the same when it loads the workspace, but this works before cargo is installed.
+func ManifestWorkspaceMembers(applicationPath string) ([]string, error) {
+	var manifest struct {
+		Workspace struct {
+			Members []string `toml:"members"`
+			Exclude []string `toml:"exclude"`
+		} `toml:"workspace"`
+	}
+
+	if _, err := toml.DecodeFile(filepath.Join(applicationPath, "Cargo.toml"), &manifest); err != nil {
+		return nil, fmt.Errorf("unable to decode Cargo.toml\n%w", err)
+	}
+
+	return expandMembers(applicationPath, manifest.Workspace.Members, manifest.Workspace.Exclude)
+}
+
+// expandMembers expands the member globs of a workspace in applicationPath
+func expandMembers(applicationPath string, members []string, exclude []string) ([]string, error) {
+	seen := map[string]bool{}
+	var dirs []string
+
+	for _, member := range members {
+		matches, err := filepath.Glob(filepath.Join(applicationPath, member))
+		if err != nil {
+			return nil, fmt.Errorf("unable to expand workspace member %s\n%w", member, err)
+		}
+
+		for _, match := range matches {
+			if _, err := os.Stat(filepath.Join(match, "Cargo.toml")); errors.Is(err, os.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("unable to read workspace member %s\n%w", match, err)
+			}
+
+			rel, err := filepath.Rel(applicationPath, match)
+			if err != nil {
+				return nil, fmt.Errorf("unable to find workspace member %s\n%w", match, err)
+			}
+
+			if !seen[rel] && !excludedMember(rel, exclude) {
+				seen[rel] = true
+				dirs = append(dirs, rel)
+			}
+		}
+	}
+	sort.Strings(dirs)
+
+	return dirs, nil
+}
+
+// excludedMember checks if dir is, or is below, one of the paths in exclude
+func excludedMember(dir string, exclude []string) bool {
+	for _, e := range exclude {
+		e = filepath.Clean(e)
+		if dir == e || strings.HasPrefix(dir, e+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cargo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-community/cargo/cargo"
+	"github.com/sclevine/spec"
+)
+
+func testWorkspace(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		appDir string
+	)
+
+	it.Before(func() {
+		appDir = t.TempDir()
+
+		for _, dir := range []string{"crates/api", "crates/worker", "crates/legacy", "tools/cli"} {
+			Expect(os.MkdirAll(filepath.Join(appDir, dir), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(appDir, dir, "Cargo.toml"), []byte("[package]\nname = \"member\"\n"), 0644)).To(Succeed())
+		}
+		Expect(os.MkdirAll(filepath.Join(appDir, "crates", "docs"), 0755)).To(Succeed())
+	})
+
+	it("expands globbed members", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte("[workspace]\nmembers = [\"crates/*\", \"tools/cli\"]\n"), 0644)).To(Succeed())
+
+		Expect(cargo.ManifestWorkspaceMembers(appDir)).To(Equal([]string{"crates/api", "crates/legacy", "crates/worker", "tools/cli"}))
+	})
+
+	it("skips excluded members", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte("[workspace]\nmembers = [\"crates/*\"]\nexclude = [\"crates/legacy\"]\n"), 0644)).To(Succeed())
+
+		Expect(cargo.ManifestWorkspaceMembers(appDir)).To(Equal([]string{"crates/api", "crates/worker"}))
+	})
+
+	it("returns nothing for a package", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\n"), 0644)).To(Succeed())
+
+		Expect(cargo.ManifestWorkspaceMembers(appDir)).To(BeEmpty())
+	})
+
+	it("fails with an invalid Cargo.toml", func() {
+		Expect(os.WriteFile(filepath.Join(appDir, "Cargo.toml"), []byte("[workspace\n"), 0644)).To(Succeed())
+
+		_, err := cargo.ManifestWorkspaceMembers(appDir)
+		Expect(err).To(MatchError(ContainSubstring("unable to decode Cargo.toml")))
+	})
+}
@@ -3,18 +3,18 @@ module github.com/paketo-community/cargo
 go 1.23.4
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/buildpacks/libcnb v1.30.4
 	github.com/heroku/color v0.0.6
 	github.com/mattn/go-shellwords v1.0.12
 	github.com/onsi/gomega v1.36.2
 	github.com/paketo-buildpacks/libpak v1.72.1
-	github.com/paketo-buildpacks/source-removal v0.2.27
 	github.com/sclevine/spec v1.4.0
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/sys v0.29.0
 )
 
 require (
-	github.com/BurntSushi/toml v1.4.0 // indirect
 	github.com/Masterminds/semver/v3 v3.3.1 // indirect
 	github.com/creack/pty v1.1.24 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -27,7 +27,6 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	golang.org/x/net v0.34.0 // indirect
-	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
@@ -17,26 +17,43 @@
 package mtimes
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/paketo-buildpacks/libpak/bard"
+	"golang.org/x/sys/unix"
 )
 
 const PreserverMetadataFile = "mtimes.json"
 
+const (
+	// StrategyMTimes restores the recorded mtime of every file
+	StrategyMTimes = "mtimes"
+
+	// StrategyChecksum records the size and a checksum of every file and restores the mtime only of files whose
+	// content did not change. Only files whose size or mtime differ from the record are hashed. Files whose content
+	// changed get the current time, so they are always seen as modified.
+	StrategyChecksum = "checksum"
+)
+
 // Preserver can be used to preserve the mtimes of a directory structure to a JSON file
 type Preserver struct {
-	Logger bard.Logger
+	Logger   bard.Logger
+	Strategy string
 }
 
 type Record struct {
-	Path  string
-	MTime time.Time
+	Path     string
+	MTime    time.Time
+	Size     int64  `json:",omitempty"`
+	Checksum string `json:",omitempty"`
 }
 
 func NewPreserver(logger bard.Logger) Preserver {
@@ -47,6 +64,17 @@ func NewPreserver(logger bard.Logger) Preserver {
 
 func (p Preserver) Preserve(path string) error {
 	metadataPath := filepath.Join(path, PreserverMetadataFile)
+
+	// checksums of files which did not change since they were recorded are reused
+	var previous map[string]Record
+	if p.Strategy == StrategyChecksum {
+		var err error
+		previous, err = readRecords(metadataPath)
+		if err != nil {
+			return err
+		}
+	}
+
 	fileOut, err := os.Create(metadataPath)
 	if err != nil {
 		return fmt.Errorf("unable create metadata file %s\n%w", metadataPath, err)
@@ -60,12 +88,28 @@ func (p Preserver) Preserve(path string) error {
 			return fmt.Errorf("unable read directory\n%w", err)
 		}
 
-		fileInfo, err := d.Info()
+		// record the link's own mtime rather than the mtime of its target
+		var fileInfo fs.FileInfo
+		if d.Type()&fs.ModeSymlink != 0 {
+			fileInfo, err = os.Lstat(path)
+		} else {
+			fileInfo, err = d.Info()
+		}
 		if err != nil {
 			return fmt.Errorf("unable to read file\n%w", err)
 		}
 
-		err = jsonEncoder.Encode(Record{path, fileInfo.ModTime().UTC()})
+		record := Record{Path: path, MTime: fileInfo.ModTime().UTC()}
+		if p.Strategy == StrategyChecksum && fileInfo.Mode().IsRegular() && path != metadataPath {
+			record.Size = fileInfo.Size()
+			if prev, ok := previous[path]; ok && prev.Checksum != "" && unchanged(prev, fileInfo) {
+				record.Checksum = prev.Checksum
+			} else if record.Checksum, err = Checksum(path); err != nil {
+				return fmt.Errorf("unable to checksum file\n%w", err)
+			}
+		}
+
+		err = jsonEncoder.Encode(record)
 		if err != nil {
 			return fmt.Errorf("unable to encode mtime\n%w", err)
 		}
@@ -115,7 +159,36 @@ func (p Preserver) Restore(path string) error {
 			return fmt.Errorf("unable to decode JSON\n%w", err)
 		}
 
-		err = os.Chtimes(r.Path, r.MTime, r.MTime)
+		fileInfo, err := os.Lstat(r.Path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				p.Logger.Bodyf("unable to read file %s\n%s", r.Path, err)
+			}
+			continue
+		}
+
+		mtime := r.MTime
+		if p.Strategy == StrategyChecksum && r.Checksum != "" && !unchanged(r, fileInfo) {
+			current, err := Checksum(r.Path)
+			if err != nil {
+				p.Logger.Bodyf("unable to checksum file %s\n%s", r.Path, err)
+				continue
+			}
+
+			if current != r.Checksum {
+				mtime = time.Now()
+			}
+		}
+
+		if fileInfo.ModTime().Equal(mtime) {
+			continue
+		}
+
+		if fileInfo.Mode()&os.ModeSymlink != 0 {
+			err = lchtimes(r.Path, mtime)
+		} else {
+			err = os.Chtimes(r.Path, mtime, mtime)
+		}
 		if err != nil {
 			p.Logger.Bodyf("unable to restore time of file %s\n%w", r.Path, err)
 		}
@@ -138,3 +211,54 @@ func (p Preserver) RestoreAll(paths ...string) error {
 
 	return nil
 }
+
+// readRecords reads the records of the metadata file at metadataPath by path, nothing is read if it does not exist
+func readRecords(metadataPath string) (map[string]Record, error) {
+	fileIn, err := os.Open(metadataPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable open metadata file %s\n%w", metadataPath, err)
+	}
+	defer fileIn.Close()
+
+	records := map[string]Record{}
+	jsonDecoder := json.NewDecoder(fileIn)
+	for jsonDecoder.More() {
+		var r Record
+		if err := jsonDecoder.Decode(&r); err != nil {
+			return nil, fmt.Errorf("unable to decode JSON\n%w", err)
+		}
+		records[r.Path] = r
+	}
+
+	return records, nil
+}
+
+// unchanged returns true if the file has the recorded size and mtime, so its content is not hashed again
+func unchanged(r Record, fileInfo fs.FileInfo) bool {
+	return fileInfo.Size() == r.Size && fileInfo.ModTime().Equal(r.MTime)
+}
+
+// lchtimes sets the access and modification times of a symlink without following it
+func lchtimes(path string, mtime time.Time) error {
+	ts := unix.NsecToTimespec(mtime.UnixNano())
+	return unix.UtimesNanoAt(unix.AT_FDCWD, path, []unix.Timespec{ts, ts}, unix.AT_SYMLINK_NOFOLLOW)
+}
+
+// Checksum returns the hex encoded SHA-256 checksum of the file at path
+func Checksum(path string) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, in); err != nil {
+		return "", fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
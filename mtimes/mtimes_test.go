@@ -18,10 +18,12 @@ package mtimes_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 	"text/template"
 	"time"
@@ -29,6 +31,7 @@ import (
 	"github.com/onsi/gomega/types"
 	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/paketo-community/cargo/mtimes"
+	"golang.org/x/sys/unix"
 
 	"github.com/sclevine/spec"
 
@@ -140,6 +143,138 @@ func testMTimes(t *testing.T, context spec.G, it spec.S) {
 			Expect(filepath.Join(workDir, "testdata/folder1/folder2/folder3/file3a.txt")).To(HaveMTime("2021-04-13T21:33:21.115193516"))
 			Expect(filepath.Join(workDir, "testdata/foldera/folderb")).To(HaveMTime("2021-04-13T21:31:36.645595542"))
 		})
+
+		it("preserves the mtime of a symlink independently of its target", func() {
+			logs := bytes.Buffer{}
+
+			target := filepath.Join(workDir, "testdata/folder1/file1a.txt")
+			link := filepath.Join(workDir, "testdata/link")
+			Expect(os.Symlink(target, link)).To(Succeed())
+
+			linkTime := time.Date(2021, 4, 14, 10, 0, 0, 0, time.UTC)
+			ts := unix.NsecToTimespec(linkTime.UnixNano())
+			Expect(unix.UtimesNanoAt(unix.AT_FDCWD, link, []unix.Timespec{ts, ts}, unix.AT_SYMLINK_NOFOLLOW)).To(Succeed())
+
+			preserver := mtimes.NewPreserver(bard.NewLogger(&logs))
+			Expect(preserver.Preserve(filepath.Join(workDir, "testdata"))).To(Succeed())
+
+			// touching the link with os.Chtimes changes the target, not the link
+			now := time.Now().UTC()
+			Expect(os.Chtimes(link, now, now)).To(Succeed())
+			Expect(link).To(HaveMTime(linkTime))
+			Expect(target).To(HaveMTime(now))
+
+			Expect(preserver.Restore(filepath.Join(workDir, "testdata"))).To(Succeed())
+			Expect(link).To(HaveMTime(linkTime))
+			Expect(target).To(HaveMTime("2021-04-13T21:32:11.619000841"))
+		})
+
+		context("restore strategies", func() {
+			var (
+				changed     string
+				unchanged   string
+				fingerprint time.Time
+			)
+
+			// simulates a build on a filesystem which loses mtimes, every file gets a new mtime and one changes content
+			loseMTimes := func() {
+				now := time.Now().UTC()
+				Expect(filepath.WalkDir(filepath.Join(workDir, "testdata"), func(path string, d fs.DirEntry, err error) error {
+					Expect(err).ToNot(HaveOccurred())
+					return os.Chtimes(path, now, now)
+				})).To(Succeed())
+				Expect(os.WriteFile(changed, []byte("changed"), 0644)).To(Succeed())
+			}
+
+			it.Before(func() {
+				changed = filepath.Join(workDir, "testdata/folder1/file1b.txt")
+				unchanged = filepath.Join(workDir, "testdata/folder1/file1a.txt")
+			})
+
+			it("restores the recorded mtimes, even for changed files, with the mtimes strategy", func() {
+				preserver := mtimes.Preserver{Logger: bard.NewLogger(&bytes.Buffer{}), Strategy: mtimes.StrategyMTimes}
+				Expect(preserver.Preserve(filepath.Join(workDir, "testdata"))).To(Succeed())
+				fingerprint = time.Now().UTC()
+
+				loseMTimes()
+				Expect(preserver.Restore(filepath.Join(workDir, "testdata"))).To(Succeed())
+
+				Expect(unchanged).To(HaveMTime("2021-04-13T21:32:11.619000841"))
+				// the changed file looks older than the last build, so it would not be rebuilt
+				Expect(changed).To(HaveMTime("2021-04-13T21:32:16.562185855"))
+				Expect(modTime(changed).Before(fingerprint)).To(BeTrue())
+			})
+
+			it("only restores unchanged files with the checksum strategy", func() {
+				preserver := mtimes.Preserver{Logger: bard.NewLogger(&bytes.Buffer{}), Strategy: mtimes.StrategyChecksum}
+				Expect(preserver.Preserve(filepath.Join(workDir, "testdata"))).To(Succeed())
+				fingerprint = time.Now().UTC()
+
+				buf, err := os.ReadFile(filepath.Join(workDir, "testdata/mtimes.json"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(buf)).To(ContainSubstring(`"Checksum":"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"`))
+
+				loseMTimes()
+				Expect(preserver.Restore(filepath.Join(workDir, "testdata"))).To(Succeed())
+
+				Expect(unchanged).To(HaveMTime("2021-04-13T21:32:11.619000841"))
+				Expect(filepath.Join(workDir, "testdata/folder1")).To(HaveMTime("2021-04-13T21:32:16.56220856"))
+				// the changed file is newer than the last build, so it will be rebuilt
+				Expect(modTime(changed).After(fingerprint)).To(BeTrue())
+			})
+
+			it("only hashes files whose size or mtime changed with the checksum strategy", func() {
+				info, err := os.Stat(unchanged)
+				Expect(err).ToNot(HaveOccurred())
+
+				// a checksum which does not match the content is only noticed if the file is hashed
+				Expect(writeRecords(filepath.Join(workDir, "testdata/mtimes.json"),
+					mtimes.Record{Path: unchanged, MTime: info.ModTime().UTC(), Size: info.Size(), Checksum: "not-hashed"})).To(Succeed())
+
+				preserver := mtimes.Preserver{Logger: bard.NewLogger(&bytes.Buffer{}), Strategy: mtimes.StrategyChecksum}
+				Expect(preserver.Restore(filepath.Join(workDir, "testdata"))).To(Succeed())
+				Expect(unchanged).To(HaveMTime(info.ModTime().UTC()))
+
+				Expect(preserver.Preserve(filepath.Join(workDir, "testdata"))).To(Succeed())
+				buf, err := os.ReadFile(filepath.Join(workDir, "testdata/mtimes.json"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(buf)).To(ContainSubstring(`"Checksum":"not-hashed"`))
+			})
+		})
+
+		it("skips files whose mtime already matches", func() {
+			logs := bytes.Buffer{}
+
+			path := filepath.Join(workDir, "testdata/folder1/file1a.txt")
+			mTime, err := time.Parse("2006-01-02T15:04:05.999999999", "2021-04-13T21:32:11.619000841")
+			Expect(err).ToNot(HaveOccurred())
+			aTime := time.Unix(0, 0).UTC()
+			Expect(os.Chtimes(path, aTime, mTime)).To(Succeed())
+
+			Expect(writeRecords(filepath.Join(workDir, "testdata/mtimes.json"), mtimes.Record{Path: path, MTime: mTime})).To(Succeed())
+
+			Expect(mtimes.NewPreserver(bard.NewLogger(&logs)).Restore(filepath.Join(workDir, "testdata"))).To(Succeed())
+			Expect(path).To(HaveMTime(mTime))
+
+			// os.Chtimes would have reset the access time as well
+			Expect(accessTime(path)).To(Equal(aTime))
+			Expect(logs.String()).To(BeEmpty())
+		})
+
+		it("silently skips files that no longer exist", func() {
+			logs := bytes.Buffer{}
+
+			missing := filepath.Join(workDir, "testdata/folder1/deleted.txt")
+			present := filepath.Join(workDir, "testdata/folder1/file1b.txt")
+			Expect(writeRecords(filepath.Join(workDir, "testdata/mtimes.json"),
+				mtimes.Record{Path: missing, MTime: time.Unix(0, 0).UTC()},
+				mtimes.Record{Path: present, MTime: time.Unix(0, 0).UTC()})).To(Succeed())
+
+			Expect(mtimes.NewPreserver(bard.NewLogger(&logs)).Restore(filepath.Join(workDir, "testdata"))).To(Succeed())
+			Expect(missing).ToNot(BeAnExistingFile())
+			Expect(present).To(HaveMTime(time.Unix(0, 0).UTC()))
+			Expect(logs.String()).To(BeEmpty())
+		})
 	})
 }
 
@@ -188,6 +323,41 @@ func (matcher *haveMTimeMatcher) NegatedFailureMessage(_ interface{}) (message s
 	return fmt.Sprintf("Expected %s not to equal %s", matcher.actual, matcher.expected)
 }
 
+func writeRecords(path string, records ...mtimes.Record) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create %s\n%w", path, err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, r := range records {
+		if err := encoder.Encode(r); err != nil {
+			return fmt.Errorf("unable to encode record\n%w", err)
+		}
+	}
+
+	return file.Close()
+}
+
+func modTime(path string) time.Time {
+	fileInfo, err := os.Lstat(path)
+	if err != nil {
+		panic(fmt.Errorf("unable to read file info %s\n%w", path, err))
+	}
+
+	return fileInfo.ModTime()
+}
+
+func accessTime(path string) (time.Time, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return time.Time{}, fmt.Errorf("unable to stat %s\n%w", path, err)
+	}
+
+	return time.Unix(stat.Atim.Sec, stat.Atim.Nsec).UTC(), nil
+}
+
 func touch(fileName string) error {
 	dirName := filepath.Dir(fileName)
 	err := os.MkdirAll(dirName, 0755)
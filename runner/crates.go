@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// InstalledCrate is a crate `cargo install` recorded in `$CARGO_HOME/.crates2.json`
+type InstalledCrate struct {
+	Name    string
+	Version string
+	Source  string
+	Bins    []string
+}
+
+// ReadInstalledCrates reads the crates installed with `cargo install` from `.crates2.json` in cargoHome, sorted by
+// name. No crates are returned if the file doesn't exist.
+func ReadInstalledCrates(cargoHome string) ([]InstalledCrate, error) {
+	path := filepath.Join(cargoHome, ".crates2.json")
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	var tracker struct {
+		Installs map[string]struct {
+			Bins []string `json:"bins"`
+		} `json:"installs"`
+	}
+	if err := json.Unmarshal(content, &tracker); err != nil {
+		return nil, fmt.Errorf("unable to decode %s\n%w", path, err)
+	}
+
+	var crates []InstalledCrate
+	for key, install := range tracker.Installs {
+		// keys are package ids like `cargo-audit 0.17.0 (registry+https://github.com/rust-lang/crates.io-index)`
+		fields := strings.SplitN(key, " ", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("unable to parse package id %q in %s", key, path)
+		}
+
+		crate := InstalledCrate{Name: fields[0], Version: fields[1], Bins: install.Bins}
+		if len(fields) == 3 {
+			crate.Source = strings.TrimSuffix(strings.TrimPrefix(fields[2], "("), ")")
+		}
+		crates = append(crates, crate)
+	}
+
+	sort.Slice(crates, func(i, j int) bool {
+		return crates[i].Name < crates[j].Name || crates[i].Name == crates[j].Name && crates[i].Version < crates[j].Version
+	})
+
+	return crates, nil
+}
+
+// toolInstalled checks if a tool installed with args is recorded in `.crates2.json` and its binaries are still in
+// `$CARGO_HOME/bin`. Only a tool pinned to an exact version is skipped, without a version or for a version requirement
+// cargo decides, so an unpinned tool is updated when there is a newer release.
+func (c CargoRunner) toolInstalled(name string, args []string) (InstalledCrate, bool, error) {
+	version := ""
+	for i, arg := range args {
+		switch {
+		case arg == "--force" || arg == "-f":
+			return InstalledCrate{}, false, nil
+		case (arg == "--version" || arg == "--vers") && i+1 < len(args):
+			version = args[i+1]
+		case strings.HasPrefix(arg, "--version="):
+			version = strings.TrimPrefix(arg, "--version=")
+		}
+	}
+
+	if version == "" || strings.Count(version, ".") != 2 || strings.ContainsAny(version, "^~=<>*") {
+		return InstalledCrate{}, false, nil
+	}
+
+	crates, err := ReadInstalledCrates(c.CargoHome)
+	if err != nil {
+		return InstalledCrate{}, false, err
+	}
+
+	for _, crate := range crates {
+		if crate.Name != name || crate.Version != version {
+			continue
+		}
+
+		for _, bin := range crate.Bins {
+			if _, err := os.Stat(filepath.Join(c.CargoHome, "bin", bin)); err != nil {
+				return InstalledCrate{}, false, nil
+			}
+		}
+
+		return crate, true, nil
+	}
+
+	return InstalledCrate{}, false, nil
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+
+	"github.com/paketo-buildpacks/libpak/effect"
+)
+
+// ContextExecutor is an executor which can stop the command it runs when the context is done
+type ContextExecutor interface {
+	effect.Executor
+
+	// ExecuteContext executes the command described in the Execution and kills it when ctx is done
+	ExecuteContext(ctx context.Context, execution effect.Execution) error
+}
+
+// CommandExecutor runs commands like effect.CommandExecutor. The commands run in their own process group, so
+// stopping one also kills the processes it started, like the `rustc` processes of `cargo`.
+type CommandExecutor struct{}
+
+func (e CommandExecutor) Execute(execution effect.Execution) error {
+	return e.ExecuteContext(context.Background(), execution)
+}
+
+func (CommandExecutor) ExecuteContext(ctx context.Context, execution effect.Execution) error {
+	cmd := exec.CommandContext(ctx, execution.Command, execution.Args...)
+
+	if execution.Dir != "" {
+		cmd.Dir = execution.Dir
+	}
+
+	if len(execution.Env) > 0 {
+		cmd.Env = execution.Env
+	}
+
+	cmd.Stdin = execution.Stdin
+	cmd.Stdout = execution.Stdout
+	cmd.Stderr = execution.Stderr
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	return cmd.Run()
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-community/cargo/runner"
+	"github.com/sclevine/spec"
+)
+
+func testExecutor(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+	)
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+	})
+
+	it("runs the command", func() {
+		buf := &bytes.Buffer{}
+
+		Expect(runner.CommandExecutor{}.Execute(effect.Execution{
+			Command: "sh",
+			Args:    []string{"-c", `echo "$GREETING $(basename "$PWD")"`},
+			Dir:     workingDir,
+			Env:     []string{"GREETING=hello"},
+			Stdout:  buf,
+		})).To(Succeed())
+
+		Expect(buf.String()).To(Equal("hello " + filepath.Base(workingDir) + "\n"))
+	})
+
+	it("kills the command and the processes it started when the context is done", func() {
+		marker := filepath.Join(workingDir, "finished")
+
+		ctx, cancel := contextWithTimeout(100 * time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := runner.CommandExecutor{}.ExecuteContext(ctx, effect.Execution{
+			Command: "sh",
+			Args:    []string{"-c", `(sleep 1; touch "$0") & wait`, marker},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+
+		time.Sleep(1500 * time.Millisecond)
+		Expect(marker).NotTo(BeAnExistingFile())
+	})
+}
+
+func contextWithTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), timeout)
+}
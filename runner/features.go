@@ -0,0 +1,189 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ParseMemberFeatures parses a `;` separated list of `<member>=<features>` entries into a map of workspace member name
+// to the features enabled for it, the features are separated by commas or spaces
+func ParseMemberFeatures(raw string) (map[string][]string, error) {
+	features := map[string][]string{}
+
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, rawFeatures, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("unable to parse %q, expected <member>=<features>", entry)
+		}
+
+		features[name] = splitFeatures(rawFeatures)
+	}
+
+	return features, nil
+}
+
+// memberFeatures returns the features configured for the member in memberPath, if there are any
+func (c CargoRunner) memberFeatures(srcDir string, memberPath string) ([]string, bool, error) {
+	memberDir := memberPath
+	if !filepath.IsAbs(memberDir) {
+		memberDir = filepath.Join(srcDir, memberDir)
+	}
+
+	m, err := c.fetchCargoMetadata(srcDir)
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to load cargo metadata\n%w", err)
+	}
+
+	for _, id := range m.WorkspaceMembers {
+		pkgDir, err := packagePath(id)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if filepath.Clean(pkgDir) != filepath.Clean(memberDir) {
+			continue
+		}
+
+		name, _, _, err := ParseWorkspaceMember(id)
+		if err != nil {
+			return nil, false, fmt.Errorf("unable to parse: %w", err)
+		}
+
+		features, ok := c.MemberFeatures[name]
+		return features, ok, nil
+	}
+
+	return nil, false, nil
+}
+
+// memberTargets reads the targets of a member by running `cargo metadata` in the member's directory with its
+// features, along with the member's feature table to resolve the features they enable
+func (c CargoRunner) memberTargets(id string, features []string) ([]metadataTarget, map[string][]string, error) {
+	memberDir, err := packagePath(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var args []string
+	if len(features) > 0 {
+		args = append(args, fmt.Sprintf("--features=%s", strings.Join(features, ",")))
+	}
+
+	m, err := c.fetchCargoMetadata(memberDir, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to load cargo metadata of %s\n%w", memberDir, err)
+	}
+
+	for _, pkg := range m.Packages {
+		if pkg.ID == id {
+			return pkg.Targets, pkg.Features, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("unable to find %s in the cargo metadata of %s", id, memberDir)
+}
+
+// featureSelection is the set of features a package is built with
+type featureSelection struct {
+	all       bool
+	noDefault bool
+	selected  []string
+}
+
+// featureSelection returns the features `cargo install` runs with, which are selected by the install args if they
+// select any or by features, the features configured for all members or for the member
+func (c CargoRunner) featureSelection(features string) (featureSelection, error) {
+	args, err := c.installArgs()
+	if err != nil {
+		return featureSelection{}, fmt.Errorf("unable to parse install args\n%w", err)
+	}
+
+	selection := featureSelection{}
+	fromArgs := false
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch name {
+		case "--all-features":
+			selection.all = true
+		case "--no-default-features":
+			selection.noDefault = true
+		case "--features", "-F":
+			if !hasValue && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			selection.selected = append(selection.selected, splitFeatures(value)...)
+			fromArgs = true
+		}
+	}
+
+	if !fromArgs {
+		selection.selected = splitFeatures(features)
+	}
+
+	return selection, nil
+}
+
+// enabled checks that every required feature is enabled by the selection, directly or through the features they
+// enable in the package's feature table. Features of dependencies, like `serde/derive`, can't be resolved without
+// the dependencies and are assumed to be enabled.
+func (f featureSelection) enabled(required []string, table map[string][]string) bool {
+	if f.all {
+		return true
+	}
+
+	active := map[string]bool{}
+
+	pending := append([]string{}, f.selected...)
+	if !f.noDefault {
+		pending = append(pending, "default")
+	}
+
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+
+		if active[name] {
+			continue
+		}
+
+		active[name] = true
+		pending = append(pending, table[name]...)
+	}
+
+	for _, name := range required {
+		if !active[name] && !strings.Contains(name, "/") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitFeatures splits a comma or space separated list of features
+func splitFeatures(features string) []string {
+	return strings.FieldsFunc(features, func(r rune) bool { return r == ',' || r == ' ' })
+}
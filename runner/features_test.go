@@ -0,0 +1,180 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/effect/mocks"
+	"github.com/paketo-community/cargo/runner"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+)
+
+const featuresMetadata = `{
+  "packages": [
+    {
+      "id": "path+file:///workspace/api#api@1.0.0",
+      "features": {"default": [], "admin": [], "full": ["admin"]},
+      "targets": [
+        {"kind": ["bin"], "crate_types": ["bin"], "name": "api", "src_path": "/workspace/api/src/main.rs"},
+        {"kind": ["bin"], "crate_types": ["bin"], "name": "api-admin", "src_path": "/workspace/api/src/bin/admin.rs", "required-features": ["admin"]}
+      ]
+    },
+    {
+      "id": "path+file:///workspace/worker#worker@1.0.0",
+      "features": {},
+      "targets": [
+        {"kind": ["bin"], "crate_types": ["bin"], "name": "worker", "src_path": "/workspace/worker/src/main.rs"}
+      ]
+    }
+  ],
+  "workspace_members": ["path+file:///workspace/api#api@1.0.0", "path+file:///workspace/worker#worker@1.0.0"]
+}`
+
+func testFeatures(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		executor *mocks.Executor
+	)
+
+	it.Before(func() {
+		executor = &mocks.Executor{}
+		executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+			return ex.Args[0] == "metadata"
+		})).Return(func(ex effect.Execution) error {
+			_, err := ex.Stdout.Write([]byte(featuresMetadata))
+			Expect(err).ToNot(HaveOccurred())
+			return nil
+		})
+		executor.On("Execute", mock.Anything).Return(nil)
+	})
+
+	it("parses member features", func() {
+		features, err := runner.ParseMemberFeatures("api=server,tls ; worker=queue;cli=")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(features).To(Equal(map[string][]string{
+			"api":    {"server", "tls"},
+			"worker": {"queue"},
+			"cli":    {},
+		}))
+
+		_, err = runner.ParseMemberFeatures("server,tls")
+		Expect(err).To(MatchError(ContainSubstring(`unable to parse "server,tls"`)))
+	})
+
+	it("includes a bin only when its required features are enabled for the member", func() {
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithExecutor(executor),
+			runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+			runner.WithMemberFeatures(map[string][]string{"api": {"full"}}))
+
+		targets, err := cargoRunner.ProjectTargets("/workspace")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(targets).To(Equal([]string{"api", "api-admin", "worker"}))
+
+		e := executor.Calls[1].Arguments[0].(effect.Execution)
+		Expect(e.Dir).To(Equal("/workspace/api"))
+		Expect(e.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps", "--features=full"}))
+	})
+
+	it("excludes a bin when its required features are not enabled for the member", func() {
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithExecutor(executor),
+			runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+			runner.WithMemberFeatures(map[string][]string{"api": {}}))
+
+		targets, err := cargoRunner.ProjectTargets("/workspace")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(targets).To(Equal([]string{"api", "worker"}))
+	})
+
+	it("installs a member with its own features", func() {
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithCargoHome(t.TempDir()),
+			runner.WithExecutor(executor),
+			runner.WithFeatures("everything"),
+			runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+			runner.WithMemberFeatures(map[string][]string{"api": {"full"}}))
+
+		Expect(cargoRunner.InstallMember("/workspace/api", "/workspace", libcnb.Layer{Path: t.TempDir()})).To(Succeed())
+		Expect(cargoRunner.InstallMember("/workspace/worker", "/workspace", libcnb.Layer{Path: t.TempDir()})).To(Succeed())
+
+		var installs [][]string
+		for _, call := range executor.Calls {
+			if e := call.Arguments[0].(effect.Execution); e.Args[0] == "install" {
+				installs = append(installs, e.Args)
+			}
+		}
+		Expect(installs).To(HaveLen(2))
+		Expect(installs[0]).To(ContainElement("--features=full"))
+		Expect(installs[0]).ToNot(ContainElement("--features=everything"))
+		Expect(installs[1]).To(ContainElement("--features=everything"))
+	})
+
+	context("required features", func() {
+		it("excludes bins whose required features are not enabled", func() {
+			cargoRunner := runner.NewCargoRunner(
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			targets, err := cargoRunner.ProjectTargets("/workspace")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(targets).To(Equal([]string{"api", "worker"}))
+		})
+
+		it("includes bins whose required features are enabled through other features", func() {
+			cargoRunner := runner.NewCargoRunner(
+				runner.WithExecutor(executor),
+				runner.WithFeatures("full"),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			targets, err := cargoRunner.ProjectTargets("/workspace")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(targets).To(Equal([]string{"api", "api-admin", "worker"}))
+		})
+
+		it("uses the features selected by the install args", func() {
+			cargoRunner := runner.NewCargoRunner(
+				runner.WithCargoInstallArgs("--features admin"),
+				runner.WithExecutor(executor),
+				runner.WithFeatures("other"),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			targets, err := cargoRunner.ProjectTargets("/workspace")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(targets).To(Equal([]string{"api", "api-admin", "worker"}))
+		})
+
+		it("includes every bin with all features", func() {
+			cargoRunner := runner.NewCargoRunner(
+				runner.WithCargoInstallArgs("--all-features"),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			targets, err := cargoRunner.ProjectTargets("/workspace")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(targets).To(Equal([]string{"api", "api-admin", "worker"}))
+		})
+	})
+}
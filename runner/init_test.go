@@ -25,6 +25,13 @@ import (
 
 func TestUnitRunner(t *testing.T) {
 	suite := spec.New("Runners", spec.Report(report.Terminal{}))
+	suite("Executor", testExecutor)
+	suite("Features", testFeatures)
+	suite("Linker", testLinker)
+	suite("Retry", testRetry)
 	suite("Runner", testRunners)
+	suite("Strip", testStrip)
+	suite("Verify", testVerify)
+	suite("Warm", testWarm)
 	suite.Run(t)
 }
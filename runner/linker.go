@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ActiveTarget returns the target triple selected by `--target` in args or, without one, the triple of the build host
+func ActiveTarget(args []string) string {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "--target=") {
+			return strings.TrimPrefix(arg, "--target=")
+		}
+
+		if arg == "--target" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+
+	return fmt.Sprintf("%s-unknown-linux-gnu", archFromSystem())
+}
+
+// WriteLinkerConfig sets `target.<triple>.linker` in the project's `.cargo/config.toml`, or the legacy
+// `.cargo/config` if only that exists, keeping all other settings
+func WriteLinkerConfig(srcDir string, target string, linker string) (string, error) {
+	path := filepath.Join(srcDir, ".cargo", "config.toml")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if _, err := os.Stat(filepath.Join(srcDir, ".cargo", "config")); err == nil {
+			path = filepath.Join(srcDir, ".cargo", "config")
+		}
+	}
+
+	config := map[string]interface{}{}
+	if _, err := toml.DecodeFile(path, &config); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("unable to decode %s\n%w", path, err)
+	}
+
+	targets, ok := config["target"].(map[string]interface{})
+	if !ok {
+		targets = map[string]interface{}{}
+		config["target"] = targets
+	}
+
+	settings, ok := targets[target].(map[string]interface{})
+	if !ok {
+		settings = map[string]interface{}{}
+		targets[target] = settings
+	}
+	settings["linker"] = linker
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("unable to create %s\n%w", filepath.Dir(path), err)
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer out.Close()
+
+	if err := toml.NewEncoder(out).Encode(config); err != nil {
+		return "", fmt.Errorf("unable to encode %s\n%w", path, err)
+	}
+
+	return path, nil
+}
+
+// configureLinker writes the configured linker for the target selected by args into the project's cargo config
+func (c CargoRunner) configureLinker(srcDir string, args []string) error {
+	target := ActiveTarget(args)
+
+	if _, err := exec.LookPath(c.Linker); err != nil {
+		c.Logger.Bodyf("WARNING: linker %s was not found on the PATH, the build may fail", c.Linker)
+	}
+
+	if c.DryRun {
+		c.Logger.Bodyf("Dry run, skipping: setting target.%s.linker = %q", target, c.Linker)
+		return nil
+	}
+
+	path, err := WriteLinkerConfig(srcDir, target, c.Linker)
+	if err != nil {
+		return err
+	}
+
+	c.Logger.Bodyf("Using linker %s for target %s in %s", c.Linker, target, path)
+	return nil
+}
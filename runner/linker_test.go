@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/effect/mocks"
+	"github.com/paketo-community/cargo/runner"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+)
+
+func testLinker(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		srcDir string
+	)
+
+	it.Before(func() {
+		srcDir = t.TempDir()
+	})
+
+	var readConfig = func(path string) map[string]interface{} {
+		config := map[string]interface{}{}
+		_, err := toml.DecodeFile(path, &config)
+		Expect(err).ToNot(HaveOccurred())
+		return config
+	}
+
+	it("uses the target from the arguments", func() {
+		Expect(runner.ActiveTarget([]string{"install", "--target=aarch64-unknown-linux-musl"})).To(Equal("aarch64-unknown-linux-musl"))
+		Expect(runner.ActiveTarget([]string{"install", "--target", "x86_64-unknown-linux-musl"})).To(Equal("x86_64-unknown-linux-musl"))
+	})
+
+	context("no target is selected", func() {
+		it.Before(func() {
+			t.Setenv("BP_ARCH", "arm64")
+		})
+
+		it("uses the build host", func() {
+			Expect(runner.ActiveTarget([]string{"install"})).To(Equal("aarch64-unknown-linux-gnu"))
+		})
+	})
+
+	it("creates .cargo/config.toml", func() {
+		path, err := runner.WriteLinkerConfig(srcDir, "x86_64-unknown-linux-musl", "musl-gcc")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path).To(Equal(filepath.Join(srcDir, ".cargo", "config.toml")))
+
+		Expect(readConfig(path)).To(Equal(map[string]interface{}{
+			"target": map[string]interface{}{
+				"x86_64-unknown-linux-musl": map[string]interface{}{"linker": "musl-gcc"},
+			},
+		}))
+	})
+
+	it("keeps existing settings", func() {
+		Expect(os.MkdirAll(filepath.Join(srcDir, ".cargo"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(srcDir, ".cargo", "config.toml"), []byte(`
+[build]
+target = "x86_64-unknown-linux-musl"
+
+[target.x86_64-unknown-linux-musl]
+rustflags = ["-C", "target-cpu=native"]
+linker = "cc"
+`), 0644)).To(Succeed())
+
+		path, err := runner.WriteLinkerConfig(srcDir, "x86_64-unknown-linux-musl", "rust-lld")
+		Expect(err).ToNot(HaveOccurred())
+
+		config := readConfig(path)
+		Expect(config["build"]).To(Equal(map[string]interface{}{"target": "x86_64-unknown-linux-musl"}))
+		Expect(config["target"]).To(Equal(map[string]interface{}{
+			"x86_64-unknown-linux-musl": map[string]interface{}{
+				"linker":    "rust-lld",
+				"rustflags": []interface{}{"-C", "target-cpu=native"},
+			},
+		}))
+	})
+
+	it("uses the legacy .cargo/config if only it exists", func() {
+		Expect(os.MkdirAll(filepath.Join(srcDir, ".cargo"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(srcDir, ".cargo", "config"), []byte("[net]\noffline = true\n"), 0644)).To(Succeed())
+
+		path, err := runner.WriteLinkerConfig(srcDir, "aarch64-unknown-linux-gnu", "rust-lld")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path).To(Equal(filepath.Join(srcDir, ".cargo", "config")))
+		Expect(filepath.Join(srcDir, ".cargo", "config.toml")).ToNot(BeAnExistingFile())
+		Expect(readConfig(path)).To(HaveKeyWithValue("net", map[string]interface{}{"offline": true}))
+	})
+
+	it("configures the linker for the active target before installing", func() {
+		logBuf := &bytes.Buffer{}
+		executor := &mocks.Executor{}
+		executor.On("Execute", mock.Anything).Return(nil)
+
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithCargoHome(t.TempDir()),
+			runner.WithExecutor(executor),
+			runner.WithLinker("linker-which-does-not-exist"),
+			runner.WithLogger(bard.NewLogger(logBuf)),
+			runner.WithTarget("x86_64-unknown-linux-musl"))
+
+		Expect(cargoRunner.Install(srcDir, libcnb.Layer{Path: t.TempDir()})).To(Succeed())
+
+		config := readConfig(filepath.Join(srcDir, ".cargo", "config.toml"))
+		Expect(config["target"]).To(HaveKeyWithValue("x86_64-unknown-linux-musl", map[string]interface{}{"linker": "linker-which-does-not-exist"}))
+		Expect(logBuf.String()).To(ContainSubstring("WARNING: linker linker-which-does-not-exist was not found on the PATH"))
+
+		e := executor.Calls[0].Arguments[0].(effect.Execution)
+		Expect(e.Args).To(ContainElement("--target=x86_64-unknown-linux-musl"))
+	})
+}
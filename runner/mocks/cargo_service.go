@@ -6,6 +6,8 @@ import (
 	libcnb "github.com/buildpacks/libcnb"
 	mock "github.com/stretchr/testify/mock"
 
+	runner "github.com/paketo-community/cargo/runner"
+
 	url "net/url"
 )
 
@@ -14,6 +16,20 @@ type CargoService struct {
 	mock.Mock
 }
 
+// Audit provides a mock function with given fields: srcDir
+func (_m *CargoService) Audit(srcDir string) error {
+	ret := _m.Called(srcDir)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(srcDir)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CargoVersion provides a mock function with given fields:
 func (_m *CargoService) CargoVersion() (string, error) {
 	ret := _m.Called()
@@ -49,6 +65,34 @@ func (_m *CargoService) CleanCargoHomeCache() error {
 	return r0
 }
 
+// CompileWarnings provides a mock function with given fields:
+func (_m *CargoService) CompileWarnings() int {
+	ret := _m.Called()
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// Fetch provides a mock function with given fields: srcDir
+func (_m *CargoService) Fetch(srcDir string) error {
+	ret := _m.Called(srcDir)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(srcDir)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Install provides a mock function with given fields: srcDir, destLayer
 func (_m *CargoService) Install(srcDir string, destLayer libcnb.Layer) error {
 	ret := _m.Called(srcDir, destLayer)
@@ -63,6 +107,29 @@ func (_m *CargoService) Install(srcDir string, destLayer libcnb.Layer) error {
 	return r0
 }
 
+// InstalledBinaries provides a mock function with given fields: destLayer
+func (_m *CargoService) InstalledBinaries(destLayer libcnb.Layer) ([]string, error) {
+	ret := _m.Called(destLayer)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(libcnb.Layer) []string); ok {
+		r0 = rf(destLayer)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(libcnb.Layer) error); ok {
+		r1 = rf(destLayer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // InstallMember provides a mock function with given fields: memberPath, srcDir, destLayer
 func (_m *CargoService) InstallMember(memberPath string, srcDir string, destLayer libcnb.Layer) error {
 	ret := _m.Called(memberPath, srcDir, destLayer)
@@ -91,6 +158,43 @@ func (_m *CargoService) InstallTool(name string, additionalArgs []string) error
 	return r0
 }
 
+// PreBuild provides a mock function with given fields: srcDir, command
+func (_m *CargoService) PreBuild(srcDir string, command string) error {
+	ret := _m.Called(srcDir, command)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(srcDir, command)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ProjectTargetDetails provides a mock function with given fields: srcDir
+func (_m *CargoService) ProjectTargetDetails(srcDir string) ([]runner.Target, error) {
+	ret := _m.Called(srcDir)
+
+	var r0 []runner.Target
+	if rf, ok := ret.Get(0).(func(string) []runner.Target); ok {
+		r0 = rf(srcDir)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]runner.Target)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(srcDir)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ProjectTargets provides a mock function with given fields: srcDir
 func (_m *CargoService) ProjectTargets(srcDir string) ([]string, error) {
 	ret := _m.Called(srcDir)
@@ -114,6 +218,20 @@ func (_m *CargoService) ProjectTargets(srcDir string) ([]string, error) {
 	return r0, r1
 }
 
+// PruneRegistrySources provides a mock function with given fields: srcDir
+func (_m *CargoService) PruneRegistrySources(srcDir string) error {
+	ret := _m.Called(srcDir)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(srcDir)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // RustVersion provides a mock function with given fields:
 func (_m *CargoService) RustVersion() (string, error) {
 	ret := _m.Called()
@@ -135,6 +253,111 @@ func (_m *CargoService) RustVersion() (string, error) {
 	return r0, r1
 }
 
+// StripBinaries provides a mock function with given fields: binDir
+func (_m *CargoService) StripBinaries(binDir string) error {
+	ret := _m.Called(binDir)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(binDir)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Test provides a mock function with given fields: srcDir
+func (_m *CargoService) Test(srcDir string) error {
+	ret := _m.Called(srcDir)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(srcDir)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Toolchain provides a mock function with given fields:
+func (_m *CargoService) Toolchain() (string, string, error) {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func() string); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func() error); ok {
+		r2 = rf()
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ToolchainRequirements provides a mock function with given fields: srcDir
+func (_m *CargoService) ToolchainRequirements(srcDir string) (runner.ToolchainRequirements, error) {
+	ret := _m.Called(srcDir)
+
+	var r0 runner.ToolchainRequirements
+	if rf, ok := ret.Get(0).(func(string) runner.ToolchainRequirements); ok {
+		r0 = rf(srcDir)
+	} else {
+		r0 = ret.Get(0).(runner.ToolchainRequirements)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(srcDir)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ValidateManifest provides a mock function with given fields: srcDir
+func (_m *CargoService) ValidateManifest(srcDir string) error {
+	ret := _m.Called(srcDir)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(srcDir)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VerifyBinaries provides a mock function with given fields: binDir
+func (_m *CargoService) VerifyBinaries(binDir string) error {
+	ret := _m.Called(binDir)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(binDir)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // WorkspaceMembers provides a mock function with given fields: srcDir, destLayer
 func (_m *CargoService) WorkspaceMembers(srcDir string, destLayer libcnb.Layer) ([]url.URL, error) {
 	ret := _m.Called(srcDir, destLayer)
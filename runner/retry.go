@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/paketo-buildpacks/libpak/bard"
+)
+
+// Backoff returns how long to wait before a retry, retries are counted from 1
+type Backoff func(retry int) time.Duration
+
+// ExponentialBackoff doubles the wait for every retry, starting with base and never waiting longer than max
+func ExponentialBackoff(base time.Duration, max time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		wait := base
+		for i := 1; i < retry && wait < max; i++ {
+			wait *= 2
+		}
+
+		if wait > max {
+			return max
+		}
+		return wait
+	}
+}
+
+// DefaultBackoff is used when no Backoff is configured
+var DefaultBackoff = ExponentialBackoff(time.Second, 30*time.Second)
+
+// Retry runs f and, if it fails, retries it up to retries times waiting between attempts as given by backoff
+func Retry(logger bard.Logger, name string, retries int, backoff Backoff, f func() error) error {
+	return RetryIf(logger, name, retries, backoff, nil, f)
+}
+
+// RetryIf works like Retry, but only retries failures for which retryable returns true. A nil retryable retries all
+// failures.
+func RetryIf(logger bard.Logger, name string, retries int, backoff Backoff, retryable func(error) bool, f func() error) error {
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+
+	attempts := retries + 1
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = f(); err == nil {
+			return nil
+		}
+
+		if retryable != nil && !retryable(err) {
+			return err
+		}
+
+		if attempt < attempts {
+			wait := backoff(attempt)
+			logger.Bodyf("%s failed (attempt %d of %d), retrying in %s", name, attempt, attempts, wait)
+			time.Sleep(wait)
+		}
+	}
+
+	return fmt.Errorf("%s failed after %d attempts\n%w", name, attempts, err)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-community/cargo/runner"
+	"github.com/sclevine/spec"
+)
+
+func testRetry(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		noWait = func(int) time.Duration { return 0 }
+	)
+
+	it("doubles the wait up to the maximum", func() {
+		backoff := runner.ExponentialBackoff(time.Second, 5*time.Second)
+
+		Expect(backoff(1)).To(Equal(time.Second))
+		Expect(backoff(2)).To(Equal(2 * time.Second))
+		Expect(backoff(3)).To(Equal(4 * time.Second))
+		Expect(backoff(4)).To(Equal(5 * time.Second))
+		Expect(backoff(100)).To(Equal(5 * time.Second))
+	})
+
+	it("retries until it succeeds", func() {
+		buf := &bytes.Buffer{}
+
+		calls := 0
+		err := runner.Retry(bard.NewLogger(buf), "cargo fetch", 3, noWait, func() error {
+			calls++
+			if calls < 3 {
+				return errors.New("network unreachable")
+			}
+			return nil
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(calls).To(Equal(3))
+		Expect(buf.String()).To(ContainSubstring("cargo fetch failed (attempt 1 of 4), retrying in 0s"))
+		Expect(buf.String()).To(ContainSubstring("cargo fetch failed (attempt 2 of 4), retrying in 0s"))
+	})
+
+	it("returns the last error when all attempts fail", func() {
+		calls := 0
+		err := runner.Retry(bard.NewLogger(&bytes.Buffer{}), "cargo fetch", 2, noWait, func() error {
+			calls++
+			return errors.New("network unreachable")
+		})
+
+		Expect(err).To(MatchError("cargo fetch failed after 3 attempts\nnetwork unreachable"))
+		Expect(calls).To(Equal(3))
+	})
+
+	it("runs once without retries", func() {
+		calls := 0
+		err := runner.Retry(bard.NewLogger(&bytes.Buffer{}), "cargo fetch", 0, noWait, func() error {
+			calls++
+			return errors.New("network unreachable")
+		})
+
+		Expect(err).To(HaveOccurred())
+		Expect(calls).To(Equal(1))
+	})
+
+	it("stops at failures that are not retryable", func() {
+		calls := 0
+		err := runner.RetryIf(bard.NewLogger(&bytes.Buffer{}), "cargo install", 3, noWait, func(err error) bool {
+			return err.Error() == "lock held"
+		}, func() error {
+			calls++
+			if calls == 1 {
+				return errors.New("lock held")
+			}
+			return errors.New("could not compile")
+		})
+
+		Expect(err).To(MatchError("could not compile"))
+		Expect(calls).To(Equal(2))
+	})
+}
@@ -18,20 +18,32 @@ package runner
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/url"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/buildpacks/libcnb"
 	"github.com/mattn/go-shellwords"
 	"github.com/paketo-buildpacks/libpak"
 	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/paketo-buildpacks/libpak/effect"
-	"github.com/paketo-buildpacks/libpak/sherpa"
 )
 
 //go:generate mockery --name CargoService --case underscore
@@ -40,11 +52,62 @@ type CargoService interface {
 	Install(srcDir string, destLayer libcnb.Layer) error
 	InstallMember(memberPath string, srcDir string, destLayer libcnb.Layer) error
 	InstallTool(name string, additionalArgs []string) error
+	InstalledBinaries(destLayer libcnb.Layer) ([]string, error)
+	CompileWarnings() int
 	WorkspaceMembers(srcDir string, destLayer libcnb.Layer) ([]url.URL, error)
 	ProjectTargets(srcDir string) ([]string, error)
+	ProjectTargetDetails(srcDir string) ([]Target, error)
 	CleanCargoHomeCache() error
+	PruneRegistrySources(srcDir string) error
+	StripBinaries(binDir string) error
+	VerifyBinaries(binDir string) error
 	CargoVersion() (string, error)
 	RustVersion() (string, error)
+	Toolchain() (cargoVersion string, rustVersion string, err error)
+	Audit(srcDir string) error
+	Fetch(srcDir string) error
+	PreBuild(srcDir string, command string) error
+	ToolchainRequirements(srcDir string) (ToolchainRequirements, error)
+	ValidateManifest(srcDir string) error
+	Test(srcDir string) error
+}
+
+// Target is a binary target of the project
+type Target struct {
+	// Name is the name of the binary
+	Name string
+
+	// Member is the name of the workspace member which owns the binary
+	Member string
+
+	// MemberPath is the path of the workspace member which owns the binary
+	MemberPath string
+
+	// Example is set if the binary is an example, from `examples/`, and not a binary target
+	Example bool
+
+	// Edition is the Rust edition the binary is compiled with
+	Edition string
+
+	// SrcPath is the path of the binary's main source file
+	SrcPath string
+}
+
+// ErrInstallTimedOut is returned when `cargo install` for a workspace member takes longer than the install timeout
+var ErrInstallTimedOut = errors.New("cargo install timed out")
+
+// DefaultCleanHomeExcept are the entries of CARGO_HOME which are kept when cleaning it. `.crates.toml` and
+// `.crates2.json` track binaries installed with `cargo install`, like tools.
+var DefaultCleanHomeExcept = []string{"bin", "registry", "git", ".crates.toml", ".crates2.json"}
+
+// ToolchainRequirements are the Rust toolchain requirements of the workspace members
+type ToolchainRequirements struct {
+	// Editions are the distinct Rust editions used by the members, sorted
+	Editions []string
+
+	// MSRV is the minimum Rust version which builds all members, the highest `rust-version` of the members or empty
+	// if no member sets `rust-version`
+	MSRV string
 }
 
 const (
@@ -52,9 +115,55 @@ const (
 	StaticTypeGNULIBC = "gnulibc"
 )
 
+const (
+	ColorNever  = "never"
+	ColorAlways = "always"
+	ColorAuto   = "auto"
+)
+
 // Option is a function for configuring a CargoRunner
 type Option func(runner CargoRunner) CargoRunner
 
+// WithBackoff sets how long to wait between retries
+func WithBackoff(backoff Backoff) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.Backoff = backoff
+		return runner
+	}
+}
+
+// WithBinaryProbes sets the arguments binaries are run with when they are verified, by binary name
+func WithBinaryProbes(probes map[string][]string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.BinaryProbes = probes
+		return runner
+	}
+}
+
+// WithBinExcludePatterns sets glob patterns for binary targets that are neither installed nor used as process types
+func WithBinExcludePatterns(patterns []string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.BinExcludePatterns = patterns
+		return runner
+	}
+}
+
+// WithCargoAuditIgnore sets a comma or space separated list of advisory ids for `cargo audit` to ignore
+func WithCargoAuditIgnore(ids string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.CargoAuditIgnore = ids
+		return runner
+	}
+}
+
+// WithCargoEnv sets additional `KEY=VALUE` environment variables for `cargo install`
+func WithCargoEnv(env []string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.CargoEnv = env
+		return runner
+	}
+}
+
 // WithCargoHome sets CARGO_HOME
 func WithCargoHome(cargoHome string) Option {
 	return func(runner CargoRunner) CargoRunner {
@@ -79,6 +188,22 @@ func WithCargoInstallArgs(installArgs string) Option {
 	}
 }
 
+// WithCleanHomeExcept sets the entries of CARGO_HOME to keep when cleaning it, everything else is removed
+func WithCleanHomeExcept(keep []string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.CleanHomeExcept = keep
+		return runner
+	}
+}
+
+// WithDryRun sets if cargo commands and cleanup actions are only logged, without running them
+func WithDryRun(dryRun bool) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.DryRun = dryRun
+		return runner
+	}
+}
+
 // WithExecutor sets the executor to use when running cargo
 func WithExecutor(executor effect.Executor) Option {
 	return func(runner CargoRunner) CargoRunner {
@@ -87,6 +212,94 @@ func WithExecutor(executor effect.Executor) Option {
 	}
 }
 
+// WithFeatures sets the features which are enabled with `--features` for `cargo install`
+func WithFeatures(features string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.Features = features
+		return runner
+	}
+}
+
+// WithFetchRetry sets how often `cargo fetch` is retried when it fails
+func WithFetchRetry(retries int) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.FetchRetry = retries
+		return runner
+	}
+}
+
+// WithIncludeExamples sets if examples are installed and reported as targets along with binaries
+func WithIncludeExamples(includeExamples bool) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.IncludeExamples = includeExamples
+		return runner
+	}
+}
+
+// WithIncludeDepBins sets if binary targets whose source lives outside of the project, like bins re-exported from dependencies, are reported as targets
+func WithIncludeDepBins(includeDepBins bool) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.IncludeDepBins = includeDepBins
+		return runner
+	}
+}
+
+// WithInstallRetries sets how often `cargo install` is retried when it fails
+func WithInstallRetries(retries int) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.InstallRetries = retries
+		return runner
+	}
+}
+
+// WithInstallRetryOnLock sets how often `cargo install` is retried when it fails waiting for a file lock
+func WithInstallRetryOnLock(retries int) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.InstallRetryOnLock = retries
+		return runner
+	}
+}
+
+// WithInstallTimeout sets how long `cargo install` may run for each workspace member, zero means no limit
+func WithInstallTimeout(timeout time.Duration) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.InstallTimeout = timeout
+		return runner
+	}
+}
+
+// WithJobs sets how many jobs cargo runs in parallel with `--jobs`, zero keeps cargo's default
+func WithJobs(jobs int) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.Jobs = jobs
+		return runner
+	}
+}
+
+// WithKeepDebugSymbols disables stripping of binaries built with cargo install
+func WithKeepDebugSymbols(keepDebugSymbols bool) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.KeepDebugSymbols = keepDebugSymbols
+		return runner
+	}
+}
+
+// WithMemberFeatures sets the features enabled per workspace member, they replace the features set for all members
+func WithMemberFeatures(features map[string][]string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.MemberFeatures = features
+		return runner
+	}
+}
+
+// WithMetadataArgs sets additional args to pass to cargo metadata, they should be filtered with FilterMetadataArgs
+func WithMetadataArgs(args []string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.MetadataArgs = args
+		return runner
+	}
+}
+
 // WithLogger sets additional args to pass to cargo install
 func WithLogger(logger bard.Logger) Option {
 	return func(runner CargoRunner) CargoRunner {
@@ -95,6 +308,14 @@ func WithLogger(logger bard.Logger) Option {
 	}
 }
 
+// WithSccacheDir enables sccache as `RUSTC_WRAPPER` and sets the directory where it caches compiled objects
+func WithSccacheDir(dir string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.SccacheDir = dir
+		return runner
+	}
+}
+
 // WithStack sets the stack on which we're running
 func WithStack(stack string) Option {
 	return func(runner CargoRunner) CargoRunner {
@@ -103,6 +324,62 @@ func WithStack(stack string) Option {
 	}
 }
 
+// WithLinker sets the linker which is configured for the target in the project's `.cargo/config.toml`
+func WithLinker(linker string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.Linker = linker
+		return runner
+	}
+}
+
+// WithLocked sets if `--locked` is passed to cargo, so Cargo.lock is never updated during the build
+func WithLocked(locked bool) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.Locked = locked
+		return runner
+	}
+}
+
+// WithRegistryCacheMaxBytes limits the size of the registry cache, the oldest crates are pruned after a build
+func WithRegistryCacheMaxBytes(maxBytes int64) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.RegistryCacheMaxBytes = maxBytes
+		return runner
+	}
+}
+
+// WithSrcKeep sets for how many builds unused extracted crate sources are kept in the registry
+func WithSrcKeep(builds int) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.SrcKeep = builds
+		return runner
+	}
+}
+
+// WithRustFlags sets flags which are merged into the inherited RUSTFLAGS for `cargo install`
+func WithRustFlags(flags string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.RustFlags = flags
+		return runner
+	}
+}
+
+// WithTarget sets the target triple to build for
+func WithTarget(target string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.Target = target
+		return runner
+	}
+}
+
+// WithStripArgs sets the arguments `strip` is run with by StripBinaries
+func WithStripArgs(args []string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.StripArgs = args
+		return runner
+	}
+}
+
 // WithStaticType sets the static type to use
 func WithStaticType(staticType string) Option {
 	return func(runner CargoRunner) CargoRunner {
@@ -111,15 +388,135 @@ func WithStaticType(staticType string) Option {
 	}
 }
 
+// WithVerbosity sets how verbose cargo is when building, 1 passes `-v` and 2 passes `-vv`, zero keeps cargo's default
+func WithVerbosity(verbosity int) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.Verbosity = verbosity
+		return runner
+	}
+}
+
+// WithBuildBeforeInstall runs `cargo build` before `cargo install`, which reuses the compiled artifacts
+func WithBuildBeforeInstall(buildBeforeInstall bool) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.BuildBeforeInstall = buildBeforeInstall
+		return runner
+	}
+}
+
+// WithCargoInstallArgsFile sets a file of additional args to pass to cargo install, after those of WithCargoInstallArgs
+func WithCargoInstallArgsFile(path string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.CargoInstallArgsFile = path
+		return runner
+	}
+}
+
+// WithBins sets the binary targets to install, all of them are installed if none are set
+func WithBins(bins []string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.Bins = bins
+		return runner
+	}
+}
+
+// WithColor sets when cargo colors its output, one of ColorNever, ColorAlways or ColorAuto
+func WithColor(color string) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.Color = color
+		return runner
+	}
+}
+
+// WithFrozen sets if `--frozen` is passed to cargo, which implies `--locked` and `--offline` and takes precedence over them
+func WithFrozen(frozen bool) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.Frozen = frozen
+		return runner
+	}
+}
+
+// WithOffline sets if `--offline` is passed to cargo, so it doesn't access the network
+func WithOffline(offline bool) Option {
+	return func(runner CargoRunner) CargoRunner {
+		runner.Offline = offline
+		return runner
+	}
+}
+
 // CargoRunner can execute cargo via CLI
 type CargoRunner struct {
+	Backoff               Backoff
+	BinaryProbes          map[string][]string
+	BinExcludePatterns    []string
+	Bins                  []string
+	BuildBeforeInstall    bool
+	CargoAuditIgnore      string
+	CargoEnv              []string
 	CargoHome             string
 	CargoWorkspaceMembers string
 	CargoInstallArgs      string
+	CargoInstallArgsFile  string
+	CleanHomeExcept       []string
+	Color                 string
+	DryRun                bool
 	Executor              effect.Executor
+	Features              string
+	FetchRetry            int
+	Frozen                bool
+	IncludeDepBins        bool
+	IncludeExamples       bool
+	InstallRetries        int
+	InstallRetryOnLock    int
+	InstallTimeout        time.Duration
+	Jobs                  int
+	KeepDebugSymbols      bool
+	Linker                string
+	Locked                bool
 	Logger                bard.Logger
+	MemberFeatures        map[string][]string
+	MetadataArgs          []string
+	Offline               bool
+	RegistryCacheMaxBytes int64
+	RustFlags             string
+	SccacheDir            string
+	SrcKeep               int
 	Stack                 string
 	StaticType            string
+	StripArgs             []string
+	Target                string
+	Verbosity             int
+
+	metadataCache   *metadataCache
+	toolchainCache  *toolchainCache
+	warningsCounter *warningsCounter
+}
+
+// warningsCounter adds up the compile warnings of all installs, it's shared by copies of the runner
+type warningsCounter struct {
+	mutex sync.Mutex
+	count int
+}
+
+// toolchainCache keeps the toolchain versions for the lifetime of a runner, it's shared by copies of the runner
+type toolchainCache struct {
+	mutex        sync.Mutex
+	found        bool
+	cargoVersion string
+	rustVersion  string
+}
+
+// metadataCache keeps the parsed `cargo metadata` of each source directory for the lifetime of a runner,
+// it's shared by copies of the runner
+type metadataCache struct {
+	mutex   sync.Mutex
+	entries map[metadataCacheKey]metadata
+}
+
+// metadataCacheKey identifies a `cargo metadata` run by its directory and additional arguments, like features
+type metadataCacheKey struct {
+	dir  string
+	args string
 }
 
 type metadataTarget struct {
@@ -131,11 +528,16 @@ type metadataTarget struct {
 	Doc        bool     `json:"doc"`
 	Doctest    bool     `json:"doctest"`
 	Test       bool     `json:"test"`
+
+	RequiredFeatures []string `json:"required-features"`
 }
 
 type metadataPackage struct {
-	ID      string
-	Targets []metadataTarget `json:"targets"`
+	ID          string
+	Edition     string              `json:"edition"`
+	Features    map[string][]string `json:"features"`
+	RustVersion string              `json:"rust_version"`
+	Targets     []metadataTarget    `json:"targets"`
 }
 
 type metadata struct {
@@ -145,7 +547,11 @@ type metadata struct {
 
 // NewCargoRunner creates a new cargo runner with the given options
 func NewCargoRunner(options ...Option) CargoRunner {
-	runner := CargoRunner{}
+	runner := CargoRunner{
+		metadataCache:   &metadataCache{entries: map[metadataCacheKey]metadata{}},
+		toolchainCache:  &toolchainCache{},
+		warningsCounter: &warningsCounter{},
+	}
 
 	for _, option := range options {
 		runner = option(runner)
@@ -159,260 +565,1427 @@ func (c CargoRunner) Install(srcDir string, destLayer libcnb.Layer) error {
 	return c.InstallMember(".", srcDir, destLayer)
 }
 
-// InstallMember will build and install a specific workspace member using `cargo install`
-func (c CargoRunner) InstallMember(memberPath string, srcDir string, destLayer libcnb.Layer) error {
-	// makes warning from `cargo install` go away
-	path := os.Getenv("PATH")
-	if path != "" && !strings.Contains(path, destLayer.Path) {
-		path = sherpa.AppendToEnvVar("PATH", ":", filepath.Join(destLayer.Path, "bin"))
-		err := os.Setenv("PATH", path)
+// InstalledBinaries returns the absolute paths of the files installed into `bin` of destLayer, sorted. `cargo install`
+// puts the binaries of every target, including one selected with `--target`, directly into `bin`, files in
+// subdirectories, like a directory per target triple, are returned too.
+func (c CargoRunner) InstalledBinaries(destLayer libcnb.Layer) ([]string, error) {
+	binDir := filepath.Join(destLayer.Path, "bin")
+
+	var binaries []string
+	err := filepath.WalkDir(binDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return fmt.Errorf("unable to update PATH\n%w", err)
+			return err
 		}
-	}
-
-	args, err := c.BuildArgs(destLayer, memberPath)
-	if err != nil {
-		return fmt.Errorf("unable to build args\n%w", err)
-	}
 
-	c.Logger.Bodyf("cargo %s", strings.Join(args, " "))
-	if err := c.Executor.Execute(effect.Execution{
-		Command: "cargo",
-		Args:    args,
-		Dir:     srcDir,
-		Stdout:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
-		Stderr:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
-	}); err != nil {
-		return fmt.Errorf("unable to build\n%w", err)
+		if !d.IsDir() {
+			binaries = append(binaries, path)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to list binaries in %s\n%w", binDir, err)
 	}
 
-	err = c.CleanCargoHomeCache()
-	if err != nil {
-		return fmt.Errorf("unable to cleanup: %w", err)
-	}
-	return nil
+	sort.Strings(binaries)
+	return binaries, nil
 }
 
-func (c CargoRunner) InstallTool(name string, additionalArgs []string) error {
-	args := []string{"install", name}
-	args = append(args, additionalArgs...)
+// InstallMember will build and install a specific workspace member using `cargo install`
+func (c CargoRunner) InstallMember(memberPath string, srcDir string, destLayer libcnb.Layer) error {
+	// finding the member's features runs `cargo metadata`, so a dry run uses the features set for all members
+	if len(c.MemberFeatures) > 0 && !c.DryRun {
+		features, ok, err := c.memberFeatures(srcDir, memberPath)
+		if err != nil {
+			return fmt.Errorf("unable to find member features\n%w", err)
+		}
 
-	c.Logger.Bodyf("cargo %s", strings.Join(args, " "))
-	if err := c.Executor.Execute(effect.Execution{
-		Command: "cargo",
-		Args:    args,
-		Stdout:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
-		Stderr:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
-	}); err != nil {
-		return fmt.Errorf("unable to install tool\n%w", err)
+		if ok {
+			c.Features = strings.Join(features, ",")
+		}
 	}
 
-	return nil
-}
+	// `--bin` fails for a binary the member doesn't have, so only the member's binaries are selected
+	if len(c.Bins) > 0 && !c.DryRun {
+		bins, err := c.memberBinaries(srcDir, memberPath)
+		if err != nil {
+			return fmt.Errorf("unable to select binaries\n%w", err)
+		}
 
-// WorkspaceMembers loads the members from the project workspace
-func (c CargoRunner) WorkspaceMembers(srcDir string, destLayer libcnb.Layer) ([]url.URL, error) {
-	m, err := c.fetchCargoMetadata(srcDir)
-	if err != nil {
-		return []url.URL{}, fmt.Errorf("unable to load cargo metadata\n%w", err)
+		c.Bins = slices.DeleteFunc(slices.Clone(c.Bins), func(bin string) bool { return !slices.Contains(bins, bin) })
+		if len(c.Bins) == 0 {
+			c.Logger.Bodyf("Skipping %s, none of its binary targets are in BP_CARGO_BINS", memberPath)
+			return nil
+		}
 	}
 
-	filterMap := c.makeFilterMap()
+	args, err := c.BuildArgs(destLayer, RelativeMemberPath(srcDir, memberPath))
+	if err != nil {
+		return fmt.Errorf("unable to build args\n%w", err)
+	}
 
-	var paths []url.URL
-	for _, workspace := range m.WorkspaceMembers {
-		pkgName, _, pathUrl, err := ParseWorkspaceMember(workspace)
+	// selecting binaries runs `cargo metadata`, so a dry run shows the arguments without the selection
+	if len(c.BinExcludePatterns) > 0 && !hasBinSelection(args) && !c.DryRun {
+		bins, err := c.memberBinaries(srcDir, memberPath)
 		if err != nil {
-			return nil, fmt.Errorf("unable to parse: %w", err)
+			return fmt.Errorf("unable to select binaries\n%w", err)
 		}
 
-		if len(filterMap) > 0 && filterMap[strings.TrimSpace(pkgName)] || len(filterMap) == 0 {
-			path, err := url.Parse(pathUrl)
-			if err != nil {
-				return nil, fmt.Errorf("unable to parse path URL %s: %w", workspace, err)
+		if len(bins) > 0 {
+			included := c.filterExcludedBinaries(bins)
+			if len(included) == 0 {
+				c.Logger.Bodyf("Skipping %s, all of its binary targets are excluded", memberPath)
+				return nil
+			}
+
+			for _, bin := range included {
+				args = append(args, fmt.Sprintf("--bin=%s", bin))
 			}
-			paths = append(paths, *path)
+		}
+	}
+
+	// `--examples` alone installs only examples, so binaries have to be selected too
+	if c.IncludeExamples && !hasExampleSelection(args) {
+		if !hasBinSelection(args) {
+			args = append(args, "--bins")
+		}
+		args = append(args, "--examples")
+	}
+
+	if c.Linker != "" {
+		if err := c.configureLinker(srcDir, args); err != nil {
+			return fmt.Errorf("unable to configure linker\n%w", err)
+		}
+	}
+
+	if c.DryRun {
+		if c.BuildBeforeInstall {
+			c.Logger.Bodyf("Dry run, skipping: cargo %s", strings.Join(WarmBuildArgs(args), " "))
+		}
+		c.Logger.Bodyf("Dry run, skipping: cargo %s", strings.Join(args, " "))
+		return c.CleanCargoHomeCache()
+	}
+
+	if c.SccacheDir != "" {
+		if err := c.checkSccache(); err != nil {
+			return err
+		}
+	}
+
+	// makes warning from `cargo install` go away, only this command sees the layer on the PATH
+	env := withPath(c.installEnv(), destLayer.Path, filepath.Join(destLayer.Path, "bin"))
+
+	if c.BuildBeforeInstall {
+		if err := c.warmBuild(srcDir, args, env); err != nil {
+			return err
+		}
+	}
+
+	var stderr *bytes.Buffer
+	install := func() error {
+		// every attempt gets its own buffer, a timed out attempt may still be writing to the previous one
+		stderr = &bytes.Buffer{}
+
+		c.Logger.Bodyf("cargo %s", strings.Join(args, " "))
+		return c.executeWithTimeout(effect.Execution{
+			Command: "cargo",
+			Args:    args,
+			Dir:     srcDir,
+			Env:     env,
+			Stdout:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+			Stderr:  io.MultiWriter(bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)), stderr),
+		})
+	}
+
+	// lock contention is retried on its own, so it does not use up the retries for other failures
+	attempt := install
+	if c.InstallRetryOnLock > 0 {
+		attempt = func() error {
+			return RetryIf(c.Logger, "cargo install", c.InstallRetryOnLock, c.Backoff, func(error) bool {
+				return IsLockContention(stderr.String())
+			}, install)
+		}
+	}
+
+	// a timed out install would most likely time out again
+	if c.InstallRetries > 0 {
+		err = RetryIf(c.Logger, "cargo install", c.InstallRetries, c.Backoff, func(err error) bool {
+			return !errors.Is(err, ErrInstallTimedOut)
+		}, attempt)
+	} else {
+		err = attempt()
+	}
+
+	if err != nil {
+		if errors.Is(err, ErrInstallTimedOut) {
+			return fmt.Errorf("unable to build %s, it did not finish within %s\n%w", memberPath, c.InstallTimeout, err)
+		}
+		if IsLockFileOutdated(stderr.String()) {
+			return fmt.Errorf("unable to build, Cargo.lock is out of date and --locked or --frozen prevents updating it, run `cargo update` and commit Cargo.lock or set BP_CARGO_LOCKED=false and BP_CARGO_FROZEN=false\n%w", err)
+		}
+		if IsLockContention(stderr.String()) {
+			return fmt.Errorf("unable to build, another cargo process holds a file lock, set BP_CARGO_INSTALL_RETRY_ON_LOCK to wait for it\n%w", err)
+		}
+		return fmt.Errorf("unable to build\n%w", err)
+	}
+
+	c.addCompileWarnings(CountCompileWarnings(stderr.String()))
+
+	if c.SccacheDir != "" {
+		c.logSccacheStats(env)
+	}
+
+	err = c.CleanCargoHomeCache()
+	if err != nil {
+		return fmt.Errorf("unable to cleanup: %w", err)
+	}
+
+	if c.RegistryCacheMaxBytes > 0 {
+		if err := c.PruneRegistryCache(c.RegistryCacheMaxBytes); err != nil {
+			return fmt.Errorf("unable to prune registry cache\n%w", err)
+		}
+	}
+
+	return nil
+}
+
+// CompileWarnings returns the number of compile warnings cargo reported for all installs so far
+func (c CargoRunner) CompileWarnings() int {
+	if c.warningsCounter == nil {
+		return 0
+	}
+
+	c.warningsCounter.mutex.Lock()
+	defer c.warningsCounter.mutex.Unlock()
+
+	return c.warningsCounter.count
+}
+
+func (c CargoRunner) addCompileWarnings(count int) {
+	if c.warningsCounter == nil {
+		return
+	}
+
+	c.warningsCounter.mutex.Lock()
+	defer c.warningsCounter.mutex.Unlock()
+
+	c.warningsCounter.count += count
+}
+
+// compileWarningsSummary matches the summary cargo prints for each crate with warnings, like
+// "warning: `app` (bin "app") generated 3 warnings (1 duplicate)"
+var compileWarningsSummary = regexp.MustCompile(`(?m)^warning: .* generated (\d+) warnings?\b`)
+
+// CountCompileWarnings adds up the warnings of the summaries in the output of cargo
+func CountCompileWarnings(output string) int {
+	count := 0
+	for _, match := range compileWarningsSummary.FindAllStringSubmatch(output, -1) {
+		if n, err := strconv.Atoi(match[1]); err == nil {
+			count += n
+		}
+	}
+	return count
+}
+
+// executeWithTimeout runs execution and stops it after InstallTimeout. An executor which is not a ContextExecutor
+// cannot stop the command, then only the wait for it is given up and the build fails while cargo is still running.
+func (c CargoRunner) executeWithTimeout(execution effect.Execution) error {
+	if c.InstallTimeout <= 0 {
+		return c.Executor.Execute(execution)
+	}
+
+	if executor, ok := c.Executor.(ContextExecutor); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), c.InstallTimeout)
+		defer cancel()
+
+		err := executor.ExecuteContext(ctx, execution)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrInstallTimedOut
+		}
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Executor.Execute(execution)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(c.InstallTimeout):
+		return ErrInstallTimedOut
+	}
+}
+
+// checkSccache makes sure that sccache can be run before it is used as `RUSTC_WRAPPER`
+func (c CargoRunner) checkSccache() error {
+	buf := &bytes.Buffer{}
+
+	if err := c.Executor.Execute(effect.Execution{
+		Command: "sccache",
+		Args:    []string{"--version"},
+		Stdout:  buf,
+		Stderr:  buf,
+	}); err != nil {
+		return fmt.Errorf("unable to find sccache, it must be installed when BP_CARGO_SCCACHE is set\n%s\n%w", buf.String(), err)
+	}
+
+	return nil
+}
+
+// logSccacheStats logs the sccache statistics, failing to read them does not fail the build
+func (c CargoRunner) logSccacheStats(env []string) {
+	c.Logger.Body("sccache --show-stats")
+	if err := c.Executor.Execute(effect.Execution{
+		Command: "sccache",
+		Args:    []string{"--show-stats"},
+		Env:     env,
+		Stdout:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+		Stderr:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+	}); err != nil {
+		c.Logger.Bodyf("Unable to read sccache statistics: %s", err)
+	}
+}
+
+// InstallTool installs a tool with `cargo install`, unless it is already installed in CargoHome and additionalArgs
+// don't contain `--force`
+func (c CargoRunner) InstallTool(name string, additionalArgs []string) error {
+	args := []string{"install", name}
+	args = append(args, additionalArgs...)
+
+	if crate, ok, err := c.toolInstalled(name, additionalArgs); err != nil {
+		return fmt.Errorf("unable to check installed tools\n%w", err)
+	} else if ok {
+		c.Logger.Bodyf("Using cached %s %s", crate.Name, crate.Version)
+		return nil
+	}
+
+	if c.DryRun {
+		c.Logger.Bodyf("Dry run, skipping: cargo %s", strings.Join(args, " "))
+		return nil
+	}
+
+	c.Logger.Bodyf("cargo %s", strings.Join(args, " "))
+	if err := c.Executor.Execute(effect.Execution{
+		Command: "cargo",
+		Args:    args,
+		Stdout:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+		Stderr:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+	}); err != nil {
+		return fmt.Errorf("unable to install tool\n%w", err)
+	}
+
+	return nil
+}
+
+// Test runs the tests of the workspace members in CargoWorkspaceMembers, or of the whole workspace, using `cargo test`.
+// The test builds go to the project's target directory, so they don't end up in the layer binaries are installed to.
+func (c CargoRunner) Test(srcDir string) error {
+	args := AddColor([]string{"test"}, c.Color)
+
+	filterMap := c.makeFilterMap()
+	delete(filterMap, "")
+	if len(filterMap) == 0 {
+		args = append(args, "--workspace")
+	} else {
+		var members []string
+		for member := range filterMap {
+			members = append(members, member)
+		}
+		sort.Strings(members)
+
+		for _, member := range members {
+			args = append(args, "--package", member)
+		}
+	}
+
+	args = AddTarget(args, c.Target)
+	args = AddFeatures(args, c.Features)
+	args = AddJobs(args, c.Jobs)
+	args = AddVerbosity(args, c.Verbosity)
+	args = c.addLockArgs(args)
+
+	if c.Linker != "" {
+		if err := c.configureLinker(srcDir, args); err != nil {
+			return fmt.Errorf("unable to configure linker\n%w", err)
+		}
+	}
+
+	if c.DryRun {
+		c.Logger.Bodyf("Dry run, skipping: cargo %s", strings.Join(args, " "))
+		return nil
+	}
+
+	c.Logger.Bodyf("cargo %s", strings.Join(args, " "))
+	if err := c.Executor.Execute(effect.Execution{
+		Command: "cargo",
+		Args:    args,
+		Dir:     srcDir,
+		Env:     c.installEnv(),
+		Stdout:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+		Stderr:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+	}); err != nil {
+		return fmt.Errorf("cargo test failed\n%w", err)
+	}
+
+	return nil
+}
+
+// PreBuild runs command with `sh -c` in srcDir before the application is built, for code generation which
+// `cargo install` depends on. It gets the same environment as `cargo install`. The cached `cargo metadata` of srcDir
+// is dropped afterwards.
+func (c CargoRunner) PreBuild(srcDir string, command string) error {
+	if c.DryRun {
+		c.Logger.Bodyf("Dry run, skipping: sh -c %q", command)
+		return nil
+	}
+
+	c.Logger.Bodyf("Running pre-build command: %s", command)
+	if err := c.Executor.Execute(effect.Execution{
+		Command: "sh",
+		Args:    []string{"-c", command},
+		Dir:     srcDir,
+		Env:     c.installEnv(),
+		Stdout:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+		Stderr:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+	}); err != nil {
+		return fmt.Errorf("pre-build command %q failed\n%w", command, err)
+	}
+
+	// the command may have changed the manifests, like generating a workspace member
+	c.InvalidateMetadata(srcDir)
+
+	return nil
+}
+
+// Audit checks the project's Cargo.lock for crates with known security vulnerabilities using `cargo audit`
+func (c CargoRunner) Audit(srcDir string) error {
+	args := []string{"audit", "--color=never"}
+	for _, id := range strings.FieldsFunc(c.CargoAuditIgnore, func(r rune) bool { return r == ',' || r == ' ' }) {
+		args = append(args, "--ignore", id)
+	}
+
+	if c.DryRun {
+		c.Logger.Bodyf("Dry run, skipping: cargo %s", strings.Join(args, " "))
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+
+	c.Logger.Bodyf("cargo %s", strings.Join(args, " "))
+	if err := c.Executor.Execute(effect.Execution{
+		Command: "cargo",
+		Args:    args,
+		Dir:     srcDir,
+		Stdout:  buf,
+		Stderr:  buf,
+	}); err != nil {
+		if IsVulnerabilityFound(buf.String()) {
+			return fmt.Errorf("cargo audit failed, vulnerable crates found:\n%s\n%w", buf.String(), err)
+		}
+		return fmt.Errorf("cargo audit failed:\n%s\n%w", buf.String(), err)
+	}
+
+	return nil
+}
+
+// ValidateManifest checks that the manifests of the project can be loaded by running `cargo metadata`, which is
+// cached so later steps don't have to run it again
+func (c CargoRunner) ValidateManifest(srcDir string) error {
+	if c.DryRun {
+		c.Logger.Bodyf("Dry run, skipping: cargo %s", strings.Join(c.cargoMetadataArgs(), " "))
+		return nil
+	}
+
+	c.Logger.Bodyf("cargo %s", strings.Join(c.cargoMetadataArgs(), " "))
+	if _, err := c.fetchCargoMetadata(srcDir); err != nil {
+		return fmt.Errorf("invalid Cargo manifest in %s, fix it and try again\n%w", srcDir, err)
+	}
+
+	return nil
+}
+
+// Fetch downloads the dependencies of the project using `cargo fetch`, retrying the whole command with backoff
+func (c CargoRunner) Fetch(srcDir string) error {
+	installArgs, err := c.installArgs()
+	if err != nil {
+		return err
+	}
+
+	args := AddColor([]string{"fetch"}, c.Color)
+	for _, arg := range installArgs {
+		if arg == "--locked" || arg == "--frozen" || arg == "--offline" {
+			args = append(args, arg)
+		}
+	}
+	args = AddTarget(args, c.Target)
+
+	args = c.addLockArgs(args)
+
+	if c.DryRun {
+		c.Logger.Bodyf("Dry run, skipping: cargo %s", strings.Join(args, " "))
+		return nil
+	}
+
+	env := c.installEnv()
+
+	return Retry(c.Logger, "cargo fetch", c.FetchRetry, c.Backoff, func() error {
+		c.Logger.Bodyf("cargo %s", strings.Join(args, " "))
+		return c.Executor.Execute(effect.Execution{
+			Command: "cargo",
+			Args:    args,
+			Dir:     srcDir,
+			Env:     env,
+			Stdout:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+			Stderr:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+		})
+	})
+}
+
+// WorkspaceMembers loads the members from the project workspace
+func (c CargoRunner) WorkspaceMembers(srcDir string, destLayer libcnb.Layer) ([]url.URL, error) {
+	if c.DryRun {
+		c.Logger.Bodyf("Dry run, skipping: cargo %s, using %s as the only member", strings.Join(c.cargoMetadataArgs(), " "), srcDir)
+		return []url.URL{{Scheme: "file", Path: srcDir}}, nil
+	}
+
+	m, err := c.fetchCargoMetadata(srcDir)
+	if err != nil {
+		return []url.URL{}, fmt.Errorf("unable to load cargo metadata\n%w", err)
+	}
+
+	filterMap := c.makeFilterMap()
+
+	var paths []url.URL
+	for _, workspace := range m.WorkspaceMembers {
+		pkgName, _, pathUrl, err := ParseWorkspaceMember(workspace)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse: %w", err)
+		}
+
+		if len(filterMap) > 0 && filterMap[strings.TrimSpace(pkgName)] || len(filterMap) == 0 {
+			if !IsLocalMember(pathUrl) {
+				c.Logger.Bodyf("WARNING: skipping workspace member %s, it comes from a %s source and only path members can be installed", pkgName, SourceKind(pathUrl))
+				continue
+			}
+
+			path, err := url.Parse(pathUrl)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse path URL %s: %w", workspace, err)
+			}
+			paths = append(paths, *path)
 		}
 	}
 
 	return paths, nil
 }
 
-// parseWorkspaceMember parses a workspace member which can be in a couple of different formats
+// ParseWorkspaceMember parses a workspace member which can be in a couple of different formats
 //
 //	pre-1.77: `package-name package-version (url)`, like `function 0.1.0 (path+file:///Users/dmikusa/Downloads/fn-rs)`
 //	1.77+: `url#package-name@package-version` like `path+file:///Users/dmikusa/Downloads/fn-rs#function@0.1.0`
 //
+// The URL may use any source kind, like `git+https://` or `registry+https://`, and in the 1.77+ format the package
+// name is left out if it matches the last segment of the URL path, like `path+file:///workspace/basics#2.0.0`.
+//
 // returns the package name, version, URL, and optional error in that order
 func ParseWorkspaceMember(workspaceMember string) (string, string, string, error) {
-	if strings.HasPrefix(workspaceMember, "path+file://") {
-		half := strings.SplitN(workspaceMember, "#", 2)
-		if len(half) != 2 {
+	if isSourceURL(workspaceMember) {
+		sourceURL, fragment, found := strings.Cut(workspaceMember, "#")
+		if !found {
 			return "", "", "", fmt.Errorf("unable to parse workspace member [%s], missing `#`", workspaceMember)
 		}
 
-		otherHalf := strings.SplitN(half[1], "@", 2)
-		if len(otherHalf) != 2 {
-			return "", "", "", fmt.Errorf("unable to parse workspace member [%s], missing `@`", workspaceMember)
+		// git sources keep the revision in the fragment of the URL, the package id is after the last `#`
+		if i := strings.LastIndex(fragment, "#"); i >= 0 {
+			sourceURL = fmt.Sprintf("%s#%s", sourceURL, fragment[:i])
+			fragment = fragment[i+1:]
+		}
+
+		name, version, found := strings.Cut(fragment, "@")
+		if !found {
+			if fragment == "" || fragment[0] < '0' || fragment[0] > '9' {
+				return "", "", "", fmt.Errorf("unable to parse workspace member [%s], missing `@`", workspaceMember)
+			}
+
+			u, err := url.Parse(sourceURL)
+			if err != nil {
+				return "", "", "", fmt.Errorf("unable to parse workspace member [%s]\n%w", workspaceMember, err)
+			}
+			name, version = path.Base(u.Path), fragment
+		}
+
+		return strings.TrimSpace(name), strings.TrimSpace(version), strings.TrimSpace(sourceURL), nil
+	} else {
+		// This is OK because the workspace member format is `package-name package-version (url)` and
+		//   none of name, version or URL may contain a space & be valid
+		parts := strings.SplitN(workspaceMember, " ", 3)
+		if len(parts) != 3 {
+			return "", "", "", fmt.Errorf("unable to parse workspace member [%s], unexpected format", workspaceMember)
+		}
+		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), strings.TrimSuffix(strings.TrimPrefix(parts[2], "("), ")"), nil
+	}
+}
+
+// isSourceURL checks if s starts with a source URL, like `path+file://` or `git+https://`, rather than a package name
+func isSourceURL(s string) bool {
+	kind, rest, found := strings.Cut(s, "+")
+	return found && !strings.Contains(kind, " ") && strings.Contains(rest, "://")
+}
+
+// SourceKind returns the kind of a package source URL, like `path`, `git`, `registry` or `sparse`
+func SourceKind(sourceURL string) string {
+	kind, _, found := strings.Cut(sourceURL, "+")
+	if !found {
+		return ""
+	}
+	return kind
+}
+
+// IsLocalMember checks if a workspace member URL points to a local directory that can be installed with `--path`
+func IsLocalMember(sourceURL string) bool {
+	return strings.HasPrefix(sourceURL, "path+file://")
+}
+
+// ProjectTargets loads the members from the project workspace
+func (c CargoRunner) ProjectTargets(srcDir string) ([]string, error) {
+	targets, err := c.ProjectTargetDetails(srcDir)
+	if err != nil {
+		return []string{}, err
+	}
+
+	var names []string
+	for _, target := range targets {
+		names = append(names, target.Name)
+	}
+
+	return names, nil
+}
+
+// ProjectTargetDetails loads the binary targets from the project workspace along with the member that owns them
+func (c CargoRunner) ProjectTargetDetails(srcDir string) ([]Target, error) {
+	m, err := c.fetchCargoMetadata(srcDir)
+	if err != nil {
+		return []Target{}, fmt.Errorf("unable to load cargo metadata\n%w", err)
+	}
+
+	filterMap := c.makeFilterMap()
+
+	members := map[string]string{}
+	for _, workspace := range m.WorkspaceMembers {
+		pkgName, _, _, err := ParseWorkspaceMember(workspace)
+		if err != nil {
+			return []Target{}, fmt.Errorf("unable to parse: %w", err)
+		}
+
+		if len(filterMap) > 0 && filterMap[pkgName] || len(filterMap) == 0 {
+			members[workspace] = pkgName
+		}
+	}
+
+	var targets []Target
+	for _, pkg := range m.Packages {
+		member, ok := members[pkg.ID]
+		if !ok {
+			continue
+		}
+
+		memberPath, err := packagePath(pkg.ID)
+		if err != nil {
+			return []Target{}, err
+		}
+
+		pkgTargets := pkg.Targets
+		pkgFeatures := pkg.Features
+		features := c.Features
+
+		if memberFeatures, ok := c.MemberFeatures[member]; ok {
+			pkgTargets, pkgFeatures, err = c.memberTargets(pkg.ID, memberFeatures)
+			if err != nil {
+				return []Target{}, err
+			}
+			features = strings.Join(memberFeatures, ",")
+		}
+
+		// targets gated by `required-features` are only built if their features are enabled
+		selection, err := c.featureSelection(features)
+		if err != nil {
+			return []Target{}, err
+		}
+
+		for _, target := range pkgTargets {
+			if (!c.IncludeDepBins && !strings.HasPrefix(target.SrcPath, srcDir)) || c.isExcludedBinary(target.Name) {
+				continue
+			}
+
+			if slices.Contains(target.Kind, "bin") && len(c.Bins) > 0 && !slices.Contains(c.Bins, target.Name) {
+				continue
+			}
+
+			if !selection.enabled(target.RequiredFeatures, pkgFeatures) {
+				continue
+			}
+
+			for _, kind := range target.Kind {
+				if kind == "bin" {
+					targets = append(targets, Target{Name: target.Name, Member: member, MemberPath: memberPath, Edition: target.Edition, SrcPath: target.SrcPath})
+				} else if kind == "example" && c.IncludeExamples && isExecutableExample(target) {
+					targets = append(targets, Target{Name: target.Name, Member: member, MemberPath: memberPath, Example: true, Edition: target.Edition, SrcPath: target.SrcPath})
+				}
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+// ToolchainRequirements aggregates the editions and `rust-version` of the workspace members
+func (c CargoRunner) ToolchainRequirements(srcDir string) (ToolchainRequirements, error) {
+	m, err := c.fetchCargoMetadata(srcDir)
+	if err != nil {
+		return ToolchainRequirements{}, fmt.Errorf("unable to load cargo metadata\n%w", err)
+	}
+
+	filterMap := c.makeFilterMap()
+
+	members := map[string]bool{}
+	for _, workspace := range m.WorkspaceMembers {
+		pkgName, _, _, err := ParseWorkspaceMember(workspace)
+		if err != nil {
+			return ToolchainRequirements{}, fmt.Errorf("unable to parse: %w", err)
+		}
+
+		if len(filterMap) > 0 && filterMap[pkgName] || len(filterMap) == 0 {
+			members[workspace] = true
+		}
+	}
+
+	requirements := ToolchainRequirements{}
+	editions := map[string]bool{}
+	for _, pkg := range m.Packages {
+		if !members[pkg.ID] {
+			continue
+		}
+
+		// Cargo defaults to the 2015 edition, `cargo metadata` reports that but be safe with older versions
+		edition := pkg.Edition
+		if edition == "" {
+			edition = "2015"
+		}
+		if !editions[edition] {
+			editions[edition] = true
+			requirements.Editions = append(requirements.Editions, edition)
+		}
+
+		if pkg.RustVersion != "" && compareVersions(pkg.RustVersion, requirements.MSRV) > 0 {
+			requirements.MSRV = pkg.RustVersion
+		}
+	}
+	sort.Strings(requirements.Editions)
+
+	return requirements, nil
+}
+
+// compareVersions compares `major.minor.patch` versions where missing parts are zero, an empty version is lower
+// than any other version
+func compareVersions(a string, b string) int {
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	if a == "" || b == "" {
+		return strings.Compare(a, b)
+	}
+
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			y, _ = strconv.Atoi(partsB[i])
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// isExecutableExample checks if an example builds a binary, examples may also build libraries
+func isExecutableExample(target metadataTarget) bool {
+	return len(target.CrateTypes) == 0 || slices.Contains(target.CrateTypes, "bin")
+}
+
+// hasExampleSelection checks if the install arguments already select which examples to install
+func hasExampleSelection(args []string) bool {
+	for _, arg := range args {
+		if arg == "--example" || arg == "--examples" || strings.HasPrefix(arg, "--example=") {
+			return true
+		}
+	}
+	return false
+}
+
+// memberBinaries returns the names of the binary targets of the package in memberPath
+func (c CargoRunner) memberBinaries(srcDir string, memberPath string) ([]string, error) {
+	memberDir := memberPath
+	if !filepath.IsAbs(memberDir) {
+		memberDir = filepath.Join(srcDir, memberDir)
+	}
+
+	m, err := c.fetchCargoMetadata(srcDir)
+	if err != nil {
+		return []string{}, fmt.Errorf("unable to load cargo metadata\n%w", err)
+	}
+
+	// selecting a binary whose `required-features` are not enabled makes `cargo install` fail
+	selection, err := c.featureSelection(c.Features)
+	if err != nil {
+		return []string{}, err
+	}
+
+	var bins []string
+	for _, pkg := range m.Packages {
+		pkgDir, err := packagePath(pkg.ID)
+		if err != nil {
+			return []string{}, err
+		}
+
+		if filepath.Clean(pkgDir) != filepath.Clean(memberDir) {
+			continue
+		}
+
+		for _, target := range pkg.Targets {
+			if !selection.enabled(target.RequiredFeatures, pkg.Features) {
+				continue
+			}
+
+			for _, kind := range target.Kind {
+				if kind == "bin" {
+					bins = append(bins, target.Name)
+				}
+			}
+		}
+	}
+
+	return bins, nil
+}
+
+// packagePath returns the local path of a package from its package id
+func packagePath(id string) (string, error) {
+	var pathUrl string
+	if strings.HasPrefix(id, "path+file://") {
+		pathUrl = strings.SplitN(id, "#", 2)[0]
+	} else {
+		_, _, u, err := ParseWorkspaceMember(id)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse: %w", err)
+		}
+		pathUrl = u
+	}
+
+	u, err := url.Parse(pathUrl)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse path URL %s: %w", id, err)
+	}
+
+	return u.Path, nil
+}
+
+func (c CargoRunner) filterExcludedBinaries(bins []string) []string {
+	var included []string
+	for _, bin := range bins {
+		if !c.isExcludedBinary(bin) {
+			included = append(included, bin)
+		}
+	}
+	return included
+}
+
+func (c CargoRunner) isExcludedBinary(name string) bool {
+	for _, pattern := range c.BinExcludePatterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBinSelection checks if the install arguments already select which binaries to install
+func hasBinSelection(args []string) bool {
+	for _, arg := range args {
+		if arg == "--bin" || arg == "--bins" || strings.HasPrefix(arg, "--bin=") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseBins parses a comma separated list of binary target names
+func ParseBins(raw string) []string {
+	var bins []string
+	for _, bin := range strings.Split(raw, ",") {
+		if bin = strings.TrimSpace(bin); bin != "" {
+			bins = append(bins, bin)
+		}
+	}
+
+	return bins
+}
+
+// ParseBinExcludePatterns parses a comma separated list of glob patterns for binary targets that should be excluded
+func ParseBinExcludePatterns(raw string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(raw, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q\n%w", pattern, err)
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// PruneRegistryCache removes the least recently modified `.crate` files from `$CARGO_HOME/registry/cache` until the
+// files take up at most maxBytes
+func (c CargoRunner) PruneRegistryCache(maxBytes int64) error {
+	type crateFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var (
+		crates []crateFile
+		total  int64
+	)
+
+	cacheDir := filepath.Join(c.CargoHome, "registry", "cache")
+	err := filepath.WalkDir(cacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ".crate" {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		crates = append(crates, crateFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to walk %s\n%w", cacheDir, err)
+	}
+
+	if total <= maxBytes {
+		return nil
+	}
+
+	sort.SliceStable(crates, func(i, j int) bool {
+		return crates[i].modTime.Before(crates[j].modTime)
+	})
+
+	removed := 0
+	for _, crate := range crates {
+		if total <= maxBytes {
+			break
+		}
+
+		if err := os.Remove(crate.path); err != nil {
+			return fmt.Errorf("unable to remove %s\n%w", crate.path, err)
+		}
+		total -= crate.size
+		removed++
+	}
+
+	c.Logger.Bodyf("Pruned %d crates from the registry cache, %d bytes remain", removed, total)
+
+	return nil
+}
+
+// RegistrySourcesGenerationsFile is the file in `$CARGO_HOME/registry/src` which records the last build that used
+// each extracted crate source
+const RegistrySourcesGenerationsFile = ".buildpack-generations.toml"
+
+type registrySourcesGenerations struct {
+	Generation int            `toml:"generation"`
+	Sources    map[string]int `toml:"sources"`
+}
+
+type lockFile struct {
+	Packages []struct {
+		Name    string `toml:"name"`
+		Version string `toml:"version"`
+	} `toml:"package"`
+}
+
+// PruneRegistrySources removes extracted crate sources from `$CARGO_HOME/registry/src` that have not been used by the
+// last SrcKeep builds. A source is used by a build when its crate is listed in the project's Cargo.lock. The `.crate`
+// files in `registry/cache` are not touched, so pruned sources are extracted again without downloading them.
+func (c CargoRunner) PruneRegistrySources(srcDir string) error {
+	if c.SrcKeep <= 0 {
+		return nil
+	}
+
+	if c.DryRun {
+		c.Logger.Bodyf("Dry run, skipping: pruning extracted crate sources unused for %d builds", c.SrcKeep)
+		return nil
+	}
+
+	var lock lockFile
+	if _, err := toml.DecodeFile(filepath.Join(srcDir, "Cargo.lock"), &lock); err != nil {
+		return fmt.Errorf("unable to decode Cargo.lock\n%w", err)
+	}
+
+	used := map[string]bool{}
+	for _, pkg := range lock.Packages {
+		used[fmt.Sprintf("%s-%s", pkg.Name, pkg.Version)] = true
+	}
+
+	registrySrc := filepath.Join(c.CargoHome, "registry", "src")
+	registries, err := os.ReadDir(registrySrc)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to read %s\n%w", registrySrc, err)
+	}
+
+	generationsFile := filepath.Join(registrySrc, RegistrySourcesGenerationsFile)
+	generations := registrySourcesGenerations{}
+	if _, err := toml.DecodeFile(generationsFile, &generations); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to decode %s\n%w", generationsFile, err)
+	}
+
+	generation := generations.Generation + 1
+	seen := map[string]int{}
+	removed := 0
+
+	for _, registry := range registries {
+		if !registry.IsDir() {
+			continue
+		}
+
+		sources, err := os.ReadDir(filepath.Join(registrySrc, registry.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to read %s\n%w", registry.Name(), err)
+		}
+
+		for _, source := range sources {
+			if !source.IsDir() {
+				continue
+			}
+
+			key := path.Join(registry.Name(), source.Name())
+			last, ok := generations.Sources[key]
+			if used[source.Name()] || !ok {
+				// sources which appeared during this build were used by it, even if Cargo.lock does not list them
+				last = generation
+			}
+
+			if generation-last >= c.SrcKeep {
+				if err := os.RemoveAll(filepath.Join(registrySrc, key)); err != nil {
+					return fmt.Errorf("unable to remove %s\n%w", key, err)
+				}
+				removed++
+				continue
+			}
+
+			seen[key] = last
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := toml.NewEncoder(buf).Encode(registrySourcesGenerations{Generation: generation, Sources: seen}); err != nil {
+		return fmt.Errorf("unable to encode %s\n%w", generationsFile, err)
+	}
+
+	if err := os.WriteFile(generationsFile, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("unable to write %s\n%w", generationsFile, err)
+	}
+
+	if removed > 0 {
+		c.Logger.Bodyf("Pruned %d extracted crate sources unused for %d builds", removed, c.SrcKeep)
+	}
+
+	return nil
+}
+
+// ParseCleanHomeExcept parses a comma separated list of entries of CARGO_HOME to keep when cleaning it
+func ParseCleanHomeExcept(raw string) []string {
+	keep := []string{}
+	for _, entry := range strings.Split(raw, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			keep = append(keep, entry)
+		}
+	}
+	return keep
+}
+
+// CleanCargoHomeCache clears out unnecessary files from under $CARGO_HOME
+func (c CargoRunner) CleanCargoHomeCache() error {
+	files, err := os.ReadDir(c.CargoHome)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to read directory\n%w", err)
+	}
+
+	keep := c.CleanHomeExcept
+	if keep == nil {
+		keep = DefaultCleanHomeExcept
+	}
+
+	var remove []string
+	for _, file := range files {
+		if slices.Contains(keep, file.Name()) {
+			continue
+		}
+		remove = append(remove, filepath.Join(c.CargoHome, file.Name()))
+	}
+
+	registryDir := filepath.Join(c.CargoHome, "registry")
+	files, err = os.ReadDir(registryDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to read directory\n%w", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() && file.Name() == "index" ||
+			file.IsDir() && file.Name() == "cache" {
+			continue
+		}
+
+		// extracted sources are pruned by PruneRegistrySources instead
+		if file.IsDir() && file.Name() == "src" && c.SrcKeep > 0 {
+			continue
+		}
+		remove = append(remove, filepath.Join(registryDir, file.Name()))
+	}
+
+	gitDir := filepath.Join(c.CargoHome, "git")
+	files, err = os.ReadDir(gitDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to read directory\n%w", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() && file.Name() == "db" {
+			continue
+		}
+		remove = append(remove, filepath.Join(gitDir, file.Name()))
+	}
+
+	for _, path := range remove {
+		if c.DryRun {
+			c.Logger.Bodyf("Dry run, skipping: removing %s", path)
+			continue
+		}
+
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("unable to remove files\n%w", err)
+		}
+	}
+
+	return nil
+}
+
+// CargoVersion returns the version of cargo installed
+func (c CargoRunner) CargoVersion() (string, error) {
+	return c.toolVersion("cargo", "version")
+}
+
+// RustVersion returns the version of rustc installed
+func (c CargoRunner) RustVersion() (string, error) {
+	return c.toolVersion("rustc", "--version")
+}
+
+// Toolchain returns the versions of cargo and rustc installed, they are only looked up once by a runner
+func (c CargoRunner) Toolchain() (string, string, error) {
+	if c.toolchainCache != nil {
+		c.toolchainCache.mutex.Lock()
+		defer c.toolchainCache.mutex.Unlock()
+
+		if c.toolchainCache.found {
+			return c.toolchainCache.cargoVersion, c.toolchainCache.rustVersion, nil
+		}
+	}
+
+	cargoVersion, err := c.CargoVersion()
+	if err != nil {
+		return "", "", err
+	}
+
+	rustVersion, err := c.RustVersion()
+	if err != nil {
+		return "", "", err
+	}
+
+	if c.toolchainCache != nil {
+		c.toolchainCache.found = true
+		c.toolchainCache.cargoVersion = cargoVersion
+		c.toolchainCache.rustVersion = rustVersion
+	}
+
+	return cargoVersion, rustVersion, nil
+}
+
+// toolVersion runs `<command> <arg>` and parses the version from a line like `cargo 1.2.3 (4369396ce 2021-04-27)`
+func (c CargoRunner) toolVersion(command string, arg string) (string, error) {
+	buf := &bytes.Buffer{}
+
+	if err := c.Executor.Execute(effect.Execution{
+		Command: command,
+		Args:    []string{arg},
+		Stdout:  buf,
+		Stderr:  buf,
+	}); errors.Is(err, exec.ErrNotFound) {
+		return "", fmt.Errorf("%s not found on PATH; ensure the Rust buildpack ran before cargo\n%w", command, err)
+	} else if err != nil {
+		return "", fmt.Errorf("error executing '%s %s':\n Combined Output: %s: \n%w", command, arg, buf.String(), err)
+	}
+
+	// stdout and stderr are combined, so skip lines like the ones rustup prints when it installs a toolchain
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == command {
+			return fields[1], nil
+		}
+	}
+
+	return "", fmt.Errorf("unable to parse the version of %s from %q", command, strings.TrimSpace(buf.String()))
+}
+
+// BuildArgs will build the list of arguments to pass `cargo install`
+func (c CargoRunner) BuildArgs(destLayer libcnb.Layer, defaultMemberPath string) ([]string, error) {
+	envArgs, fileArgs, err := c.installArgSources()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateInstallArgs(envArgs, "BP_CARGO_INSTALL_ARGS"); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateInstallArgs(fileArgs, "BP_CARGO_INSTALL_ARGS_FILE"); err != nil {
+		return nil, err
+	}
+
+	args := []string{"install"}
+	args = append(args, filterInstallArgs(append(envArgs, fileArgs...), c.Color == ColorAuto)...)
+	args = AddColor(args, c.Color)
+	args = append(args, fmt.Sprintf("--root=%s", destLayer.Path))
+	args = AddDefaultPath(args, defaultMemberPath)
+	args = AddTarget(args, c.Target)
+	args = AddFeatures(args, c.Features)
+	args = AddBins(args, c.Bins)
+	args = AddJobs(args, c.Jobs)
+	args = AddVerbosity(args, c.Verbosity)
+
+	args = c.addLockArgs(args)
+
+	if c.KeepDebugSymbols {
+		args = AddNoStripConfig(args)
+	}
+
+	args, err = AddDefaultTargetForTinyOrStatic(args, c.Stack, c.StaticType)
+	if err != nil {
+		return []string{}, fmt.Errorf("unable to add default target\n%w", err)
+	}
+
+	return args, nil
+}
+
+// withPath returns env, or the process environment when env is nil, with binDir appended to PATH unless PATH
+// already refers to layerDir. The environment is returned unchanged when PATH is not set.
+func withPath(env []string, layerDir string, binDir string) []string {
+	if env == nil {
+		env = os.Environ()
+	}
+
+	for i, entry := range env {
+		path, found := strings.CutPrefix(entry, "PATH=")
+		if !found {
+			continue
 		}
 
-		return strings.TrimSpace(otherHalf[0]), strings.TrimSpace(otherHalf[1]), strings.TrimSpace(half[0]), nil
-	} else {
-		// This is OK because the workspace member format is `package-name package-version (url)` and
-		//   none of name, version or URL may contain a space & be valid
-		parts := strings.SplitN(workspaceMember, " ", 3)
-		if len(parts) != 3 {
-			return "", "", "", fmt.Errorf("unable to parse workspace member [%s], unexpected format", workspaceMember)
+		if path == "" || strings.Contains(path, layerDir) {
+			return env
 		}
-		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), strings.TrimSuffix(strings.TrimPrefix(parts[2], "("), ")"), nil
+
+		result := slices.Clone(env)
+		result[i] = fmt.Sprintf("PATH=%s:%s", path, binDir)
+		return result
 	}
+
+	return env
 }
 
-// ProjectTargets loads the members from the project workspace
-func (c CargoRunner) ProjectTargets(srcDir string) ([]string, error) {
-	m, err := c.fetchCargoMetadata(srcDir)
-	if err != nil {
-		return []string{}, fmt.Errorf("unable to load cargo metadata\n%w", err)
+// installEnv returns the environment for `cargo install` or nil, if the inherited environment should be used as is
+func (c CargoRunner) installEnv() []string {
+	if len(c.CargoEnv) == 0 && strings.TrimSpace(c.RustFlags) == "" && c.SccacheDir == "" {
+		return nil
 	}
 
-	filterMap := c.makeFilterMap()
+	env := append(os.Environ(), c.CargoEnv...)
 
-	workspaces := []string{}
-	for _, workspace := range m.WorkspaceMembers {
-		// This is OK because the workspace member format is `package-name package-version (url)` and
-		//   none of name, version or URL may contain a space & be valid
-		parts := strings.SplitN(workspace, " ", 3)
-		if len(filterMap) > 0 && filterMap[strings.TrimSpace(parts[0])] || len(filterMap) == 0 {
-			workspaces = append(workspaces, workspace)
-		}
+	if c.SccacheDir != "" {
+		env = append(env, "RUSTC_WRAPPER=sccache", fmt.Sprintf("SCCACHE_DIR=%s", c.SccacheDir))
 	}
 
-	var names []string
-	for _, pkg := range m.Packages {
-		for _, workspace := range workspaces {
-			if pkg.ID == workspace {
-				for _, target := range pkg.Targets {
-					for _, kind := range target.Kind {
-						if kind == "bin" && strings.HasPrefix(target.SrcPath, srcDir) {
-							names = append(names, target.Name)
-						}
-					}
-				}
-			}
+	if strings.TrimSpace(c.RustFlags) == "" {
+		return env
+	}
+
+	inherited := ""
+	var filtered []string
+	for _, entry := range env {
+		if value, found := strings.CutPrefix(entry, "RUSTFLAGS="); found {
+			inherited = value
+			continue
 		}
+		filtered = append(filtered, entry)
 	}
 
-	return names, nil
+	return append(filtered, fmt.Sprintf("RUSTFLAGS=%s", MergeRustFlags(inherited, c.RustFlags)))
 }
 
-// CleanCargoHomeCache clears out unnecessary files from under $CARGO_HOME
-func (c CargoRunner) CleanCargoHomeCache() error {
-	files, err := os.ReadDir(c.CargoHome)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return fmt.Errorf("unable to read directory\n%w", err)
-	}
+// MergeRustFlags appends the flags in extra to the inherited flags, skipping flags which are already present. A flag
+// followed by a value, like `-C target-cpu=native`, is treated as one flag.
+func MergeRustFlags(inherited string, extra string) string {
+	var merged []string
+	seen := map[string]bool{}
 
-	for _, file := range files {
-		if file.IsDir() && file.Name() == "bin" ||
-			file.IsDir() && file.Name() == "registry" ||
-			file.IsDir() && file.Name() == "git" {
+	for _, flag := range append(splitRustFlags(inherited), splitRustFlags(extra)...) {
+		if seen[flag] {
 			continue
 		}
-		err := os.RemoveAll(filepath.Join(c.CargoHome, file.Name()))
-		if err != nil {
-			return fmt.Errorf("unable to remove files\n%w", err)
+		seen[flag] = true
+		merged = append(merged, flag)
+	}
+
+	return strings.Join(merged, " ")
+}
+
+func splitRustFlags(raw string) []string {
+	fields := strings.Fields(raw)
+
+	var flags []string
+	for i := 0; i < len(fields); i++ {
+		flag := fields[i]
+		if strings.HasPrefix(flag, "-") && i+1 < len(fields) && !strings.HasPrefix(fields[i+1], "-") {
+			flag = fmt.Sprintf("%s %s", flag, fields[i+1])
+			i++
 		}
+		flags = append(flags, flag)
 	}
 
-	registryDir := filepath.Join(c.CargoHome, "registry")
-	files, err = os.ReadDir(registryDir)
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("unable to read directory\n%w", err)
+	return flags
+}
+
+// ParseCargoEnv parses a newline or, if there are no newlines, comma separated list of `KEY=VALUE` pairs
+func ParseCargoEnv(raw string) ([]string, error) {
+	separator := ","
+	if strings.Contains(raw, "\n") {
+		separator = "\n"
 	}
 
-	for _, file := range files {
-		if file.IsDir() && file.Name() == "index" ||
-			file.IsDir() && file.Name() == "cache" {
+	var env []string
+	for _, entry := range strings.Split(raw, separator) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
 			continue
 		}
-		err := os.RemoveAll(filepath.Join(registryDir, file.Name()))
-		if err != nil {
-			return fmt.Errorf("unable to remove files\n%w", err)
+
+		key, value, found := strings.Cut(entry, "=")
+		if !found || !isEnvName(key) {
+			return nil, fmt.Errorf("unable to parse %q, expected KEY=VALUE", entry)
 		}
+
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
 	}
 
-	gitDir := filepath.Join(c.CargoHome, "git")
-	files, err = os.ReadDir(gitDir)
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("unable to read directory\n%w", err)
+	return env, nil
+}
+
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
 	}
 
-	for _, file := range files {
-		if file.IsDir() && file.Name() == "db" {
+	for i, r := range name {
+		if r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (i > 0 && r >= '0' && r <= '9') {
 			continue
 		}
-		err := os.RemoveAll(filepath.Join(gitDir, file.Name()))
-		if err != nil {
-			return fmt.Errorf("unable to remove files\n%w", err)
-		}
+		return false
 	}
 
-	return nil
+	return true
 }
 
-// CargoVersion returns the version of cargo installed
-func (c CargoRunner) CargoVersion() (string, error) {
-	buf := &bytes.Buffer{}
-
-	if err := c.Executor.Execute(effect.Execution{
-		Command: "cargo",
-		Args:    []string{"version"},
-		Stdout:  buf,
-		Stderr:  buf,
-	}); err != nil {
-		return "", fmt.Errorf("error executing 'cargo version':\n Combined Output: %s: \n%w", buf.String(), err)
+// installArgs returns the allowed arguments of CargoInstallArgs followed by those of CargoInstallArgsFile. With
+// ColorAuto, a `--color` chosen by the user is kept.
+func (c CargoRunner) installArgs() ([]string, error) {
+	args, fileArgs, err := c.installArgSources()
+	if err != nil {
+		return nil, err
 	}
 
-	s := strings.SplitN(strings.TrimSpace(buf.String()), " ", 3)
-	return s[1], nil
+	return filterInstallArgs(append(args, fileArgs...), c.Color == ColorAuto), nil
 }
 
-// RustVersion returns the version of rustc installed
-func (c CargoRunner) RustVersion() (string, error) {
-	buf := &bytes.Buffer{}
+// installArgSources returns the unfiltered arguments from CargoInstallArgs and from CargoInstallArgsFile
+func (c CargoRunner) installArgSources() ([]string, []string, error) {
+	args, err := shellwords.Parse(c.CargoInstallArgs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("filter failed: parse args failed: %w", err)
+	}
 
-	if err := c.Executor.Execute(effect.Execution{
-		Command: "rustc",
-		Args:    []string{"--version"},
-		Stdout:  buf,
-		Stderr:  buf,
-	}); err != nil {
-		return "", fmt.Errorf("error executing 'rustc --version':\n Combined Output: %s: \n%w", buf.String(), err)
+	fileArgs, err := ReadInstallArgsFile(c.CargoInstallArgsFile)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	s := strings.Split(strings.TrimSpace(buf.String()), " ")
-	return s[1], nil
+	return args, fileArgs, nil
 }
 
-// BuildArgs will build the list of arguments to pass `cargo install`
-func (c CargoRunner) BuildArgs(destLayer libcnb.Layer, defaultMemberPath string) ([]string, error) {
-	envArgs, err := FilterInstallArgs(c.CargoInstallArgs)
-	if err != nil {
-		return nil, fmt.Errorf("filter failed: %w", err)
+// ReadInstallArgsFile reads the arguments from the file at path, which are split like a shell would with newlines
+// treated as spaces. Nothing is read when path is empty.
+func ReadInstallArgsFile(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
 	}
 
-	args := []string{"install"}
-	args = append(args, envArgs...)
-	args = append(args, "--color=never", fmt.Sprintf("--root=%s", destLayer.Path))
-	args = AddDefaultPath(args, defaultMemberPath)
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("unable to find install arguments file %s, set by BP_CARGO_INSTALL_ARGS_FILE", path)
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read install arguments file %s\n%w", path, err)
+	}
 
-	args, err = AddDefaultTargetForTinyOrStatic(args, c.Stack, c.StaticType)
+	args, err := shellwords.Parse(string(contents))
 	if err != nil {
-		return []string{}, fmt.Errorf("unable to add default target\n%w", err)
+		return nil, fmt.Errorf("unable to parse install arguments file %s\n%w", path, err)
 	}
 
 	return args, nil
@@ -425,24 +1998,74 @@ func FilterInstallArgs(args string) ([]string, error) {
 		return nil, fmt.Errorf("parse args failed: %w", err)
 	}
 
+	return filterInstallArgs(argwords, false), nil
+}
+
+// filterInstallArgs removes `--root` and, unless keepColor is set, `--color` from args
+func filterInstallArgs(args []string, keepColor bool) []string {
 	var filteredArgs []string
 	skipNext := false
-	for _, arg := range argwords {
+	for _, arg := range args {
 		if skipNext {
 			skipNext = false
 			continue
 		}
-		if arg == "--root" || arg == "--color" {
+		if arg == "--root" || arg == "--color" && !keepColor {
 			skipNext = true
 			continue
 		}
-		if strings.HasPrefix(arg, "--root=") || strings.HasPrefix(arg, "--color=") {
+		if strings.HasPrefix(arg, "--root=") || strings.HasPrefix(arg, "--color=") && !keepColor {
 			continue
 		}
 		filteredArgs = append(filteredArgs, arg)
 	}
 
-	return filteredArgs, nil
+	return filteredArgs
+}
+
+// AddColor adds `--color` for color, `never` if it is empty, unless the arguments already set it
+func AddColor(args []string, color string) []string {
+	if color == "" {
+		color = ColorNever
+	}
+
+	for _, arg := range args {
+		if arg == "--color" || strings.HasPrefix(arg, "--color=") {
+			return args
+		}
+	}
+
+	return append(args, fmt.Sprintf("--color=%s", color))
+}
+
+// ParseColor validates a BP_CARGO_COLOR value, an empty value is `never`
+func ParseColor(raw string) (string, error) {
+	switch color := strings.TrimSpace(raw); color {
+	case "":
+		return ColorNever, nil
+	case ColorNever, ColorAlways, ColorAuto:
+		return color, nil
+	default:
+		return "", fmt.Errorf("unable to use %q, must be %q, %q or %q", raw, ColorNever, ColorAlways, ColorAuto)
+	}
+}
+
+// ValidateInstallArgs rejects arguments which conflict with how the buildpack runs `cargo install`
+//
+//	--target-dir moves build output out of the cached target directory
+//	--no-track makes `cargo install` fail when reusing binaries from a cached layer
+func ValidateInstallArgs(args []string, source string) error {
+	for _, arg := range args {
+		if arg == "--target-dir" || strings.HasPrefix(arg, "--target-dir=") {
+			return fmt.Errorf("unable to use --target-dir in %s, the buildpack caches build output by linking the target directory to a cache layer, remove --target-dir to continue", source)
+		}
+
+		if arg == "--no-track" {
+			return fmt.Errorf("unable to use --no-track in %s, the buildpack reinstalls into a cached layer and needs Cargo to track installed binaries, remove --no-track to continue", source)
+		}
+	}
+
+	return nil
 }
 
 // AddDefaultPath will add --path=. if --path is not set
@@ -455,6 +2078,224 @@ func AddDefaultPath(args []string, defaultMemberPath string) []string {
 	return append(args, fmt.Sprintf("--path=%s", defaultMemberPath))
 }
 
+// RelativeMemberPath returns the path of a member below srcDir relative to srcDir, like `./api`, so `cargo install`
+// run in srcDir gets the same `--path` for members and single packages. Relative paths and paths outside of srcDir
+// are returned as they are.
+func RelativeMemberPath(srcDir string, memberPath string) string {
+	if !filepath.IsAbs(memberPath) {
+		return memberPath
+	}
+
+	rel, err := filepath.Rel(srcDir, memberPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return memberPath
+	}
+
+	if rel == "." {
+		return rel
+	}
+
+	return "./" + rel
+}
+
+// AddTarget adds `--target` for the given target triple, unless it is empty or the user already picked a target
+func AddTarget(args []string, target string) []string {
+	if target == "" {
+		return args
+	}
+
+	for _, arg := range args {
+		if arg == "--target" || strings.HasPrefix(arg, "--target=") {
+			return args
+		}
+	}
+
+	return append(args, fmt.Sprintf("--target=%s", target))
+}
+
+// AddJobs adds `--jobs` to limit how many jobs cargo runs in parallel, unless it is zero or the user already set
+// the jobs
+func AddJobs(args []string, jobs int) []string {
+	if jobs <= 0 {
+		return args
+	}
+
+	for _, arg := range args {
+		if arg == "--jobs" || strings.HasPrefix(arg, "--jobs=") || strings.HasPrefix(arg, "-j") {
+			return args
+		}
+	}
+
+	return append(args, fmt.Sprintf("--jobs=%d", jobs))
+}
+
+// ParseVerbosity parses the verbosity of cargo from `1` for `-v` or `2` for `-vv`, an empty value keeps cargo's default
+func ParseVerbosity(raw string) (int, error) {
+	switch strings.TrimSpace(raw) {
+	case "":
+		return 0, nil
+	case "1":
+		return 1, nil
+	case "2":
+		return 2, nil
+	default:
+		return 0, fmt.Errorf("must be 1 for -v or 2 for -vv")
+	}
+}
+
+// AddVerbosity adds `-v` or `-vv` to make cargo verbose, unless verbosity is zero or the user already set the
+// verbosity
+func AddVerbosity(args []string, verbosity int) []string {
+	if verbosity <= 0 {
+		return args
+	}
+
+	for _, arg := range args {
+		if arg == "--verbose" || arg == "--quiet" || arg == "-q" || strings.HasPrefix(arg, "-v") && !strings.HasPrefix(arg, "--") {
+			return args
+		}
+	}
+
+	return append(args, "-"+strings.Repeat("v", min(verbosity, 2)))
+}
+
+// AddFeatures adds `--features` for a comma or space separated list of features, unless it is empty or the user
+// already selected features
+func AddFeatures(args []string, features string) []string {
+	features = strings.Join(strings.FieldsFunc(features, func(r rune) bool { return r == ',' || r == ' ' }), ",")
+	if features == "" {
+		return args
+	}
+
+	for _, arg := range args {
+		if arg == "--features" || arg == "-F" || arg == "--all-features" || strings.HasPrefix(arg, "--features=") || strings.HasPrefix(arg, "-F=") {
+			return args
+		}
+	}
+
+	return append(args, fmt.Sprintf("--features=%s", features))
+}
+
+// AddBins adds `--bin` for each of bins, unless the user already selected binaries
+func AddBins(args []string, bins []string) []string {
+	if len(bins) == 0 || hasBinSelection(args) {
+		return args
+	}
+
+	for _, bin := range bins {
+		args = append(args, fmt.Sprintf("--bin=%s", bin))
+	}
+
+	return args
+}
+
+// addLockArgs adds `--frozen`, `--locked` and `--offline` as configured, `--frozen` replaces the other two
+func (c CargoRunner) addLockArgs(args []string) []string {
+	if c.Frozen {
+		return AddFrozen(args)
+	}
+
+	if c.Locked {
+		args = AddLocked(args)
+	}
+
+	if c.Offline {
+		args = AddOffline(args)
+	}
+
+	return args
+}
+
+// AddFrozen adds `--frozen` and removes `--locked` and `--offline`, which it implies
+func AddFrozen(args []string) []string {
+	args = slices.DeleteFunc(slices.Clone(args), func(arg string) bool { return arg == "--locked" || arg == "--offline" })
+	if slices.Contains(args, "--frozen") {
+		return args
+	}
+
+	return append(args, "--frozen")
+}
+
+// AddOffline adds `--offline`, unless the arguments already include `--offline` or `--frozen`, which implies it
+func AddOffline(args []string) []string {
+	for _, arg := range args {
+		if arg == "--offline" || arg == "--frozen" {
+			return args
+		}
+	}
+
+	return append(args, "--offline")
+}
+
+// AddLocked adds `--locked`, unless the arguments already include `--locked` or `--frozen`, which implies it
+func AddLocked(args []string) []string {
+	for _, arg := range args {
+		if arg == "--locked" || arg == "--frozen" {
+			return args
+		}
+	}
+
+	return append(args, "--locked")
+}
+
+// IsLockFileOutdated checks cargo's error output for the failure caused by `--locked` or `--frozen` when Cargo.lock needs updating
+func IsLockFileOutdated(output string) bool {
+	return strings.Contains(output, "needs to be updated but --locked was passed") ||
+		strings.Contains(output, "needs to be updated but --frozen was passed")
+}
+
+// vulnerabilitiesFound matches the summary cargo-audit prints when it found vulnerable crates, like
+// `error: 1 vulnerability found!`
+var vulnerabilitiesFound = regexp.MustCompile(`\d+ vulnerabilit(y|ies) found`)
+
+// IsVulnerabilityFound checks cargo-audit's output for vulnerable crates, cargo-audit exits with the same status when
+// it fails otherwise, like when the advisory database cannot be fetched
+func IsVulnerabilityFound(output string) bool {
+	return vulnerabilitiesFound.MatchString(output)
+}
+
+// lockContentionMessages are printed by cargo when another cargo process holds a lock on CARGO_HOME or the target directory
+var lockContentionMessages = []string{
+	"Blocking waiting for file lock",
+	"failed to acquire package cache lock",
+	"could not acquire package cache lock",
+	"failed to lock file",
+}
+
+// IsLockContention checks cargo's error output for a failure caused by waiting for a file lock. Output of a failed
+// compilation is never lock contention, even if cargo had to wait for a lock before compiling.
+func IsLockContention(output string) bool {
+	if strings.Contains(output, "could not compile") {
+		return false
+	}
+
+	for _, message := range lockContentionMessages {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddNoStripConfig will add `--config profile.release.strip=false` unless the user already configured stripping
+func AddNoStripConfig(args []string) []string {
+	for i, arg := range args {
+		value := ""
+		if arg == "--config" && i+1 < len(args) {
+			value = args[i+1]
+		} else if strings.HasPrefix(arg, "--config=") {
+			value = strings.TrimPrefix(arg, "--config=")
+		}
+
+		if strings.Contains(value, "profile.release.strip") {
+			return args
+		}
+	}
+
+	return append(args, "--config", "profile.release.strip=false")
+}
+
 // AddDefaultTargetForTinyOrStatic will add the appropriate options if not already set
 func AddDefaultTargetForTinyOrStatic(args []string, stack string, staticType string) ([]string, error) {
 	if !libpak.IsTinyStack(stack) && !libpak.IsStaticStack(stack) {
@@ -496,13 +2337,69 @@ func AddDefaultTargetForTinyOrStatic(args []string, stack string, staticType str
 	return append(args, target), nil
 }
 
-func (c CargoRunner) fetchCargoMetadata(srcDir string) (metadata, error) {
+// metadataArgs are the arguments used to read the project's metadata with `cargo metadata`
+var metadataArgs = []string{"metadata", "--format-version=1", "--no-deps"}
+
+// allowedMetadataFlags are the flags of `cargo metadata` which can be added to metadataArgs, mapped to whether they
+// take a value. Everything else, like `--format-version`, could change the output that is parsed and is dropped
+var allowedMetadataFlags = map[string]bool{
+	"--all-features":        false,
+	"--features":            true,
+	"-F":                    true,
+	"--filter-platform":     true,
+	"--frozen":              false,
+	"--locked":              false,
+	"--no-default-features": false,
+	"--offline":             false,
+}
+
+// FilterMetadataArgs splits args into the ones that can be passed to `cargo metadata` and the ones that are dropped
+func FilterMetadataArgs(args []string) ([]string, []string) {
+	var allowed, dropped []string
+
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := strings.Cut(args[i], "=")
+
+		takesValue, ok := allowedMetadataFlags[name]
+		if !ok || (hasValue && !takesValue) {
+			dropped = append(dropped, args[i])
+			continue
+		}
+
+		allowed = append(allowed, args[i])
+		if takesValue && !hasValue && i+1 < len(args) {
+			i++
+			allowed = append(allowed, args[i])
+		}
+	}
+
+	return allowed, dropped
+}
+
+// cargoMetadataArgs returns the arguments for `cargo metadata`, with the configured args after the fixed ones
+func (c CargoRunner) cargoMetadataArgs() []string {
+	return append(slices.Clone(metadataArgs), c.MetadataArgs...)
+}
+
+func (c CargoRunner) fetchCargoMetadata(srcDir string, extraArgs ...string) (metadata, error) {
+	args := append(c.cargoMetadataArgs(), extraArgs...)
+	key := metadataCacheKey{dir: srcDir, args: strings.Join(extraArgs, " ")}
+
+	if c.metadataCache != nil {
+		c.metadataCache.mutex.Lock()
+		defer c.metadataCache.mutex.Unlock()
+
+		if m, ok := c.metadataCache.entries[key]; ok {
+			return m, nil
+		}
+	}
+
 	stdout := bytes.Buffer{}
 	stderr := bytes.Buffer{}
 
 	if err := c.Executor.Execute(effect.Execution{
 		Command: "cargo",
-		Args:    []string{"metadata", "--format-version=1", "--no-deps"},
+		Args:    args,
 		Dir:     srcDir,
 		Stdout:  &stdout,
 		Stderr:  &stderr,
@@ -515,9 +2412,29 @@ func (c CargoRunner) fetchCargoMetadata(srcDir string) (metadata, error) {
 		return metadata{}, fmt.Errorf("unable to parse Cargo metadata: %w", err)
 	}
 
+	if c.metadataCache != nil {
+		c.metadataCache.entries[key] = m
+	}
+
 	return m, nil
 }
 
+// InvalidateMetadata drops the cached `cargo metadata` of srcDir, so it's read again the next time it's needed
+func (c CargoRunner) InvalidateMetadata(srcDir string) {
+	if c.metadataCache == nil {
+		return
+	}
+
+	c.metadataCache.mutex.Lock()
+	defer c.metadataCache.mutex.Unlock()
+
+	for key := range c.metadataCache.entries {
+		if key.dir == srcDir {
+			delete(c.metadataCache.entries, key)
+		}
+	}
+}
+
 func (c CargoRunner) makeFilterMap() map[string]bool {
 	filter := c.CargoWorkspaceMembers != ""
 	filterMap := make(map[string]bool)
@@ -18,13 +18,17 @@ package runner_test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/paketo-community/cargo/runner"
@@ -112,6 +116,81 @@ func testRunners(t *testing.T, context spec.G, it spec.S) {
 		Expect(version).To(Equal("1.2.3"))
 	})
 
+	context("toolchain", func() {
+		it.Before(func() {
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				return ex.Command == "cargo" && reflect.DeepEqual(ex.Args, []string{"version"})
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte("cargo 1.80.0 (376290515 2024-07-16)\n"))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				return ex.Command == "rustc" && reflect.DeepEqual(ex.Args, []string{"--version"})
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte("info: syncing channel updates for '1.80.1-x86_64-unknown-linux-gnu'\nrustc 1.80.1 (3f5fd8dd4 2024-08-06)\n"))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+		})
+
+		it("fetches both versions once", func() {
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.Logger{}))
+
+			for i := 0; i < 2; i++ {
+				cargoVersion, rustVersion, err := runner.Toolchain()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cargoVersion).To(Equal("1.80.0"))
+				Expect(rustVersion).To(Equal("1.80.1"))
+			}
+
+			Expect(executor.Calls).To(HaveLen(2))
+		})
+
+		it("uses the same parsing as the individual versions", func() {
+			runner := runner.CargoRunner{Executor: executor}
+
+			cargoVersion, rustVersion, err := runner.Toolchain()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.CargoVersion()).To(Equal(cargoVersion))
+			Expect(runner.RustVersion()).To(Equal(rustVersion))
+		})
+	})
+
+	it("fails on version output it cannot parse", func() {
+		executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+			_, err := ex.Stdout.Write([]byte("error: no override and no default toolchain set\n"))
+			Expect(err).ToNot(HaveOccurred())
+			return nil
+		})
+
+		runner := runner.CargoRunner{Executor: executor}
+
+		_, _, err := runner.Toolchain()
+		Expect(err).To(MatchError(`unable to parse the version of cargo from "error: no override and no default toolchain set"`))
+	})
+
+	it("explains that cargo or rustc are missing", func() {
+		for _, command := range []string{"cargo", "rustc"} {
+			executor := &mocks.Executor{}
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool { return ex.Command == command })).
+				Return(&exec.Error{Name: command, Err: exec.ErrNotFound})
+			executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte(fmt.Sprintf("%s 1.80.0 (abc 2024-07-21)\n", ex.Command)))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+
+			_, _, err := runner.CargoRunner{Executor: executor}.Toolchain()
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("%s not found on PATH; ensure the Rust buildpack ran before cargo", command))))
+			Expect(errors.Is(err, exec.ErrNotFound)).To(BeTrue())
+		}
+	})
+
 	context("builds install arguments", func() {
 		it("builds a default set of arguments", func() {
 			runner := runner.CargoRunner{}
@@ -126,613 +205,2656 @@ func testRunners(t *testing.T, context spec.G, it spec.S) {
 			}))
 		})
 
-		context("with custom args", func() {
-			it("builds with custom args", func() {
-				runner := runner.CargoRunner{
-					CargoInstallArgs: "--path=./todo --foo=bar --foo baz",
-				}
+		context("with a target", func() {
+			it("adds the target", func() {
+				runner := runner.CargoRunner{Target: "x86_64-unknown-linux-musl"}
 
 				args, err := runner.BuildArgs(destLayer, ".")
 				Expect(err).ToNot(HaveOccurred())
-				Expect(args).To(Equal([]string{
-					"install",
-					"--path=./todo",
-					"--foo=bar",
-					"--foo",
-					"baz",
-					"--color=never",
-					"--root=/some/location/2",
-				}))
+				Expect(args).To(Equal([]string{"install", "--color=never", "--root=/some/location/2", "--path=.", "--target=x86_64-unknown-linux-musl"}))
 			})
-		})
-	})
-
-	context("cargo install tools", func() {
-		it("installs with no args", func() {
-			runner := runner.CargoRunner{
-				CargoHome: cargoHome,
-				Executor:  executor,
-			}
 
-			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
-				return reflect.DeepEqual(ex.Args, []string{"install", "foo"})
-			})).Return(nil)
+			it("prefers a target from the install args", func() {
+				runner := runner.CargoRunner{CargoInstallArgs: "--target aarch64-unknown-linux-gnu", Target: "x86_64-unknown-linux-musl"}
 
-			err := runner.InstallTool("foo", []string{})
-			Expect(err).ToNot(HaveOccurred())
+				args, err := runner.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{"install", "--target", "aarch64-unknown-linux-gnu", "--color=never", "--root=/some/location/2", "--path=."}))
+			})
 
-			Expect(executor.Calls).To(HaveLen(1))
+			it("does not add the default target on tiny stacks", func() {
+				runner := runner.CargoRunner{Stack: libpak.BionicTinyStackID, Target: "aarch64-unknown-linux-musl"}
 
-			e := executor.Calls[0].Arguments[0].(effect.Execution)
-			Expect(e.Command).To(Equal("cargo"))
-			Expect(e.Args).To(Equal([]string{"install", "foo"}))
+				args, err := runner.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{"install", "--color=never", "--root=/some/location/2", "--path=.", "--target=aarch64-unknown-linux-musl"}))
+			})
 		})
 
-		it("installs with additional args", func() {
-			runner := runner.CargoRunner{
-				CargoHome: cargoHome,
-				Executor:  executor,
-			}
+		context("with a member path", func() {
+			it("makes members below the working directory relative", func() {
+				Expect(runner.RelativeMemberPath("/workspace", "/workspace/api")).To(Equal("./api"))
+				Expect(runner.RelativeMemberPath("/workspace", "/workspace/crates/worker/")).To(Equal("./crates/worker"))
+				Expect(runner.RelativeMemberPath("/workspace", "/workspace")).To(Equal("."))
+			})
 
-			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
-				return reflect.DeepEqual(ex.Args, []string{"install", "foo", "--bar", "--baz"})
-			})).Return(nil)
+			it("keeps relative paths and paths outside of the working directory", func() {
+				Expect(runner.RelativeMemberPath("/workspace", "./api")).To(Equal("./api"))
+				Expect(runner.RelativeMemberPath("/workspace", ".")).To(Equal("."))
+				Expect(runner.RelativeMemberPath("/workspace", "/other/api")).To(Equal("/other/api"))
+				Expect(runner.RelativeMemberPath("/workspace", "/workspace-other/api")).To(Equal("/workspace-other/api"))
+			})
 
-			err := runner.InstallTool("foo", []string{"--bar", "--baz"})
-			Expect(err).ToNot(HaveOccurred())
+			it("installs a member with a relative path", func() {
+				executor.On("Execute", mock.Anything).Return(nil)
 
-			Expect(executor.Calls).To(HaveLen(1))
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
 
-			e := executor.Calls[0].Arguments[0].(effect.Execution)
-			Expect(e.Command).To(Equal("cargo"))
-			Expect(e.Args).To(Equal([]string{"install", "foo", "--bar", "--baz"}))
-		})
-	})
+				Expect(runner.InstallMember("/workspace/api", "/workspace", destLayer)).To(Succeed())
+				Expect(runner.Install("/workspace", destLayer)).To(Succeed())
 
-	context("BP_CARGO_INSTALL_ARGS filters --color and --root", func() {
-		it("filters --root", func() {
-			Expect(runner.FilterInstallArgs("--root=somewhere")).To(BeEmpty())
-			Expect(runner.FilterInstallArgs("--root somewhere")).To(BeEmpty())
-			Expect(runner.FilterInstallArgs("--root=somewhere --root somewhere --bar=baz")).To(Equal([]string{"--bar=baz"}))
-			Expect(runner.FilterInstallArgs("--foo bar --root somewhere --baz --test true")).To(Equal([]string{"--foo", "bar", "--baz", "--test", "true"}))
-		})
-		it("filters --color", func() {
-			Expect(runner.FilterInstallArgs("--color=never")).To(BeEmpty())
-			Expect(runner.FilterInstallArgs("--color always")).To(BeEmpty())
-			Expect(runner.FilterInstallArgs("--color=always --color never --bar=baz")).To(Equal([]string{"--bar=baz"}))
-			Expect(runner.FilterInstallArgs("--foo bar --color always --baz --test true")).To(Equal([]string{"--foo", "bar", "--baz", "--test", "true"}))
-		})
-		it("filters both --color and --root", func() {
-			Expect(runner.FilterInstallArgs("--color=never --root=blah")).To(BeEmpty())
-			Expect(runner.FilterInstallArgs("--color always --root blah")).To(BeEmpty())
-			Expect(runner.FilterInstallArgs("--color=always --root=blah --root blah --color never --bar=baz")).To(Equal([]string{"--bar=baz"}))
-			Expect(runner.FilterInstallArgs("--foo bar --root=blah --color always --baz --test true")).To(Equal([]string{"--foo", "bar", "--baz", "--test", "true"}))
+				Expect(executor.Calls).To(HaveLen(2))
+				Expect(executor.Calls[0].Arguments[0].(effect.Execution).Args).To(ContainElement("--path=./api"))
+				Expect(executor.Calls[1].Arguments[0].(effect.Execution).Args).To(ContainElement("--path=."))
+			})
 		})
-	})
 
-	context("set default --path argument", func() {
-		it("is specified by the user", func() {
-			Expect(runner.AddDefaultPath([]string{"install", "--path"}, ".")).To(Equal([]string{"install", "--path"}))
-			Expect(runner.AddDefaultPath([]string{"install", "--path=test"}, ".")).To(Equal([]string{"install", "--path=test"}))
-			Expect(runner.AddDefaultPath([]string{"install", "--path", "test"}, ".")).To(Equal([]string{"install", "--path", "test"}))
-		})
+		context("with jobs", func() {
+			it("adds the jobs once", func() {
+				runner := runner.CargoRunner{Jobs: 2}
 
-		it("should be the default", func() {
-			Expect(runner.AddDefaultPath([]string{"install"}, ".")).To(Equal([]string{"install", "--path=."}))
-			Expect(runner.AddDefaultPath([]string{"install", "--foo=bar"}, ".")).To(Equal([]string{"install", "--foo=bar", "--path=."}))
-			Expect(runner.AddDefaultPath([]string{"install", "--foo", "bar"}, ".")).To(Equal([]string{"install", "--foo", "bar", "--path=."}))
-		})
-	})
+				args, err := runner.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{"install", "--color=never", "--root=/some/location/2", "--path=.", "--jobs=2"}))
+			})
 
-	context("set default --target argument", func() {
-		context("amd64 architecture", func() {
-			it.Before(func() {
-				t.Setenv("BP_ARCH", "amd64") // sets so that tests run consistently
+			it("prefers the jobs from the install args", func() {
+				for _, installArgs := range []string{"--jobs 4", "--jobs=4", "-j4", "-j 4"} {
+					runner := runner.CargoRunner{CargoInstallArgs: installArgs, Jobs: 2}
+
+					args, err := runner.BuildArgs(destLayer, ".")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(args).ToNot(ContainElement("--jobs=2"))
+				}
 			})
+		})
 
-			it("is specified by the user", func() {
-				args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--target"}, "foo", runner.StaticTypeMUSLC)
-				Expect(err).To(Succeed())
-				Expect(args).To(Equal([]string{"install", "--target"}))
+		context("with verbosity", func() {
+			it("maps the verbosity to the flag", func() {
+				for raw, flag := range map[string]string{"1": "-v", "2": "-vv"} {
+					verbosity, err := runner.ParseVerbosity(raw)
+					Expect(err).ToNot(HaveOccurred())
 
-				args, err = runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--target=test"}, "foo", runner.StaticTypeMUSLC)
-				Expect(err).To(Succeed())
-				Expect(args).To(Equal([]string{"install", "--target=test"}))
+					runner := runner.CargoRunner{Verbosity: verbosity}
 
-				args, err = runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--target", "test"}, "foo", runner.StaticTypeMUSLC)
-				Expect(err).To(Succeed())
-				Expect(args).To(Equal([]string{"install", "--target", "test"}))
+					args, err := runner.BuildArgs(destLayer, ".")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(args).To(Equal([]string{"install", "--color=never", "--root=/some/location/2", "--path=.", flag}))
+				}
 			})
 
-			it("is not the tiny stack so no args are added", func() {
-				args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, "foo", runner.StaticTypeMUSLC)
-				Expect(err).To(Succeed())
-				Expect(args).To(Equal([]string{"install"}))
-
-				args, err = runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--foo=bar"}, "foo", runner.StaticTypeMUSLC)
-				Expect(err).To(Succeed())
-				Expect(args).To(Equal([]string{"install", "--foo=bar"}))
+			it("adds no flag by default", func() {
+				verbosity, err := runner.ParseVerbosity("")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(verbosity).To(Equal(0))
 
-				args, err = runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--foo", "bar"}, "foo", runner.StaticTypeMUSLC)
-				Expect(err).To(Succeed())
-				Expect(args).To(Equal([]string{"install", "--foo", "bar"}))
+				args, err := runner.CargoRunner{}.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).ToNot(ContainElements("-v", "-vv"))
 			})
 
-			it("is the tiny stack so default args are added", func() {
-				args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, runner.StaticTypeMUSLC)
-				Expect(err).To(Succeed())
-				Expect(args).To(Equal([]string{"install", "--target=x86_64-unknown-linux-musl"}))
+			it("rejects other values", func() {
+				for _, raw := range []string{"0", "3", "-v", "true"} {
+					_, err := runner.ParseVerbosity(raw)
+					Expect(err).To(MatchError("must be 1 for -v or 2 for -vv"))
+				}
+			})
 
-				args, err = runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--foo=bar"}, libpak.BionicTinyStackID, runner.StaticTypeMUSLC)
-				Expect(err).To(Succeed())
-				Expect(args).To(Equal([]string{"install", "--foo=bar", "--target=x86_64-unknown-linux-musl"}))
+			it("prefers the verbosity from the install args", func() {
+				for _, installArgs := range []string{"-v", "-vv", "--verbose", "--quiet", "-q"} {
+					runner := runner.CargoRunner{CargoInstallArgs: installArgs, Verbosity: 2}
 
-				args, err = runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--foo", "bar"}, libpak.BionicTinyStackID, runner.StaticTypeMUSLC)
-				Expect(err).To(Succeed())
-				Expect(args).To(Equal([]string{"install", "--foo", "bar", "--target=x86_64-unknown-linux-musl"}))
+					args, err := runner.BuildArgs(destLayer, ".")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(args).To(Equal([]string{"install", installArgs, "--color=never", "--root=/some/location/2", "--path=."}))
+				}
 			})
+		})
 
-			context("unset RUSTFLAGS", func() {
-				it.Before(func() {
-					t.Setenv("RUSTFLAGS", "") // using os.Getenv so "" is same as unset
-				})
+		context("with incompatible args", func() {
+			it("fails on --target-dir", func() {
+				for _, args := range []string{"--locked --target-dir=/tmp/target", "--target-dir /tmp/target"} {
+					runner := runner.CargoRunner{CargoInstallArgs: args}
 
-				it("is the tiny stack so default args are added with static type GNU LIBC", func() {
-					args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, runner.StaticTypeGNULIBC)
-					Expect(err).To(Succeed())
-					Expect(args).To(Equal([]string{"install", "--target=x86_64-unknown-linux-gnu"}))
-					Expect(os.Getenv("RUSTFLAGS")).To(Equal("-C target-feature=+crt-static"))
-				})
+					_, err := runner.BuildArgs(destLayer, ".")
+					Expect(err).To(MatchError(ContainSubstring("unable to use --target-dir in BP_CARGO_INSTALL_ARGS")))
+				}
+			})
 
-				it("is the tiny stack so default args are added with static type GNU LIBC including existing args", func() {
-					args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--foo=bar"}, libpak.BionicTinyStackID, runner.StaticTypeGNULIBC)
-					Expect(err).To(Succeed())
-					Expect(args).To(Equal([]string{"install", "--foo=bar", "--target=x86_64-unknown-linux-gnu"}))
-					Expect(os.Getenv("RUSTFLAGS")).To(Equal("-C target-feature=+crt-static"))
-				})
+			it("fails on --no-track", func() {
+				runner := runner.CargoRunner{CargoInstallArgs: "--locked --no-track"}
+
+				_, err := runner.BuildArgs(destLayer, ".")
+				Expect(err).To(MatchError(ContainSubstring("unable to use --no-track in BP_CARGO_INSTALL_ARGS")))
 			})
 
-			context("existing RUSTFLAGS", func() {
-				it.Before(func() {
-					t.Setenv("RUSTFLAGS", "--something foo --more")
-				})
+			it("names the install arguments file", func() {
+				argsFile := filepath.Join(t.TempDir(), "install-args")
+				Expect(os.WriteFile(argsFile, []byte("--locked\n--no-track\n"), 0644)).To(Succeed())
 
-				it("is the tiny stack so default args are added with static type GNU LIBC", func() {
-					args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, runner.StaticTypeGNULIBC)
-					Expect(err).To(Succeed())
-					Expect(args).To(Equal([]string{"install", "--target=x86_64-unknown-linux-gnu"}))
-					Expect(os.Getenv("RUSTFLAGS")).To(Equal("--something foo --more -C target-feature=+crt-static"))
-				})
+				runner := runner.CargoRunner{CargoInstallArgs: "--locked", CargoInstallArgsFile: argsFile}
 
-				it("is the tiny stack so default args are added with static type GNU LIBC including existing args", func() {
-					args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--foo=bar"}, libpak.BionicTinyStackID, runner.StaticTypeGNULIBC)
-					Expect(err).To(Succeed())
-					Expect(args).To(Equal([]string{"install", "--foo=bar", "--target=x86_64-unknown-linux-gnu"}))
-					Expect(os.Getenv("RUSTFLAGS")).To(Equal("--something foo --more -C target-feature=+crt-static"))
-				})
+				_, err := runner.BuildArgs(destLayer, ".")
+				Expect(err).To(MatchError(ContainSubstring("unable to use --no-track in BP_CARGO_INSTALL_ARGS_FILE")))
 			})
 
-			context("existing RUSTFLAGS with static args", func() {
-				it.Before(func() {
-					t.Setenv("RUSTFLAGS", "-C target-feature=+crt-static")
-				})
+			it("still filters --root and --color", func() {
+				runner := runner.CargoRunner{CargoInstallArgs: "--root=/elsewhere --color always --locked"}
 
-				it("is the tiny stack so default args are added with static type GNU LIBC", func() {
-					args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, runner.StaticTypeGNULIBC)
-					Expect(err).To(Succeed())
-					Expect(args).To(Equal([]string{"install"}))
-					Expect(os.Getenv("RUSTFLAGS")).To(Equal("-C target-feature=+crt-static"))
-				})
+				args, err := runner.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{"install", "--locked", "--color=never", "--root=/some/location/2", "--path=."}))
 			})
 		})
 
-		context("arm64 architecture", func() {
+		context("with an install args file", func() {
+			var argsFile string
+
 			it.Before(func() {
-				t.Setenv("BP_ARCH", "arm64")
-				t.Setenv("RUSTFLAGS", "") // make sure this is clean when we run the tests
+				argsFile = filepath.Join(t.TempDir(), "install-args")
+				Expect(os.WriteFile(argsFile, []byte("--bin api\n--bin 'worker cli'\n--root /elsewhere\n"), 0644)).To(Succeed())
 			})
 
-			it("is the tiny stack so default args are added with static type GNU LIBC and ARM64", func() {
-				args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, runner.StaticTypeGNULIBC)
-				Expect(err).To(Succeed())
-				Expect(args).To(Equal([]string{"install", "--target=aarch64-unknown-linux-gnu"}))
-				Expect(os.Getenv("RUSTFLAGS")).To(Equal("-C target-feature=+crt-static"))
+			it("adds the args from the file after the env args", func() {
+				runner := runner.CargoRunner{CargoInstallArgs: "--locked --bin web", CargoInstallArgsFile: argsFile}
+
+				args, err := runner.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{
+					"install", "--locked", "--bin", "web", "--bin", "api", "--bin", "worker cli",
+					"--color=never", "--root=/some/location/2", "--path=.",
+				}))
 			})
 
-			it("is the tiny stack so default args are added with static type MUSL and ARM64", func() {
-				args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, runner.StaticTypeMUSLC)
-				Expect(err).To(Succeed())
-				Expect(args).To(Equal([]string{"install", "--target=aarch64-unknown-linux-musl"}))
-				Expect(os.Getenv("RUSTFLAGS")).To(Equal(""))
+			it("fails if the file does not exist", func() {
+				runner := runner.CargoRunner{CargoInstallArgsFile: filepath.Join(filepath.Dir(argsFile), "missing")}
+
+				_, err := runner.BuildArgs(destLayer, ".")
+				Expect(err).To(MatchError(ContainSubstring("unable to find install arguments file")))
+				Expect(err).To(MatchError(ContainSubstring("BP_CARGO_INSTALL_ARGS_FILE")))
 			})
 
-			it("is the tiny stack so default args are added with static type unset and ARM64", func() {
-				args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, "")
-				Expect(err).To(Succeed())
-				Expect(args).To(Equal([]string{"install", "--target=aarch64-unknown-linux-musl"}))
-				Expect(os.Getenv("RUSTFLAGS")).To(Equal(""))
+			it("reads nothing without a file", func() {
+				Expect(runner.ReadInstallArgsFile("")).To(BeEmpty())
+			})
+		})
+
+		context("with custom args", func() {
+			it("builds with custom args", func() {
+				runner := runner.CargoRunner{
+					CargoInstallArgs: "--path=./todo --foo=bar --foo baz",
+				}
+
+				args, err := runner.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{
+					"install",
+					"--path=./todo",
+					"--foo=bar",
+					"--foo",
+					"baz",
+					"--color=never",
+					"--root=/some/location/2",
+				}))
 			})
 		})
 	})
 
-	context("when there is a valid Rust project", func() {
-		it("builds correctly with defaults", func() {
-			logBuf := bytes.Buffer{}
-			logger := bard.NewLogger(&logBuf)
+	context("keep debug symbols", func() {
+		it("disables stripping", func() {
+			runner := runner.CargoRunner{
+				KeepDebugSymbols: true,
+			}
 
-			expectedArgs := []string{
+			args, err := runner.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{
 				"install",
 				"--color=never",
 				"--root=/some/location/2",
 				"--path=.",
-			}
-			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
-				return reflect.DeepEqual(ex.Args, expectedArgs) &&
-					ex.Dir == workingDir
-			})).Return(nil)
+				"--config",
+				"profile.release.strip=false",
+			}))
+		})
 
-			runner := runner.NewCargoRunner(
-				runner.WithCargoHome(cargoHome),
-				runner.WithExecutor(executor),
-				runner.WithLogger(logger))
+		it("does not disable stripping by default", func() {
+			runner := runner.CargoRunner{}
 
-			err := runner.Install(workingDir, destLayer)
+			args, err := runner.BuildArgs(destLayer, ".")
 			Expect(err).ToNot(HaveOccurred())
+			Expect(args).ToNot(ContainElement("profile.release.strip=false"))
 		})
 
-		context("sets custom args", func() {
-			it("builds correctly with custom args", func() {
-				logBuf := bytes.Buffer{}
-				logger := bard.NewLogger(&logBuf)
+		it("does not override a user provided strip config", func() {
+			Expect(runner.AddNoStripConfig([]string{"install", "--config", "profile.release.strip=\"debuginfo\""})).To(Equal(
+				[]string{"install", "--config", "profile.release.strip=\"debuginfo\""}))
+			Expect(runner.AddNoStripConfig([]string{"install", "--config=profile.release.strip=true"})).To(Equal(
+				[]string{"install", "--config=profile.release.strip=true"}))
+		})
 
-				expectedArgs := []string{
-					"install",
-					"--path=./todo",
-					"--foo=baz",
-					"bar",
-					"--color=never",
-					"--root=/some/location/2",
-				}
-				executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
-					return reflect.DeepEqual(ex.Args, expectedArgs) &&
-						ex.Dir == workingDir
-				})).Return(nil)
+		it("keeps unrelated user provided config", func() {
+			Expect(runner.AddNoStripConfig([]string{"install", "--config", "net.git-fetch-with-cli=true"})).To(Equal(
+				[]string{"install", "--config", "net.git-fetch-with-cli=true", "--config", "profile.release.strip=false"}))
+		})
+	})
 
-				runner := runner.NewCargoRunner(
-					runner.WithCargoHome(cargoHome),
-					runner.WithCargoInstallArgs("--path=./todo --foo=baz bar"),
-					runner.WithExecutor(executor),
-					runner.WithLogger(logger))
+	context("features", func() {
+		it("adds --features", func() {
+			runner := runner.CargoRunner{
+				Features: "tls, metrics",
+			}
 
-				err := runner.Install(workingDir, destLayer)
-				Expect(err).ToNot(HaveOccurred())
-			})
+			args, err := runner.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{
+				"install",
+				"--color=never",
+				"--root=/some/location/2",
+				"--path=.",
+				"--features=tls,metrics",
+			}))
 		})
 
-		context("and there is metadata", func() {
-			context("pre-rust 1.77.0", func() {
-				it("parses the member paths from metadata", func() {
-					logBuf := bytes.Buffer{}
-					logger := bard.NewLogger(&logBuf)
+		it("does not override features from the install args", func() {
+			Expect(runner.AddFeatures([]string{"install", "--features", "json"}, "tls")).To(Equal([]string{"install", "--features", "json"}))
+			Expect(runner.AddFeatures([]string{"install", "-F", "json"}, "tls")).To(Equal([]string{"install", "-F", "json"}))
+			Expect(runner.AddFeatures([]string{"install", "--all-features"}, "tls")).To(Equal([]string{"install", "--all-features"}))
+			Expect(runner.AddFeatures([]string{"install"}, " ")).To(Equal([]string{"install"}))
+		})
+	})
 
-					metadata := BuildMetadata("/workspace",
-						[]string{
-							"basics 2.0.0 (path+file:///workspace/basics)",
-							"todo 1.2.0 (path+file:///workspace/todo)",
-							"routes 0.5.0 (path+file:///workspace/routes)",
-							"jokes 1.5.6 (path+file:///workspace/jokes)",
-						})
+	context("bins", func() {
+		it("adds --bin for each binary", func() {
+			runner := runner.CargoRunner{Bins: []string{"api", "worker"}}
 
-					executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
-						Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
-						return true
-					})).Return(func(ex effect.Execution) error {
-						_, err := ex.Stdout.Write([]byte(metadata))
-						Expect(err).ToNot(HaveOccurred())
-						return nil
-					})
+			args, err := runner.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{
+				"install",
+				"--color=never",
+				"--root=/some/location/2",
+				"--path=.",
+				"--bin=api",
+				"--bin=worker",
+			}))
+		})
 
-					runner := runner.NewCargoRunner(
-						runner.WithCargoHome(cargoHome),
-						runner.WithExecutor(executor),
-						runner.WithLogger(logger))
+		it("does not override a binary selection from the install args", func() {
+			Expect(runner.AddBins([]string{"install", "--bins"}, []string{"api"})).To(Equal([]string{"install", "--bins"}))
+			Expect(runner.AddBins([]string{"install", "--bin", "cli"}, []string{"api"})).To(Equal([]string{"install", "--bin", "cli"}))
+			Expect(runner.AddBins([]string{"install"}, nil)).To(Equal([]string{"install"}))
+		})
 
-					urls, err := runner.WorkspaceMembers(workingDir, destLayer)
-					Expect(err).ToNot(HaveOccurred())
+		it("parses a comma separated list of binaries", func() {
+			Expect(runner.ParseBins(" api, worker,,")).To(Equal([]string{"api", "worker"}))
+			Expect(runner.ParseBins("")).To(BeEmpty())
+		})
+	})
 
-					Expect(urls).To(HaveLen(4))
+	context("locked", func() {
+		it("adds --locked", func() {
+			runner := runner.CargoRunner{
+				Locked: true,
+			}
 
-					url, err := url.Parse("path+file:///workspace/basics")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(urls[0]).To(Equal(*url))
+			args, err := runner.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{
+				"install",
+				"--color=never",
+				"--root=/some/location/2",
+				"--path=.",
+				"--locked",
+			}))
+		})
 
-					url, err = url.Parse("path+file:///workspace/todo")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(urls[1]).To(Equal(*url))
+		it("does not add --locked twice", func() {
+			runner := runner.CargoRunner{
+				CargoInstallArgs: "--locked --bins",
+				Locked:           true,
+			}
 
-					url, err = url.Parse("path+file:///workspace/routes")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(urls[2]).To(Equal(*url))
+			args, err := runner.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{
+				"install",
+				"--locked",
+				"--bins",
+				"--color=never",
+				"--root=/some/location/2",
+				"--path=.",
+			}))
+		})
 
-					url, err = url.Parse("path+file:///workspace/jokes")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(urls[3]).To(Equal(*url))
-				})
-			})
+		it("does not add --locked with --frozen", func() {
+			Expect(runner.AddLocked([]string{"install", "--frozen"})).To(Equal([]string{"install", "--frozen"}))
+		})
 
-			context("post-rust 1.77.0", func() {
-				it("parses the member paths from metadata", func() {
-					logBuf := bytes.Buffer{}
-					logger := bard.NewLogger(&logBuf)
+		it("does not add --locked by default", func() {
+			runner := runner.CargoRunner{}
 
-					metadata := BuildMetadata("/workspace",
-						[]string{
-							"path+file:///workspace/basics#basics@2.0.0",
-							"path+file:///workspace/todo#todo@1.2.0",
-							"path+file:///workspace/routes#routes@0.5.0",
-							"path+file:///workspace/jokes#jokes@1.5.6",
-						})
+			args, err := runner.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).ToNot(ContainElement("--locked"))
+		})
+	})
 
-					executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
-						Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
-						return true
-					})).Return(func(ex effect.Execution) error {
-						_, err := ex.Stdout.Write([]byte(metadata))
-						Expect(err).ToNot(HaveOccurred())
-						return nil
-					})
+	context("frozen and offline", func() {
+		it("adds --offline", func() {
+			runner := runner.CargoRunner{
+				Locked:  true,
+				Offline: true,
+			}
 
-					runner := runner.NewCargoRunner(
-						runner.WithCargoHome(cargoHome),
-						runner.WithExecutor(executor),
-						runner.WithLogger(logger))
+			args, err := runner.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{
+				"install",
+				"--color=never",
+				"--root=/some/location/2",
+				"--path=.",
+				"--locked",
+				"--offline",
+			}))
+		})
 
-					urls, err := runner.WorkspaceMembers(workingDir, destLayer)
-					Expect(err).ToNot(HaveOccurred())
+		it("adds --frozen instead of --locked and --offline", func() {
+			runner := runner.CargoRunner{
+				Frozen:  true,
+				Locked:  true,
+				Offline: true,
+			}
 
-					Expect(urls).To(HaveLen(4))
+			args, err := runner.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{
+				"install",
+				"--color=never",
+				"--root=/some/location/2",
+				"--path=.",
+				"--frozen",
+			}))
+		})
 
-					url, err := url.Parse("path+file:///workspace/basics")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(urls[0]).To(Equal(*url))
+		it("adds --frozen even if --locked is disabled", func() {
+			runner := runner.CargoRunner{
+				Frozen: true,
+			}
 
-					url, err = url.Parse("path+file:///workspace/todo")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(urls[1]).To(Equal(*url))
+			args, err := runner.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(ContainElement("--frozen"))
+			Expect(args).ToNot(ContainElement("--locked"))
+		})
 
-					url, err = url.Parse("path+file:///workspace/routes")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(urls[2]).To(Equal(*url))
+		it("replaces --locked and --offline from the install arguments", func() {
+			Expect(runner.AddFrozen([]string{"install", "--locked", "--offline"})).To(Equal([]string{"install", "--frozen"}))
+			Expect(runner.AddFrozen([]string{"install", "--frozen", "--locked"})).To(Equal([]string{"install", "--frozen"}))
+		})
 
-					url, err = url.Parse("path+file:///workspace/jokes")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(urls[3]).To(Equal(*url))
-				})
-			})
+		it("does not add --offline with --frozen", func() {
+			Expect(runner.AddOffline([]string{"fetch", "--frozen"})).To(Equal([]string{"fetch", "--frozen"}))
+			Expect(runner.AddOffline([]string{"fetch", "--offline"})).To(Equal([]string{"fetch", "--offline"}))
+		})
 
-			context("member filter is set", func() {
-				it("parses the member paths from metadata and preserves order with filters", func() {
-					logBuf := bytes.Buffer{}
-					logger := bard.NewLogger(&logBuf)
+		it("recognizes an outdated Cargo.lock with --frozen", func() {
+			Expect(runner.IsLockFileOutdated("error: the lock file /workspace/Cargo.lock needs to be updated but --frozen was passed to prevent this")).To(BeTrue())
+		})
+	})
 
-					metadata := BuildMetadata("/workspace",
-						[]string{
-							"basics 2.0.0 (path+file:///workspace/basics)",
-							"todo 1.2.0 (path+file:///workspace/todo)",
-							"routes 0.5.0 (path+file:///workspace/routes)",
-							"jokes 1.5.6 (path+file:///workspace/jokes)",
-						})
+	context("cargo install tools", func() {
+		it("installs with no args", func() {
+			runner := runner.CargoRunner{
+				CargoHome: cargoHome,
+				Executor:  executor,
+			}
+
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				return reflect.DeepEqual(ex.Args, []string{"install", "foo"})
+			})).Return(nil)
+
+			err := runner.InstallTool("foo", []string{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.Calls).To(HaveLen(1))
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Command).To(Equal("cargo"))
+			Expect(e.Args).To(Equal([]string{"install", "foo"}))
+		})
+
+		it("installs with additional args", func() {
+			runner := runner.CargoRunner{
+				CargoHome: cargoHome,
+				Executor:  executor,
+			}
+
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				return reflect.DeepEqual(ex.Args, []string{"install", "foo", "--bar", "--baz"})
+			})).Return(nil)
+
+			err := runner.InstallTool("foo", []string{"--bar", "--baz"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.Calls).To(HaveLen(1))
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Command).To(Equal("cargo"))
+			Expect(e.Args).To(Equal([]string{"install", "foo", "--bar", "--baz"}))
+		})
+
+		context("the tool is already installed", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(cargoHome, "bin"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(cargoHome, "bin", "cargo-audit"), []byte{}, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(cargoHome, ".crates2.json"), []byte(`{"installs":{
+					"cargo-audit 0.17.0 (registry+https://github.com/rust-lang/crates.io-index)":{"bins":["cargo-audit"],"profile":"release"},
+					"foo 1.0.0 (path+file:///workspace/foo)":{"bins":["foo"],"profile":"release"}
+				}}`), 0644)).To(Succeed())
+
+				executor.On("Execute", mock.Anything).Return(nil)
+			})
+
+			it("reads the installed crates", func() {
+				crates, err := runner.ReadInstalledCrates(cargoHome)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(crates).To(Equal([]runner.InstalledCrate{
+					{Name: "cargo-audit", Version: "0.17.0", Source: "registry+https://github.com/rust-lang/crates.io-index", Bins: []string{"cargo-audit"}},
+					{Name: "foo", Version: "1.0.0", Source: "path+file:///workspace/foo", Bins: []string{"foo"}},
+				}))
+
+				crates, err = runner.ReadInstalledCrates(t.TempDir())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(crates).To(BeEmpty())
+			})
+
+			it("skips the installation of the pinned version", func() {
+				runner := runner.CargoRunner{CargoHome: cargoHome, Executor: executor}
+
+				Expect(runner.InstallTool("cargo-audit", []string{"--version", "0.17.0"})).To(Succeed())
+				Expect(runner.InstallTool("cargo-audit", []string{"--version=0.17.0"})).To(Succeed())
+				Expect(executor.Calls).To(BeEmpty())
+			})
+
+			it("lets cargo update a tool without a version", func() {
+				runner := runner.CargoRunner{CargoHome: cargoHome, Executor: executor}
+
+				Expect(runner.InstallTool("cargo-audit", []string{})).To(Succeed())
+				Expect(executor.Calls).To(HaveLen(1))
+				Expect(executor.Calls[0].Arguments[0].(effect.Execution).Args).To(Equal([]string{"install", "cargo-audit"}))
+			})
+
+			it("reinstalls with --force", func() {
+				runner := runner.CargoRunner{CargoHome: cargoHome, Executor: executor}
+
+				Expect(runner.InstallTool("cargo-audit", []string{"--force"})).To(Succeed())
+				Expect(executor.Calls).To(HaveLen(1))
+				Expect(executor.Calls[0].Arguments[0].(effect.Execution).Args).To(Equal([]string{"install", "cargo-audit", "--force"}))
+			})
+
+			it("installs a different version", func() {
+				runner := runner.CargoRunner{CargoHome: cargoHome, Executor: executor}
+
+				Expect(runner.InstallTool("cargo-audit", []string{"--version", "0.18.0"})).To(Succeed())
+				Expect(runner.InstallTool("cargo-audit", []string{"--version", "~0.17"})).To(Succeed())
+				Expect(executor.Calls).To(HaveLen(2))
+			})
+
+			it("installs a tool whose binaries are missing", func() {
+				runner := runner.CargoRunner{CargoHome: cargoHome, Executor: executor}
+
+				Expect(runner.InstallTool("foo", []string{"--version", "1.0.0"})).To(Succeed())
+				Expect(executor.Calls).To(HaveLen(1))
+			})
+		})
+	})
+
+	context("installed binaries", func() {
+		var layer libcnb.Layer
+
+		it.Before(func() {
+			layer = libcnb.Layer{Name: "cargo", Path: t.TempDir()}
+		})
+
+		it("lists the binaries without a target", func() {
+			Expect(os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(layer.Path, "bin", "worker"), []byte{}, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(layer.Path, "bin", "api"), []byte{}, 0755)).To(Succeed())
+
+			binaries, err := runner.CargoRunner{}.InstalledBinaries(layer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(binaries).To(Equal([]string{
+				filepath.Join(layer.Path, "bin", "api"),
+				filepath.Join(layer.Path, "bin", "worker"),
+			}))
+		})
+
+		it("lists the binaries in a target triple directory", func() {
+			Expect(os.MkdirAll(filepath.Join(layer.Path, "bin", "x86_64-unknown-linux-musl"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(layer.Path, "bin", "x86_64-unknown-linux-musl", "api"), []byte{}, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(layer.Path, "bin", "tool"), []byte{}, 0755)).To(Succeed())
+
+			binaries, err := runner.CargoRunner{Target: "x86_64-unknown-linux-musl"}.InstalledBinaries(layer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(binaries).To(Equal([]string{
+				filepath.Join(layer.Path, "bin", "tool"),
+				filepath.Join(layer.Path, "bin", "x86_64-unknown-linux-musl", "api"),
+			}))
+		})
+
+		it("lists nothing when nothing is installed", func() {
+			binaries, err := runner.CargoRunner{}.InstalledBinaries(layer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(binaries).To(BeEmpty())
+		})
+	})
+
+	context("cargo audit", func() {
+		it("passes when no vulnerabilities are found", func() {
+			runner := runner.CargoRunner{
+				Executor: executor,
+			}
+
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				return reflect.DeepEqual(ex.Args, []string{"audit", "--color=never"}) && ex.Dir == workingDir
+			})).Return(nil)
+
+			Expect(runner.Audit(workingDir)).To(Succeed())
+			Expect(executor.Calls).To(HaveLen(1))
+		})
+
+		it("ignores advisories", func() {
+			runner := runner.CargoRunner{
+				CargoAuditIgnore: "RUSTSEC-2020-0071, RUSTSEC-2021-0139",
+				Executor:         executor,
+			}
+
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				return reflect.DeepEqual(ex.Args, []string{
+					"audit", "--color=never",
+					"--ignore", "RUSTSEC-2020-0071",
+					"--ignore", "RUSTSEC-2021-0139",
+				})
+			})).Return(nil)
+
+			Expect(runner.Audit(workingDir)).To(Succeed())
+			Expect(executor.Calls).To(HaveLen(1))
+		})
+
+		it("fails with the advisories when vulnerabilities are found", func() {
+			runner := runner.CargoRunner{
+				Executor: executor,
+			}
+
+			executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte("Crate:    time\nID:       RUSTSEC-2020-0071\nerror: 1 vulnerability found!\n"))
+				Expect(err).ToNot(HaveOccurred())
+				return fmt.Errorf("exit status 1")
+			})
+
+			err := runner.Audit(workingDir)
+			Expect(err).To(MatchError(ContainSubstring("cargo audit failed, vulnerable crates found")))
+			Expect(err).To(MatchError(ContainSubstring("ID:       RUSTSEC-2020-0071")))
+			Expect(err).To(MatchError(ContainSubstring("exit status 1")))
+		})
+
+		it("fails without reporting vulnerabilities when cargo audit fails otherwise", func() {
+			runner := runner.CargoRunner{
+				Executor: executor,
+			}
+
+			executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+				_, err := ex.Stderr.Write([]byte("error: couldn't fetch advisory database: git operation failed\n"))
+				Expect(err).ToNot(HaveOccurred())
+				return fmt.Errorf("exit status 1")
+			})
+
+			err := runner.Audit(workingDir)
+			Expect(err).To(MatchError(ContainSubstring("cargo audit failed:\nerror: couldn't fetch advisory database")))
+			Expect(err).ToNot(MatchError(ContainSubstring("vulnerable crates found")))
+		})
+
+		it("detects the vulnerabilities summary", func() {
+			Expect(runner.IsVulnerabilityFound("error: 1 vulnerability found!")).To(BeTrue())
+			Expect(runner.IsVulnerabilityFound("error: 3 vulnerabilities found!")).To(BeTrue())
+			Expect(runner.IsVulnerabilityFound("error: couldn't fetch advisory database")).To(BeFalse())
+		})
+	})
+
+	context("cargo test", func() {
+		it("tests the whole workspace", func() {
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			runner := runner.NewCargoRunner(
+				runner.WithExecutor(executor),
+				runner.WithFeatures("tls"),
+				runner.WithLocked(true),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			Expect(runner.Test(workingDir)).To(Succeed())
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Args).To(Equal([]string{"test", "--color=never", "--workspace", "--features=tls", "--locked"}))
+			Expect(e.Dir).To(Equal(workingDir))
+		})
+
+		it("tests only the selected workspace members", func() {
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoWorkspaceMembers("worker, api"),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			Expect(runner.Test(workingDir)).To(Succeed())
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Args).To(Equal([]string{"test", "--color=never", "--package", "api", "--package", "worker"}))
+		})
+
+		it("tests the build target", func() {
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			runner := runner.NewCargoRunner(
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+				runner.WithTarget("x86_64-unknown-linux-musl"))
+
+			Expect(runner.Test(workingDir)).To(Succeed())
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Args).To(Equal([]string{"test", "--color=never", "--workspace", "--target=x86_64-unknown-linux-musl"}))
+		})
+
+		it("fails when the tests fail", func() {
+			executor.On("Execute", mock.Anything).Return(fmt.Errorf("exit status 101"))
+
+			runner := runner.NewCargoRunner(
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			Expect(runner.Test(workingDir)).To(MatchError("cargo test failed\nexit status 101"))
+		})
+	})
+
+	context("pre-build command", func() {
+		it("runs the command in the source directory and streams its output", func() {
+			logBuf := &bytes.Buffer{}
+			executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte("generated 3 files\n"))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoEnv([]string{"PROTOC=/usr/bin/protoc"}),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(logBuf)))
+
+			Expect(runner.PreBuild(workingDir, "cargo sqlx prepare --check && ./codegen.sh")).To(Succeed())
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Command).To(Equal("sh"))
+			Expect(e.Args).To(Equal([]string{"-c", "cargo sqlx prepare --check && ./codegen.sh"}))
+			Expect(e.Dir).To(Equal(workingDir))
+			Expect(e.Env).To(ContainElement("PROTOC=/usr/bin/protoc"))
+			Expect(logBuf.String()).To(ContainSubstring("Running pre-build command: cargo sqlx prepare --check && ./codegen.sh"))
+			Expect(logBuf.String()).To(ContainSubstring("generated 3 files"))
+		})
+
+		it("fails when the command fails", func() {
+			executor.On("Execute", mock.Anything).Return(fmt.Errorf("exit status 1"))
+
+			runner := runner.NewCargoRunner(
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			Expect(runner.PreBuild(workingDir, "./codegen.sh")).To(MatchError("pre-build command \"./codegen.sh\" failed\nexit status 1"))
+		})
+	})
+
+	context("merges RUSTFLAGS", func() {
+		it("uses the extra flags when nothing is inherited", func() {
+			Expect(runner.MergeRustFlags("", "-C target-cpu=native --cfg tokio_unstable")).To(Equal("-C target-cpu=native --cfg tokio_unstable"))
+			Expect(runner.MergeRustFlags("  ", "")).To(BeEmpty())
+		})
+
+		it("appends the extra flags to the inherited flags", func() {
+			Expect(runner.MergeRustFlags("-C target-cpu=native", "-C link-arg=-s -Dwarnings")).To(Equal("-C target-cpu=native -C link-arg=-s -Dwarnings"))
+		})
+
+		it("does not duplicate identical flags", func() {
+			Expect(runner.MergeRustFlags("-C target-cpu=native  -Dwarnings", "-Dwarnings -C target-cpu=native -C opt-level=3")).To(Equal("-C target-cpu=native -Dwarnings -C opt-level=3"))
+		})
+
+		it("keeps flags with different values", func() {
+			Expect(runner.MergeRustFlags("-C opt-level=2", "-C opt-level=3")).To(Equal("-C opt-level=2 -C opt-level=3"))
+		})
+	})
+
+	context("parses BP_CARGO_BIN_EXCLUDE", func() {
+		it("parses a comma separated list of patterns", func() {
+			patterns, err := runner.ParseBinExcludePatterns(" *-bench, test-*,,exact ")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(patterns).To(Equal([]string{"*-bench", "test-*", "exact"}))
+		})
+
+		it("fails on an invalid pattern", func() {
+			_, err := runner.ParseBinExcludePatterns("*-bench,[test")
+			Expect(err).To(MatchError(ContainSubstring(`invalid glob pattern "[test"`)))
+		})
+	})
+
+	context("parses BP_CARGO_ENV", func() {
+		it("parses comma separated pairs", func() {
+			env, err := runner.ParseCargoEnv("OPENSSL_DIR=/usr/local/ssl, PKG_CONFIG_PATH=/usr/lib/pkgconfig,EMPTY=")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(env).To(Equal([]string{"OPENSSL_DIR=/usr/local/ssl", "PKG_CONFIG_PATH=/usr/lib/pkgconfig", "EMPTY="}))
+		})
+
+		it("parses newline separated pairs, allowing commas in values", func() {
+			env, err := runner.ParseCargoEnv("RUSTFLAGS=-C link-args=-Wl,-rpath\nOPENSSL_DIR=/usr/local/ssl\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(env).To(Equal([]string{"RUSTFLAGS=-C link-args=-Wl,-rpath", "OPENSSL_DIR=/usr/local/ssl"}))
+		})
+
+		it("fails on malformed pairs", func() {
+			_, err := runner.ParseCargoEnv("OPENSSL_DIR")
+			Expect(err).To(MatchError(`unable to parse "OPENSSL_DIR", expected KEY=VALUE`))
+
+			_, err = runner.ParseCargoEnv("=value")
+			Expect(err).To(MatchError(`unable to parse "=value", expected KEY=VALUE`))
+
+			_, err = runner.ParseCargoEnv("1FOO=bar")
+			Expect(err).To(MatchError(`unable to parse "1FOO=bar", expected KEY=VALUE`))
+		})
+	})
+
+	context("BP_CARGO_INSTALL_ARGS filters --color and --root", func() {
+		it("filters --root", func() {
+			Expect(runner.FilterInstallArgs("--root=somewhere")).To(BeEmpty())
+			Expect(runner.FilterInstallArgs("--root somewhere")).To(BeEmpty())
+			Expect(runner.FilterInstallArgs("--root=somewhere --root somewhere --bar=baz")).To(Equal([]string{"--bar=baz"}))
+			Expect(runner.FilterInstallArgs("--foo bar --root somewhere --baz --test true")).To(Equal([]string{"--foo", "bar", "--baz", "--test", "true"}))
+		})
+		it("filters --color", func() {
+			Expect(runner.FilterInstallArgs("--color=never")).To(BeEmpty())
+			Expect(runner.FilterInstallArgs("--color always")).To(BeEmpty())
+			Expect(runner.FilterInstallArgs("--color=always --color never --bar=baz")).To(Equal([]string{"--bar=baz"}))
+			Expect(runner.FilterInstallArgs("--foo bar --color always --baz --test true")).To(Equal([]string{"--foo", "bar", "--baz", "--test", "true"}))
+		})
+		it("filters both --color and --root", func() {
+			Expect(runner.FilterInstallArgs("--color=never --root=blah")).To(BeEmpty())
+			Expect(runner.FilterInstallArgs("--color always --root blah")).To(BeEmpty())
+			Expect(runner.FilterInstallArgs("--color=always --root=blah --root blah --color never --bar=baz")).To(Equal([]string{"--bar=baz"}))
+			Expect(runner.FilterInstallArgs("--foo bar --root=blah --color always --baz --test true")).To(Equal([]string{"--foo", "bar", "--baz", "--test", "true"}))
+		})
+	})
+
+	context("BP_CARGO_COLOR", func() {
+		it("passes the color mode to cargo install", func() {
+			for _, color := range []string{"never", "always", "auto"} {
+				runner := runner.CargoRunner{Color: color}
+
+				args, err := runner.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{"install", "--color=" + color, "--root=/some/location/2", "--path=."}))
+			}
+		})
+
+		it("defaults to never", func() {
+			args, err := runner.CargoRunner{}.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(ContainElement("--color=never"))
+		})
+
+		it("keeps --color from the install args with auto", func() {
+			runner := runner.CargoRunner{Color: "auto", CargoInstallArgs: "--locked --color always --root /elsewhere"}
+
+			args, err := runner.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"install", "--locked", "--color", "always", "--root=/some/location/2", "--path=."}))
+		})
+
+		it("filters --color from the install args with never and always", func() {
+			for _, color := range []string{"never", "always"} {
+				runner := runner.CargoRunner{Color: color, CargoInstallArgs: "--locked --color=auto"}
+
+				args, err := runner.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{"install", "--locked", "--color=" + color, "--root=/some/location/2", "--path=."}))
+			}
+		})
+
+		it("parses the color mode", func() {
+			Expect(runner.ParseColor("")).To(Equal("never"))
+			Expect(runner.ParseColor(" always ")).To(Equal("always"))
+			Expect(runner.ParseColor("auto")).To(Equal("auto"))
+
+			_, err := runner.ParseColor("yes")
+			Expect(err).To(MatchError(`unable to use "yes", must be "never", "always" or "auto"`))
+		})
+	})
+
+	context("set default --path argument", func() {
+		it("is specified by the user", func() {
+			Expect(runner.AddDefaultPath([]string{"install", "--path"}, ".")).To(Equal([]string{"install", "--path"}))
+			Expect(runner.AddDefaultPath([]string{"install", "--path=test"}, ".")).To(Equal([]string{"install", "--path=test"}))
+			Expect(runner.AddDefaultPath([]string{"install", "--path", "test"}, ".")).To(Equal([]string{"install", "--path", "test"}))
+		})
+
+		it("should be the default", func() {
+			Expect(runner.AddDefaultPath([]string{"install"}, ".")).To(Equal([]string{"install", "--path=."}))
+			Expect(runner.AddDefaultPath([]string{"install", "--foo=bar"}, ".")).To(Equal([]string{"install", "--foo=bar", "--path=."}))
+			Expect(runner.AddDefaultPath([]string{"install", "--foo", "bar"}, ".")).To(Equal([]string{"install", "--foo", "bar", "--path=."}))
+		})
+	})
+
+	context("set default --target argument", func() {
+		context("amd64 architecture", func() {
+			it.Before(func() {
+				t.Setenv("BP_ARCH", "amd64") // sets so that tests run consistently
+			})
+
+			it("is specified by the user", func() {
+				args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--target"}, "foo", runner.StaticTypeMUSLC)
+				Expect(err).To(Succeed())
+				Expect(args).To(Equal([]string{"install", "--target"}))
+
+				args, err = runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--target=test"}, "foo", runner.StaticTypeMUSLC)
+				Expect(err).To(Succeed())
+				Expect(args).To(Equal([]string{"install", "--target=test"}))
+
+				args, err = runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--target", "test"}, "foo", runner.StaticTypeMUSLC)
+				Expect(err).To(Succeed())
+				Expect(args).To(Equal([]string{"install", "--target", "test"}))
+			})
+
+			it("is not the tiny stack so no args are added", func() {
+				args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, "foo", runner.StaticTypeMUSLC)
+				Expect(err).To(Succeed())
+				Expect(args).To(Equal([]string{"install"}))
+
+				args, err = runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--foo=bar"}, "foo", runner.StaticTypeMUSLC)
+				Expect(err).To(Succeed())
+				Expect(args).To(Equal([]string{"install", "--foo=bar"}))
+
+				args, err = runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--foo", "bar"}, "foo", runner.StaticTypeMUSLC)
+				Expect(err).To(Succeed())
+				Expect(args).To(Equal([]string{"install", "--foo", "bar"}))
+			})
+
+			it("is the tiny stack so default args are added", func() {
+				args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, runner.StaticTypeMUSLC)
+				Expect(err).To(Succeed())
+				Expect(args).To(Equal([]string{"install", "--target=x86_64-unknown-linux-musl"}))
+
+				args, err = runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--foo=bar"}, libpak.BionicTinyStackID, runner.StaticTypeMUSLC)
+				Expect(err).To(Succeed())
+				Expect(args).To(Equal([]string{"install", "--foo=bar", "--target=x86_64-unknown-linux-musl"}))
+
+				args, err = runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--foo", "bar"}, libpak.BionicTinyStackID, runner.StaticTypeMUSLC)
+				Expect(err).To(Succeed())
+				Expect(args).To(Equal([]string{"install", "--foo", "bar", "--target=x86_64-unknown-linux-musl"}))
+			})
+
+			context("unset RUSTFLAGS", func() {
+				it.Before(func() {
+					t.Setenv("RUSTFLAGS", "") // using os.Getenv so "" is same as unset
+				})
+
+				it("is the tiny stack so default args are added with static type GNU LIBC", func() {
+					args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, runner.StaticTypeGNULIBC)
+					Expect(err).To(Succeed())
+					Expect(args).To(Equal([]string{"install", "--target=x86_64-unknown-linux-gnu"}))
+					Expect(os.Getenv("RUSTFLAGS")).To(Equal("-C target-feature=+crt-static"))
+				})
+
+				it("is the tiny stack so default args are added with static type GNU LIBC including existing args", func() {
+					args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--foo=bar"}, libpak.BionicTinyStackID, runner.StaticTypeGNULIBC)
+					Expect(err).To(Succeed())
+					Expect(args).To(Equal([]string{"install", "--foo=bar", "--target=x86_64-unknown-linux-gnu"}))
+					Expect(os.Getenv("RUSTFLAGS")).To(Equal("-C target-feature=+crt-static"))
+				})
+			})
+
+			context("existing RUSTFLAGS", func() {
+				it.Before(func() {
+					t.Setenv("RUSTFLAGS", "--something foo --more")
+				})
+
+				it("is the tiny stack so default args are added with static type GNU LIBC", func() {
+					args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, runner.StaticTypeGNULIBC)
+					Expect(err).To(Succeed())
+					Expect(args).To(Equal([]string{"install", "--target=x86_64-unknown-linux-gnu"}))
+					Expect(os.Getenv("RUSTFLAGS")).To(Equal("--something foo --more -C target-feature=+crt-static"))
+				})
+
+				it("is the tiny stack so default args are added with static type GNU LIBC including existing args", func() {
+					args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install", "--foo=bar"}, libpak.BionicTinyStackID, runner.StaticTypeGNULIBC)
+					Expect(err).To(Succeed())
+					Expect(args).To(Equal([]string{"install", "--foo=bar", "--target=x86_64-unknown-linux-gnu"}))
+					Expect(os.Getenv("RUSTFLAGS")).To(Equal("--something foo --more -C target-feature=+crt-static"))
+				})
+			})
+
+			context("existing RUSTFLAGS with static args", func() {
+				it.Before(func() {
+					t.Setenv("RUSTFLAGS", "-C target-feature=+crt-static")
+				})
+
+				it("is the tiny stack so default args are added with static type GNU LIBC", func() {
+					args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, runner.StaticTypeGNULIBC)
+					Expect(err).To(Succeed())
+					Expect(args).To(Equal([]string{"install"}))
+					Expect(os.Getenv("RUSTFLAGS")).To(Equal("-C target-feature=+crt-static"))
+				})
+			})
+		})
+
+		context("arm64 architecture", func() {
+			it.Before(func() {
+				t.Setenv("BP_ARCH", "arm64")
+				t.Setenv("RUSTFLAGS", "") // make sure this is clean when we run the tests
+			})
+
+			it("is the tiny stack so default args are added with static type GNU LIBC and ARM64", func() {
+				args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, runner.StaticTypeGNULIBC)
+				Expect(err).To(Succeed())
+				Expect(args).To(Equal([]string{"install", "--target=aarch64-unknown-linux-gnu"}))
+				Expect(os.Getenv("RUSTFLAGS")).To(Equal("-C target-feature=+crt-static"))
+			})
+
+			it("is the tiny stack so default args are added with static type MUSL and ARM64", func() {
+				args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, runner.StaticTypeMUSLC)
+				Expect(err).To(Succeed())
+				Expect(args).To(Equal([]string{"install", "--target=aarch64-unknown-linux-musl"}))
+				Expect(os.Getenv("RUSTFLAGS")).To(Equal(""))
+			})
+
+			it("is the tiny stack so default args are added with static type unset and ARM64", func() {
+				args, err := runner.AddDefaultTargetForTinyOrStatic([]string{"install"}, libpak.BionicTinyStackID, "")
+				Expect(err).To(Succeed())
+				Expect(args).To(Equal([]string{"install", "--target=aarch64-unknown-linux-musl"}))
+				Expect(os.Getenv("RUSTFLAGS")).To(Equal(""))
+			})
+		})
+	})
+
+	context("when there is a valid Rust project", func() {
+		it("builds correctly with defaults", func() {
+			logBuf := bytes.Buffer{}
+			logger := bard.NewLogger(&logBuf)
+
+			expectedArgs := []string{
+				"install",
+				"--color=never",
+				"--root=/some/location/2",
+				"--path=.",
+			}
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				return reflect.DeepEqual(ex.Args, expectedArgs) &&
+					ex.Dir == workingDir
+			})).Return(nil)
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(logger))
+
+			err := runner.Install(workingDir, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		context("sets custom args", func() {
+			it("builds correctly with custom args", func() {
+				logBuf := bytes.Buffer{}
+				logger := bard.NewLogger(&logBuf)
+
+				expectedArgs := []string{
+					"install",
+					"--path=./todo",
+					"--foo=baz",
+					"bar",
+					"--color=never",
+					"--root=/some/location/2",
+				}
+				executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+					return reflect.DeepEqual(ex.Args, expectedArgs) &&
+						ex.Dir == workingDir
+				})).Return(nil)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithCargoInstallArgs("--path=./todo --foo=baz bar"),
+					runner.WithExecutor(executor),
+					runner.WithLogger(logger))
+
+				err := runner.Install(workingDir, destLayer)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		context("uses sccache", func() {
+			it("sets the wrapper and logs the statistics", func() {
+				executor.On("Execute", mock.Anything).Return(nil)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+					runner.WithSccacheDir("/cache/sccache"))
+
+				Expect(runner.Install(workingDir, destLayer)).To(Succeed())
+
+				Expect(executor.Calls).To(HaveLen(3))
+
+				e := executor.Calls[0].Arguments[0].(effect.Execution)
+				Expect(e.Command).To(Equal("sccache"))
+				Expect(e.Args).To(Equal([]string{"--version"}))
+
+				e = executor.Calls[1].Arguments[0].(effect.Execution)
+				Expect(e.Command).To(Equal("cargo"))
+				Expect(e.Env).To(ContainElements("RUSTC_WRAPPER=sccache", "SCCACHE_DIR=/cache/sccache"))
+
+				e = executor.Calls[2].Arguments[0].(effect.Execution)
+				Expect(e.Command).To(Equal("sccache"))
+				Expect(e.Args).To(Equal([]string{"--show-stats"}))
+				Expect(e.Env).To(ContainElement("SCCACHE_DIR=/cache/sccache"))
+			})
+
+			it("fails when sccache is not available", func() {
+				executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+					return ex.Command == "sccache"
+				})).Return(errors.New("executable file not found"))
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+					runner.WithSccacheDir("/cache/sccache"))
+
+				Expect(runner.Install(workingDir, destLayer)).To(MatchError(ContainSubstring("unable to find sccache")))
+				Expect(executor.Calls).To(HaveLen(1))
+			})
+
+			it("does not fail the build when the statistics can't be read", func() {
+				logBuf := &bytes.Buffer{}
+
+				executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+					return ex.Command == "sccache" && ex.Args[0] == "--show-stats"
+				})).Return(errors.New("server not running"))
+				executor.On("Execute", mock.Anything).Return(nil)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(logBuf)),
+					runner.WithSccacheDir("/cache/sccache"))
+
+				Expect(runner.Install(workingDir, destLayer)).To(Succeed())
+				Expect(logBuf.String()).To(ContainSubstring("Unable to read sccache statistics: server not running"))
+			})
+		})
+
+		context("fetches dependencies", func() {
+			it("retries cargo fetch until it succeeds", func() {
+				logBuf := &bytes.Buffer{}
+
+				executor.On("Execute", mock.Anything).Return(errors.New("network unreachable")).Twice()
+				executor.On("Execute", mock.Anything).Return(nil).Once()
+
+				runner := runner.NewCargoRunner(
+					runner.WithBackoff(func(int) time.Duration { return 0 }),
+					runner.WithCargoHome(cargoHome),
+					runner.WithCargoInstallArgs("--locked --bins"),
+					runner.WithExecutor(executor),
+					runner.WithFetchRetry(3),
+					runner.WithLogger(bard.NewLogger(logBuf)),
+					runner.WithTarget("x86_64-unknown-linux-musl"))
+
+				Expect(runner.Fetch(workingDir)).To(Succeed())
+
+				Expect(executor.Calls).To(HaveLen(3))
+				for _, call := range executor.Calls {
+					e := call.Arguments[0].(effect.Execution)
+					Expect(e.Command).To(Equal("cargo"))
+					Expect(e.Args).To(Equal([]string{"fetch", "--color=never", "--locked", "--target=x86_64-unknown-linux-musl"}))
+					Expect(e.Dir).To(Equal(workingDir))
+				}
+
+				Expect(logBuf.String()).To(ContainSubstring("cargo fetch failed (attempt 1 of 4)"))
+				Expect(logBuf.String()).To(ContainSubstring("cargo fetch failed (attempt 2 of 4)"))
+			})
+
+			it("adds --locked", func() {
+				executor.On("Execute", mock.Anything).Return(nil)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLocked(true),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				Expect(runner.Fetch(workingDir)).To(Succeed())
+
+				e := executor.Calls[0].Arguments[0].(effect.Execution)
+				Expect(e.Args).To(Equal([]string{"fetch", "--color=never", "--locked"}))
+			})
+
+			it("fails when all attempts fail", func() {
+				executor.On("Execute", mock.Anything).Return(errors.New("network unreachable"))
+
+				runner := runner.NewCargoRunner(
+					runner.WithBackoff(func(int) time.Duration { return 0 }),
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithFetchRetry(1),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				Expect(runner.Fetch(workingDir)).To(MatchError("cargo fetch failed after 2 attempts\nnetwork unreachable"))
+				Expect(executor.Calls).To(HaveLen(2))
+			})
+		})
+
+		context("sets cargo env", func() {
+			it("passes the environment to cargo", func() {
+				t.Setenv("EXISTING", "value")
+
+				executor.On("Execute", mock.Anything).Return(nil)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoEnv([]string{"OPENSSL_DIR=/usr/local/ssl", "RUSTFLAGS=-C target-cpu=native"}),
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				Expect(runner.Install(workingDir, destLayer)).To(Succeed())
+
+				e := executor.Calls[0].Arguments[0].(effect.Execution)
+				Expect(e.Env).To(ContainElements("EXISTING=value", "OPENSSL_DIR=/usr/local/ssl", "RUSTFLAGS=-C target-cpu=native"))
+				Expect(os.Getenv("OPENSSL_DIR")).To(BeEmpty())
+			})
+
+			it("merges BP_CARGO_RUSTFLAGS into the inherited RUSTFLAGS", func() {
+				t.Setenv("RUSTFLAGS", "-C target-cpu=native")
+
+				executor.On("Execute", mock.Anything).Return(nil)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+					runner.WithRustFlags("-C target-cpu=native --cfg tokio_unstable"))
+
+				Expect(runner.Install(workingDir, destLayer)).To(Succeed())
+
+				e := executor.Calls[0].Arguments[0].(effect.Execution)
+				Expect(e.Env).To(ContainElement("RUSTFLAGS=-C target-cpu=native --cfg tokio_unstable"))
+				Expect(e.Env).ToNot(ContainElement("RUSTFLAGS=-C target-cpu=native"))
+			})
+
+			it("merges BP_CARGO_RUSTFLAGS into RUSTFLAGS from BP_CARGO_ENV", func() {
+				executor.On("Execute", mock.Anything).Return(nil)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoEnv([]string{"RUSTFLAGS=-C opt-level=3"}),
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+					runner.WithRustFlags("-C debuginfo=1"))
+
+				Expect(runner.Install(workingDir, destLayer)).To(Succeed())
+
+				e := executor.Calls[0].Arguments[0].(effect.Execution)
+				Expect(e.Env).To(ContainElement("RUSTFLAGS=-C opt-level=3 -C debuginfo=1"))
+				Expect(e.Env).ToNot(ContainElement("RUSTFLAGS=-C opt-level=3"))
+			})
+
+			it("only adds the layer to the PATH by default", func() {
+				t.Setenv("PATH", "/usr/bin")
+				executor.On("Execute", mock.Anything).Return(nil)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				Expect(runner.Install(workingDir, destLayer)).To(Succeed())
+
+				e := executor.Calls[0].Arguments[0].(effect.Execution)
+				Expect(e.Env).To(ContainElement("PATH=/usr/bin:/some/location/2/bin"))
+				Expect(e.Env).NotTo(ContainElement(HavePrefix("RUSTC_WRAPPER=")))
+			})
+
+			it("does not change the process PATH when installing members", func() {
+				t.Setenv("PATH", "/usr/bin")
+				executor.On("Execute", mock.Anything).Return(nil)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				Expect(runner.InstallMember("./basics", workingDir, destLayer)).To(Succeed())
+				Expect(runner.InstallMember("./todo", workingDir, destLayer)).To(Succeed())
+
+				Expect(os.Getenv("PATH")).To(Equal("/usr/bin"))
+				Expect(executor.Calls).To(HaveLen(2))
+				for _, call := range executor.Calls {
+					Expect(call.Arguments[0].(effect.Execution).Env).To(ContainElement("PATH=/usr/bin:/some/location/2/bin"))
+				}
+			})
+
+			it("does not add the layer to a PATH that already has it", func() {
+				t.Setenv("PATH", "/usr/bin:/some/location/2/bin")
+				executor.On("Execute", mock.Anything).Return(nil)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				Expect(runner.Install(workingDir, destLayer)).To(Succeed())
+
+				e := executor.Calls[0].Arguments[0].(effect.Execution)
+				Expect(e.Env).To(ContainElement("PATH=/usr/bin:/some/location/2/bin"))
+			})
+		})
+
+		context("and there is metadata", func() {
+			context("pre-rust 1.77.0", func() {
+				it("parses the member paths from metadata", func() {
+					logBuf := bytes.Buffer{}
+					logger := bard.NewLogger(&logBuf)
+
+					metadata := BuildMetadata("/workspace",
+						[]string{
+							"basics 2.0.0 (path+file:///workspace/basics)",
+							"todo 1.2.0 (path+file:///workspace/todo)",
+							"routes 0.5.0 (path+file:///workspace/routes)",
+							"jokes 1.5.6 (path+file:///workspace/jokes)",
+						})
+
+					executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+						Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
+						return true
+					})).Return(func(ex effect.Execution) error {
+						_, err := ex.Stdout.Write([]byte(metadata))
+						Expect(err).ToNot(HaveOccurred())
+						return nil
+					})
+
+					runner := runner.NewCargoRunner(
+						runner.WithCargoHome(cargoHome),
+						runner.WithExecutor(executor),
+						runner.WithLogger(logger))
+
+					urls, err := runner.WorkspaceMembers(workingDir, destLayer)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(urls).To(HaveLen(4))
+
+					url, err := url.Parse("path+file:///workspace/basics")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(urls[0]).To(Equal(*url))
+
+					url, err = url.Parse("path+file:///workspace/todo")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(urls[1]).To(Equal(*url))
+
+					url, err = url.Parse("path+file:///workspace/routes")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(urls[2]).To(Equal(*url))
+
+					url, err = url.Parse("path+file:///workspace/jokes")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(urls[3]).To(Equal(*url))
+				})
+			})
+
+			context("post-rust 1.77.0", func() {
+				it("parses the member paths from metadata", func() {
+					logBuf := bytes.Buffer{}
+					logger := bard.NewLogger(&logBuf)
+
+					metadata := BuildMetadata("/workspace",
+						[]string{
+							"path+file:///workspace/basics#basics@2.0.0",
+							"path+file:///workspace/todo#todo@1.2.0",
+							"path+file:///workspace/routes#routes@0.5.0",
+							"path+file:///workspace/jokes#jokes@1.5.6",
+						})
+
+					executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+						Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
+						return true
+					})).Return(func(ex effect.Execution) error {
+						_, err := ex.Stdout.Write([]byte(metadata))
+						Expect(err).ToNot(HaveOccurred())
+						return nil
+					})
+
+					runner := runner.NewCargoRunner(
+						runner.WithCargoHome(cargoHome),
+						runner.WithExecutor(executor),
+						runner.WithLogger(logger))
+
+					urls, err := runner.WorkspaceMembers(workingDir, destLayer)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(urls).To(HaveLen(4))
+
+					url, err := url.Parse("path+file:///workspace/basics")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(urls[0]).To(Equal(*url))
+
+					url, err = url.Parse("path+file:///workspace/todo")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(urls[1]).To(Equal(*url))
+
+					url, err = url.Parse("path+file:///workspace/routes")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(urls[2]).To(Equal(*url))
+
+					url, err = url.Parse("path+file:///workspace/jokes")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(urls[3]).To(Equal(*url))
+				})
+			})
+
+			context("with members from other sources", func() {
+				it("skips members which are not local paths", func() {
+					logBuf := bytes.Buffer{}
+					logger := bard.NewLogger(&logBuf)
+
+					metadata := BuildMetadata("/workspace",
+						[]string{
+							"path+file:///workspace/basics#basics@2.0.0",
+							"git+https://github.com/example/fork?branch=main#fork@0.3.0",
+							"path+file:///workspace/todo#1.2.0",
+						})
+
+					executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+						_, err := ex.Stdout.Write([]byte(metadata))
+						Expect(err).ToNot(HaveOccurred())
+						return nil
+					})
+
+					runner := runner.NewCargoRunner(
+						runner.WithCargoHome(cargoHome),
+						runner.WithExecutor(executor),
+						runner.WithLogger(logger))
+
+					urls, err := runner.WorkspaceMembers(workingDir, destLayer)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(urls).To(Equal([]url.URL{
+						{Scheme: "path+file", Path: "/workspace/basics"},
+						{Scheme: "path+file", Path: "/workspace/todo"},
+					}))
+					Expect(logBuf.String()).To(ContainSubstring("WARNING: skipping workspace member fork, it comes from a git source"))
+				})
+			})
+
+			context("member filter is set", func() {
+				it("parses the member paths from metadata and preserves order with filters", func() {
+					logBuf := bytes.Buffer{}
+					logger := bard.NewLogger(&logBuf)
+
+					metadata := BuildMetadata("/workspace",
+						[]string{
+							"basics 2.0.0 (path+file:///workspace/basics)",
+							"todo 1.2.0 (path+file:///workspace/todo)",
+							"routes 0.5.0 (path+file:///workspace/routes)",
+							"jokes 1.5.6 (path+file:///workspace/jokes)",
+						})
+
+					executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+						Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
+						return true
+					})).Return(func(ex effect.Execution) error {
+						_, err := ex.Stdout.Write([]byte(metadata))
+						Expect(err).ToNot(HaveOccurred())
+						return nil
+					})
+
+					runner := runner.NewCargoRunner(
+						runner.WithCargoHome(cargoHome),
+						runner.WithCargoWorkspaceMembers("todo,jokes"),
+						runner.WithExecutor(executor),
+						runner.WithLogger(logger))
+
+					urls, err := runner.WorkspaceMembers(workingDir, destLayer)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(urls).To(HaveLen(2))
+
+					url, err := url.Parse("path+file:///workspace/todo")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(urls[0]).To(Equal(*url))
+
+					url, err = url.Parse("path+file:///workspace/jokes")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(urls[1]).To(Equal(*url))
+				})
+			})
+		})
+	})
+
+	context("dry run", func() {
+		var logBuf *bytes.Buffer
+
+		it.Before(func() {
+			logBuf = &bytes.Buffer{}
+		})
+
+		it("logs cargo install without running it", func() {
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "registry", "src"), 0755)).To(Succeed())
+
+			runner := runner.NewCargoRunner(
+				runner.WithBinExcludePatterns([]string{"xtask"}),
+				runner.WithCargoHome(cargoHome),
+				runner.WithDryRun(true),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(logBuf)),
+				runner.WithRegistryCacheMaxBytes(1),
+				runner.WithSccacheDir(t.TempDir()))
+
+			Expect(runner.InstallMember("./todo", workingDir, destLayer)).To(Succeed())
+			Expect(runner.InstallTool("cargo-audit", []string{"--locked"})).To(Succeed())
+			Expect(runner.Fetch(workingDir)).To(Succeed())
+			Expect(runner.Audit(workingDir)).To(Succeed())
+
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+			Expect(logBuf.String()).To(ContainSubstring("Dry run, skipping: cargo install --color=never --root=/some/location/2 --path=./todo"))
+			Expect(logBuf.String()).To(ContainSubstring("Dry run, skipping: cargo install cargo-audit --locked"))
+			Expect(logBuf.String()).To(ContainSubstring("Dry run, skipping: cargo fetch --color=never"))
+			Expect(logBuf.String()).To(ContainSubstring("Dry run, skipping: cargo audit --color=never"))
+			Expect(filepath.Join(cargoHome, "registry", "src")).To(BeADirectory())
+		})
+
+		it("logs cargo metadata without running it", func() {
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithDryRun(true),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(logBuf)))
+
+			members, err := runner.WorkspaceMembers(workingDir, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(members).To(Equal([]url.URL{{Scheme: "file", Path: workingDir}}))
+
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+			Expect(logBuf.String()).To(ContainSubstring("Dry run, skipping: cargo metadata --format-version=1 --no-deps"))
+		})
+
+		it("logs the cleanup of CARGO_HOME without removing anything", func() {
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "bin"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "registry", "src"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(cargoHome, "config.toml"), []byte{}, 0644)).To(Succeed())
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithDryRun(true),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(logBuf)))
+
+			Expect(runner.CleanCargoHomeCache()).To(Succeed())
+
+			Expect(filepath.Join(cargoHome, "registry", "src")).To(BeADirectory())
+			Expect(filepath.Join(cargoHome, "config.toml")).To(BeARegularFile())
+			Expect(logBuf.String()).To(ContainSubstring(fmt.Sprintf("Dry run, skipping: removing %s", filepath.Join(cargoHome, "config.toml"))))
+			Expect(logBuf.String()).To(ContainSubstring(fmt.Sprintf("Dry run, skipping: removing %s", filepath.Join(cargoHome, "registry", "src"))))
+			Expect(logBuf.String()).ToNot(ContainSubstring(filepath.Join(cargoHome, "bin")))
+		})
+	})
+
+	context("failure cases", func() {
+		it("bubbles up failures", func() {
+			logBuf := bytes.Buffer{}
+			logger := bard.NewLogger(&logBuf)
+
+			expectedArgs := []string{
+				"install",
+				"--color=never",
+				"--root=/some/location/2",
+				"--path=.",
+			}
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				return reflect.DeepEqual(ex.Args, expectedArgs) &&
+					ex.Dir == workingDir
+			})).Return(fmt.Errorf("expected"))
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(logger))
+
+			err := runner.Install(workingDir, destLayer)
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(MatchError(Equal("unable to build\nexpected")))
+		})
+
+		it("explains failures caused by an outdated Cargo.lock", func() {
+			executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+				_, err := ex.Stderr.Write([]byte("error: the lock file /workspace/Cargo.lock needs to be updated but --locked was passed to prevent this\n"))
+				Expect(err).ToNot(HaveOccurred())
+				return fmt.Errorf("exit status 101")
+			})
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLocked(true),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			err := runner.Install(workingDir, destLayer)
+			Expect(err).To(MatchError(ContainSubstring("Cargo.lock is out of date")))
+			Expect(err).To(MatchError(ContainSubstring("run `cargo update`")))
+		})
+
+		it("retries cargo install until it succeeds", func() {
+			t.Setenv("PATH", "/usr/bin")
+			logBuf := &bytes.Buffer{}
+
+			executor.On("Execute", mock.Anything).Return(errors.New("failed to download from registry")).Twice()
+			executor.On("Execute", mock.Anything).Return(nil).Once()
+
+			runner := runner.NewCargoRunner(
+				runner.WithBackoff(func(int) time.Duration { return 0 }),
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithInstallRetries(3),
+				runner.WithLogger(bard.NewLogger(logBuf)))
+
+			Expect(runner.Install(workingDir, destLayer)).To(Succeed())
+
+			Expect(executor.Calls).To(HaveLen(3))
+			for _, call := range executor.Calls {
+				Expect(call.Arguments[0].(effect.Execution).Args).To(Equal([]string{"install", "--color=never", "--root=/some/location/2", "--path=."}))
+			}
+
+			Expect(logBuf.String()).To(ContainSubstring("cargo install failed (attempt 1 of 4)"))
+			Expect(logBuf.String()).To(ContainSubstring("cargo install failed (attempt 2 of 4)"))
+			Expect(os.Getenv("PATH")).To(Equal("/usr/bin"))
+			for _, call := range executor.Calls {
+				Expect(call.Arguments[0].(effect.Execution).Env).To(ContainElement("PATH=/usr/bin:/some/location/2/bin"))
+			}
+		})
+
+		it("fails when all cargo install attempts fail", func() {
+			executor.On("Execute", mock.Anything).Return(errors.New("failed to download from registry"))
+
+			runner := runner.NewCargoRunner(
+				runner.WithBackoff(func(int) time.Duration { return 0 }),
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithInstallRetries(1),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			Expect(runner.Install(workingDir, destLayer)).To(MatchError("unable to build\ncargo install failed after 2 attempts\nfailed to download from registry"))
+			Expect(executor.Calls).To(HaveLen(2))
+		})
+
+		context("compile warnings", func() {
+			output := `   Compiling api v0.1.0 (/workspace/api)
+warning: unused variable: ` + "`x`" + `
+warning: ` + "`api`" + ` (bin "api") generated 3 warnings (1 duplicate)
+warning: ` + "`common`" + ` (lib) generated 1 warning (run ` + "`cargo fix --lib -p common`" + ` to apply 1 suggestion)
+    Finished ` + "`release`" + ` profile [optimized] target(s) in 12.34s
+`
+
+			it("counts the warnings of the summaries", func() {
+				Expect(runner.CountCompileWarnings(output)).To(Equal(4))
+				Expect(runner.CountCompileWarnings("    Finished `release` profile [optimized] target(s) in 1.00s\n")).To(Equal(0))
+			})
+
+			it("adds up the warnings of all members", func() {
+				executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+					_, err := ex.Stderr.Write([]byte(output))
+					Expect(err).ToNot(HaveOccurred())
+					return nil
+				})
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				Expect(runner.CompileWarnings()).To(Equal(0))
+				Expect(runner.InstallMember("./api", workingDir, destLayer)).To(Succeed())
+				Expect(runner.InstallMember("./common", workingDir, destLayer)).To(Succeed())
+				Expect(runner.CompileWarnings()).To(Equal(8))
+			})
+		})
+
+		context("retry on lock", func() {
+			lockError := func(ex effect.Execution) error {
+				_, err := ex.Stderr.Write([]byte("    Blocking waiting for file lock on package cache\nerror: failed to acquire package cache lock\n"))
+				Expect(err).ToNot(HaveOccurred())
+				return fmt.Errorf("exit status 101")
+			}
+
+			it("retries cargo install while a file lock is held", func() {
+				logBuf := &bytes.Buffer{}
+
+				executor.On("Execute", mock.Anything).Return(lockError).Once()
+				executor.On("Execute", mock.Anything).Return(nil).Once()
+
+				runner := runner.NewCargoRunner(
+					runner.WithBackoff(func(int) time.Duration { return 0 }),
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithInstallRetryOnLock(2),
+					runner.WithLogger(bard.NewLogger(logBuf)))
+
+				Expect(runner.Install(workingDir, destLayer)).To(Succeed())
+				Expect(executor.Calls).To(HaveLen(2))
+				Expect(logBuf.String()).To(ContainSubstring("cargo install failed (attempt 1 of 3)"))
+			})
+
+			it("does not retry compile errors", func() {
+				executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+					_, err := ex.Stderr.Write([]byte("    Blocking waiting for file lock on build directory\nerror[E0425]: cannot find value `x` in this scope\nerror: could not compile `hello`\n"))
+					Expect(err).ToNot(HaveOccurred())
+					return fmt.Errorf("exit status 101")
+				})
+
+				runner := runner.NewCargoRunner(
+					runner.WithBackoff(func(int) time.Duration { return 0 }),
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithInstallRetryOnLock(2),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				Expect(runner.Install(workingDir, destLayer)).To(MatchError("unable to build\nexit status 101"))
+				Expect(executor.Calls).To(HaveLen(1))
+			})
+
+			it("explains the failure when the lock is still held", func() {
+				executor.On("Execute", mock.Anything).Return(lockError)
+
+				runner := runner.NewCargoRunner(
+					runner.WithBackoff(func(int) time.Duration { return 0 }),
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithInstallRetryOnLock(1),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				err := runner.Install(workingDir, destLayer)
+				Expect(err).To(MatchError(ContainSubstring("another cargo process holds a file lock")))
+				Expect(err).To(MatchError(ContainSubstring("cargo install failed after 2 attempts")))
+				Expect(executor.Calls).To(HaveLen(2))
+			})
+		})
+
+		it("fails the member which does not finish in time", func() {
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				return slices.Contains(ex.Args, "--path=./slow")
+			})).Return(func(ex effect.Execution) error {
+				time.Sleep(time.Second)
+				return nil
+			})
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			timedOut := runner.ErrInstallTimedOut
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithInstallTimeout(50*time.Millisecond),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			Expect(runner.InstallMember("./fast", workingDir, destLayer)).To(Succeed())
+
+			err := runner.InstallMember("./slow", workingDir, destLayer)
+			Expect(err).To(MatchError(ContainSubstring("unable to build ./slow, it did not finish within 50ms")))
+			Expect(errors.Is(err, timedOut)).To(BeTrue())
+		})
+
+		it("does not retry the member which does not finish in time", func() {
+			executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+				time.Sleep(200 * time.Millisecond)
+				return nil
+			})
+
+			runner := runner.NewCargoRunner(
+				runner.WithBackoff(func(int) time.Duration { return 0 }),
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithInstallRetries(2),
+				runner.WithInstallTimeout(50*time.Millisecond),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			err := runner.InstallMember("./slow", workingDir, destLayer)
+			Expect(err).To(MatchError(ContainSubstring("it did not finish within 50ms")))
+			Expect(executor.Calls).To(HaveLen(1))
+		})
+	})
+
+	context("when cargo home has files", func() {
+		it.After(func() {
+			Expect(os.RemoveAll(cargoHome)).To(Succeed())
+		})
+
+		it("do nothing if CARGO_HOME is set but does not exist", func() {
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome("/foo/bar"),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.Logger{}))
+
+			Expect(runner.CleanCargoHomeCache()).To(BeNil())
+		})
+
+		it("is cleaned up", func() {
+			// To keep
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "bin"), 0755)).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "registry", "index"), 0755)).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "registry", "cache"), 0755)).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "git", "db"), 0755)).ToNot(HaveOccurred())
+
+			// To destroy
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "registry", "foo"), 0755)).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "git", "bar"), 0755)).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "baz"), 0755)).ToNot(HaveOccurred())
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.Logger{}))
+
+			Expect(runner.CleanCargoHomeCache()).ToNot(HaveOccurred())
+			Expect(filepath.Join(cargoHome, "bin")).To(BeADirectory())
+			Expect(filepath.Join(cargoHome, "registry", "index")).To(BeADirectory())
+			Expect(filepath.Join(cargoHome, "registry", "cache")).To(BeADirectory())
+			Expect(filepath.Join(cargoHome, "git", "db")).To(BeADirectory())
+			Expect(filepath.Join(cargoHome, "registry", "foo")).ToNot(BeADirectory())
+			Expect(filepath.Join(cargoHome, "git", "bar")).ToNot(BeADirectory())
+			Expect(filepath.Join(cargoHome, "baz")).ToNot(BeADirectory())
+		})
+
+		it("keeps the files tracking installed binaries", func() {
+			// To keep
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "bin"), 0755)).ToNot(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(cargoHome, ".crates.toml"), []byte("[v1]\n"), 0644)).ToNot(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(cargoHome, ".crates2.json"), []byte(`{"installs":{}}`), 0644)).ToNot(HaveOccurred())
+
+			// To destroy
+			Expect(os.WriteFile(filepath.Join(cargoHome, ".package-cache"), []byte{}, 0644)).ToNot(HaveOccurred())
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.Logger{}))
+
+			Expect(runner.CleanCargoHomeCache()).ToNot(HaveOccurred())
+			Expect(filepath.Join(cargoHome, "bin")).To(BeADirectory())
+			Expect(filepath.Join(cargoHome, ".crates.toml")).To(BeARegularFile())
+			Expect(filepath.Join(cargoHome, ".crates2.json")).To(BeARegularFile())
+			Expect(filepath.Join(cargoHome, ".package-cache")).ToNot(BeAnExistingFile())
+		})
+
+		it("keeps exactly the configured entries", func() {
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "bin"), 0755)).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "registry", "index"), 0755)).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "registry", "src"), 0755)).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "git", "db"), 0755)).ToNot(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(cargoHome, "config.toml"), []byte("[net]\n"), 0644)).ToNot(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(cargoHome, ".crates.toml"), []byte("[v1]\n"), 0644)).ToNot(HaveOccurred())
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithCleanHomeExcept(runner.ParseCleanHomeExcept("registry, bin,config.toml")),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.Logger{}))
+
+			Expect(runner.CleanCargoHomeCache()).ToNot(HaveOccurred())
+			Expect(filepath.Join(cargoHome, "bin")).To(BeADirectory())
+			Expect(filepath.Join(cargoHome, "registry", "index")).To(BeADirectory())
+			Expect(filepath.Join(cargoHome, "registry", "src")).ToNot(BeAnExistingFile())
+			Expect(filepath.Join(cargoHome, "config.toml")).To(BeARegularFile())
+			Expect(filepath.Join(cargoHome, "git")).ToNot(BeAnExistingFile())
+			Expect(filepath.Join(cargoHome, ".crates.toml")).ToNot(BeAnExistingFile())
+		})
+
+		context("registry cache is limited", func() {
+			var writeCrate = func(name string, size int, age time.Duration) string {
+				path := filepath.Join(cargoHome, "registry", "cache", "index.crates.io-6f17d22bba15001f", name)
+				Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+				Expect(os.WriteFile(path, bytes.Repeat([]byte{'x'}, size), 0644)).To(Succeed())
+
+				modTime := time.Now().Add(-age)
+				Expect(os.Chtimes(path, modTime, modTime)).To(Succeed())
+				return path
+			}
+
+			it("prunes the oldest crates until the cache fits", func() {
+				oldest := writeCrate("oldest-1.0.0.crate", 400, 3*time.Hour)
+				older := writeCrate("older-1.0.0.crate", 300, 2*time.Hour)
+				newer := writeCrate("newer-1.0.0.crate", 200, time.Hour)
+				newest := writeCrate("newest-1.0.0.crate", 100, 0)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				Expect(runner.PruneRegistryCache(500)).To(Succeed())
+				Expect(oldest).ToNot(BeAnExistingFile())
+				Expect(older).ToNot(BeAnExistingFile())
+				Expect(newer).To(BeARegularFile())
+				Expect(newest).To(BeARegularFile())
+			})
+
+			it("does nothing if the cache fits", func() {
+				oldest := writeCrate("oldest-1.0.0.crate", 400, 3*time.Hour)
+				newest := writeCrate("newest-1.0.0.crate", 100, 0)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				Expect(runner.PruneRegistryCache(500)).To(Succeed())
+				Expect(oldest).To(BeARegularFile())
+				Expect(newest).To(BeARegularFile())
+			})
+
+			it("does nothing without a registry cache", func() {
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				Expect(runner.PruneRegistryCache(1)).To(Succeed())
+			})
+
+			it("prunes after installing", func() {
+				oldest := writeCrate("oldest-1.0.0.crate", 400, 3*time.Hour)
+				newest := writeCrate("newest-1.0.0.crate", 100, 0)
+
+				executor.On("Execute", mock.Anything).Return(nil)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+					runner.WithRegistryCacheMaxBytes(200))
+
+				Expect(runner.Install(workingDir, libcnb.Layer{Path: t.TempDir()})).To(Succeed())
+				Expect(oldest).ToNot(BeAnExistingFile())
+				Expect(newest).To(BeARegularFile())
+			})
+		})
+
+		context("extracted registry sources are pruned", func() {
+			var (
+				appDir string
+				index  string
+			)
+
+			var writeLock = func(crates ...string) {
+				lock := "version = 3\n"
+				for _, crate := range crates {
+					name, version, _ := strings.Cut(crate, "@")
+					lock += fmt.Sprintf("\n[[package]]\nname = %q\nversion = %q\n", name, version)
+				}
+				Expect(os.WriteFile(filepath.Join(appDir, "Cargo.lock"), []byte(lock), 0644)).To(Succeed())
+			}
+
+			var extract = func(name string) string {
+				path := filepath.Join(cargoHome, "registry", "src", "index.crates.io-6f17d22bba15001f", name)
+				Expect(os.MkdirAll(path, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(path, "lib.rs"), []byte{}, 0644)).To(Succeed())
+				return path
+			}
+
+			it.Before(func() {
+				appDir = t.TempDir()
+				index = filepath.Join(cargoHome, "registry", "cache", "index.crates.io-6f17d22bba15001f")
+				Expect(os.MkdirAll(index, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(index, "old-1.0.0.crate"), []byte{}, 0644)).To(Succeed())
+			})
+
+			it("keeps sources used by the last builds", func() {
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+					runner.WithSrcKeep(2))
+
+				old := extract("old-1.0.0")
+				current := extract("current-1.0.0")
+
+				writeLock("old@1.0.0", "current@1.0.0")
+				Expect(runner.PruneRegistrySources(appDir)).To(Succeed())
+
+				writeLock("current@1.0.0")
+				Expect(runner.PruneRegistrySources(appDir)).To(Succeed())
+				Expect(old).To(BeADirectory())
+				Expect(current).To(BeADirectory())
+
+				Expect(runner.PruneRegistrySources(appDir)).To(Succeed())
+				Expect(old).ToNot(BeAnExistingFile())
+				Expect(current).To(BeADirectory())
+				Expect(filepath.Join(index, "old-1.0.0.crate")).To(BeARegularFile())
+			})
+
+			it("keeps sources that appeared during the build", func() {
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+					runner.WithSrcKeep(1))
+
+				writeLock("current@1.0.0")
+				Expect(runner.PruneRegistrySources(appDir)).To(Succeed())
+
+				tool := extract("tool-dep-2.0.0")
+				Expect(runner.PruneRegistrySources(appDir)).To(Succeed())
+				Expect(tool).To(BeADirectory())
+
+				Expect(runner.PruneRegistrySources(appDir)).To(Succeed())
+				Expect(tool).ToNot(BeAnExistingFile())
+			})
+
+			it("keeps extracted sources when cleaning CARGO_HOME", func() {
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+					runner.WithSrcKeep(1))
+
+				old := extract("old-1.0.0")
+				Expect(runner.CleanCargoHomeCache()).To(Succeed())
+				Expect(old).To(BeADirectory())
+			})
+
+			it("does nothing when not configured", func() {
+				generations := filepath.Join(cargoHome, "registry", "src", runner.RegistrySourcesGenerationsFile)
+
+				runner := runner.NewCargoRunner(
+					runner.WithCargoHome(cargoHome),
+					runner.WithExecutor(executor),
+					runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+				old := extract("old-1.0.0")
+				Expect(runner.PruneRegistrySources(appDir)).To(Succeed())
+				Expect(old).To(BeADirectory())
+				Expect(generations).ToNot(BeAnExistingFile())
+			})
+		})
+
+		it("handles when registry and git are not present", func() {
+			// To keep
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "bin"), 0755)).ToNot(HaveOccurred())
+
+			// To destroy
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "baz"), 0755)).ToNot(HaveOccurred())
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.Logger{}))
+
+			Expect(runner.CleanCargoHomeCache()).ToNot(HaveOccurred())
+			Expect(filepath.Join(cargoHome, "bin")).To(BeADirectory())
+			Expect(filepath.Join(cargoHome, "baz")).ToNot(BeADirectory())
+		})
+	})
+
+	context("package targets", func() {
+		it("reads package target names", func() {
+			metadata := BuildMetadataWithPackages("/does/not/matter",
+				buildMetadata{
+					members: []string{
+						"basics 2.0.0 (path+file:///does/not/matter/basics)",
+					},
+					packages: []buildPackage{
+						{
+							id: "basics 2.0.0 (path+file:///does/not/matter/basics)",
+							targets: []buildTarget{
+								{kind: "lib", crateType: "lib", name: "inflector", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/Inflector-0.11.4/src/lib.rs", edition: "2015", doc: "true", doctest: "true", test: "true"},
+								{kind: "bin", crateType: "bin", name: "decrypt", srcPath: "/does/not/matter/src/bin/decrypt/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "encrypt", srcPath: "/does/not/matter/src/bin/encrypt/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "pksign", srcPath: "/does/not/matter/src/bin/pksign/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "gcc-shim", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/cc-1.0.50/src/bin/gcc-shim.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+							},
+						},
+					},
+				})
+
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
+				return true
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.Logger{}))
+
+			names, err := runner.ProjectTargets(workingDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(HaveLen(3))
+			Expect(names).To(ContainElement("decrypt"))
+			Expect(names).To(ContainElement("encrypt"))
+			Expect(names).To(ContainElement("pksign"))
+			Expect(names).ToNot(ContainElement("gcc-shim"))
+		})
+
+		it("reads target names of bins from dependencies when enabled", func() {
+			metadata := BuildMetadataWithPackages("/does/not/matter",
+				buildMetadata{
+					members: []string{
+						"basics 2.0.0 (path+file:///does/not/matter/basics)",
+					},
+					packages: []buildPackage{
+						{
+							id: "basics 2.0.0 (path+file:///does/not/matter/basics)",
+							targets: []buildTarget{
+								{kind: "lib", crateType: "lib", name: "inflector", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/Inflector-0.11.4/src/lib.rs", edition: "2015", doc: "true", doctest: "true", test: "true"},
+								{kind: "bin", crateType: "bin", name: "decrypt", srcPath: "/does/not/matter/src/bin/decrypt/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "encrypt", srcPath: "/does/not/matter/src/bin/encrypt/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "pksign", srcPath: "/does/not/matter/src/bin/pksign/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "gcc-shim", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/cc-1.0.50/src/bin/gcc-shim.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+							},
+						},
+					},
+				})
+
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
+				return true
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithIncludeDepBins(true),
+				runner.WithLogger(bard.Logger{}))
+
+			names, err := runner.ProjectTargets(workingDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(Equal([]string{"decrypt", "encrypt", "pksign", "gcc-shim"}))
+		})
+
+		it("reads filtered target names", func() {
+			metadata := BuildMetadataWithPackages("/does/not/matter",
+				buildMetadata{
+					members: []string{
+						"basics 2.0.0 (path+file:///does/not/matter/basics)",
+						"advanced 2.0.0 (path+file:///does/not/matter/advanced)",
+						"expert 2.0.0 (path+file:///does/not/matter/expert)",
+					},
+					packages: []buildPackage{
+						{
+							id: "advanced 2.0.0 (path+file:///does/not/matter/advanced)",
+							targets: []buildTarget{
+								{kind: "lib", crateType: "lib", name: "inflector", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/Inflector-0.11.4/src/lib.rs", edition: "2015", doc: "true", doctest: "true", test: "true"},
+								{kind: "bin", crateType: "bin", name: "decrypt", srcPath: "/does/not/matter/src/bin/decrypt/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "gcc-shim", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/cc-1.0.50/src/bin/gcc-shim.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+							},
+						},
+						{
+							id: "expert 2.0.0 (path+file:///does/not/matter/expert)",
+							targets: []buildTarget{
+								{kind: "lib", crateType: "lib", name: "inflector", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/Inflector-0.11.4/src/lib.rs", edition: "2015", doc: "true", doctest: "true", test: "true"},
+								{kind: "bin", crateType: "bin", name: "foo", srcPath: "/does/not/matter/src/bin/encrypt/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "bar", srcPath: "/does/not/matter/src/bin/pksign/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "gcc-shim", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/cc-1.0.50/src/bin/gcc-shim.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+							},
+						},
+					},
+				})
+
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
+				return true
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithCargoWorkspaceMembers("expert"),
+				runner.WithLogger(bard.Logger{}))
+
+			names, err := runner.ProjectTargets(workingDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(HaveLen(2))
+			Expect(names).To(ContainElement("foo"))
+			Expect(names).To(ContainElement("bar"))
+		})
+	})
+
+	context("validate manifest", func() {
+		it("passes when cargo can load the manifest", func() {
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				return ex.Args[0] == "metadata"
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte(`{"packages":[],"workspace_members":[]}`))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			Expect(runner.ValidateManifest(workingDir)).To(Succeed())
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
+			Expect(e.Dir).To(Equal(workingDir))
+		})
+
+		it("fails with cargo's error when the manifest is invalid", func() {
+			executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+				_, err := ex.Stderr.Write([]byte("error: failed to parse manifest at `/workspace/Cargo.toml`"))
+				Expect(err).ToNot(HaveOccurred())
+				return fmt.Errorf("exit status 101")
+			})
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			err := runner.ValidateManifest(workingDir)
+			Expect(err).To(MatchError(ContainSubstring("invalid Cargo manifest in " + workingDir)))
+			Expect(err).To(MatchError(ContainSubstring("failed to parse manifest at `/workspace/Cargo.toml`")))
+		})
+	})
+
+	context("metadata args", func() {
+		it("keeps only the flags which don't change the output format", func() {
+			allowed, dropped := runner.FilterMetadataArgs([]string{
+				"--features", "server,tls", "--all-features", "-F=cli", "--format-version=2", "--format-version", "2",
+				"--no-default-features", "--manifest-path=other/Cargo.toml", "--offline=yes",
+			})
+			Expect(allowed).To(Equal([]string{"--features", "server,tls", "--all-features", "-F=cli", "--no-default-features"}))
+			Expect(dropped).To(Equal([]string{"--format-version=2", "--format-version", "2", "--manifest-path=other/Cargo.toml", "--offline=yes"}))
+		})
+
+		it("appends the args to the fixed metadata args", func() {
+			executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte(`{"packages":[],"workspace_members":[]}`))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+				runner.WithMetadataArgs([]string{"--all-features"}))
+
+			_, err := runner.ProjectTargets(workingDir)
+			Expect(err).ToNot(HaveOccurred())
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps", "--all-features"}))
+		})
+	})
+
+	context("metadata cache", func() {
+		var metadataCalls func() int
+
+		it.Before(func() {
+			metadata := BuildMetadataWithPackages("/workspace",
+				buildMetadata{
+					members: []string{"path+file:///workspace#app@1.0.0"},
+					packages: []buildPackage{
+						{
+							id: "path+file:///workspace#app@1.0.0",
+							targets: []buildTarget{
+								{kind: "bin", crateType: "bin", name: "app", srcPath: "/workspace/src/main.rs", edition: "2021", doc: "true", doctest: "false", test: "true"},
+							},
+						},
+					},
+				})
+
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				return ex.Args[0] == "metadata"
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+
+			metadataCalls = func() int {
+				count := 0
+				for _, call := range executor.Calls {
+					if call.Arguments[0].(effect.Execution).Args[0] == "metadata" {
+						count++
+					}
+				}
+				return count
+			}
+		})
+
+		it("runs cargo metadata once per source directory", func() {
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.Logger{}))
+
+			members, err := runner.WorkspaceMembers("/workspace", destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(members).To(HaveLen(1))
+
+			targets, err := runner.ProjectTargets("/workspace")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(targets).To(Equal([]string{"app"}))
+
+			Expect(metadataCalls()).To(Equal(1))
+
+			_, err = runner.ProjectTargets("/other")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metadataCalls()).To(Equal(2))
+		})
+
+		it("runs cargo metadata again once invalidated", func() {
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.Logger{}))
+
+			_, err := runner.ProjectTargets("/workspace")
+			Expect(err).ToNot(HaveOccurred())
 
-					executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
-						Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
-						return true
-					})).Return(func(ex effect.Execution) error {
-						_, err := ex.Stdout.Write([]byte(metadata))
-						Expect(err).ToNot(HaveOccurred())
-						return nil
-					})
+			runner.InvalidateMetadata("/workspace")
 
-					runner := runner.NewCargoRunner(
-						runner.WithCargoHome(cargoHome),
-						runner.WithCargoWorkspaceMembers("todo,jokes"),
-						runner.WithExecutor(executor),
-						runner.WithLogger(logger))
+			_, err = runner.ProjectTargets("/workspace")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metadataCalls()).To(Equal(2))
+		})
 
-					urls, err := runner.WorkspaceMembers(workingDir, destLayer)
-					Expect(err).ToNot(HaveOccurred())
+		it("runs cargo metadata again after the pre-build command", func() {
+			executor.On("Execute", mock.Anything).Return(nil)
 
-					Expect(urls).To(HaveLen(2))
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
 
-					url, err := url.Parse("path+file:///workspace/todo")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(urls[0]).To(Equal(*url))
+			_, err := runner.ProjectTargets("/workspace")
+			Expect(err).ToNot(HaveOccurred())
 
-					url, err = url.Parse("path+file:///workspace/jokes")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(urls[1]).To(Equal(*url))
-				})
-			})
+			Expect(runner.PreBuild("/workspace", "./codegen.sh")).To(Succeed())
+
+			_, err = runner.WorkspaceMembers("/workspace", destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metadataCalls()).To(Equal(2))
 		})
 	})
 
-	context("failure cases", func() {
-		it("bubbles up failures", func() {
-			logBuf := bytes.Buffer{}
-			logger := bard.NewLogger(&logBuf)
+	context("examples", func() {
+		var metadata string
+
+		it.Before(func() {
+			metadata = BuildMetadataWithPackages("/workspace",
+				buildMetadata{
+					members: []string{"path+file:///workspace#app@1.0.0"},
+					packages: []buildPackage{
+						{
+							id: "path+file:///workspace#app@1.0.0",
+							targets: []buildTarget{
+								{kind: "bin", crateType: "bin", name: "app", srcPath: "/workspace/src/main.rs", edition: "2021", doc: "true", doctest: "false", test: "true"},
+								{kind: "example", crateType: "bin", name: "client", srcPath: "/workspace/examples/client.rs", edition: "2021", doc: "false", doctest: "false", test: "false"},
+								{kind: "example", crateType: "lib", name: "plugin", srcPath: "/workspace/examples/plugin.rs", edition: "2021", doc: "false", doctest: "false", test: "false"},
+							},
+						},
+					},
+				})
 
-			expectedArgs := []string{
-				"install",
-				"--color=never",
-				"--root=/some/location/2",
-				"--path=.",
-			}
 			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
-				return reflect.DeepEqual(ex.Args, expectedArgs) &&
-					ex.Dir == workingDir
-			})).Return(fmt.Errorf("expected"))
+				return ex.Args[0] == "metadata"
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
 
+		it("ignores examples by default", func() {
 			runner := runner.NewCargoRunner(
 				runner.WithCargoHome(cargoHome),
 				runner.WithExecutor(executor),
-				runner.WithLogger(logger))
+				runner.WithLogger(bard.Logger{}))
 
-			err := runner.Install(workingDir, destLayer)
-			Expect(err).To(HaveOccurred())
-			Expect(err).To(MatchError(Equal("unable to build\nexpected")))
+			targets, err := runner.ProjectTargetDetails("/workspace")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(targets).To(HaveLen(1))
+			Expect(targets[0].Name).To(Equal("app"))
 		})
-	})
 
-	context("when cargo home has files", func() {
-		it.After(func() {
-			Expect(os.RemoveAll(cargoHome)).To(Succeed())
+		it("includes executable examples", func() {
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithIncludeExamples(true),
+				runner.WithLogger(bard.Logger{}))
+
+			targets, err := runner.ProjectTargetDetails("/workspace")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(targets).To(HaveLen(2))
+			Expect(targets[0].Name).To(Equal("app"))
+			Expect(targets[0].Example).To(BeFalse())
+			Expect(targets[1].Name).To(Equal("client"))
+			Expect(targets[1].Member).To(Equal("app"))
+			Expect(targets[1].Example).To(BeTrue())
 		})
 
-		it("do nothing if CARGO_HOME is set but does not exist", func() {
+		it("installs examples along with binaries", func() {
 			runner := runner.NewCargoRunner(
-				runner.WithCargoHome("/foo/bar"),
+				runner.WithCargoHome(cargoHome),
 				runner.WithExecutor(executor),
-				runner.WithLogger(bard.Logger{}))
+				runner.WithIncludeExamples(true),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
 
-			Expect(runner.CleanCargoHomeCache()).To(BeNil())
+			Expect(runner.Install(workingDir, destLayer)).To(Succeed())
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Args).To(ContainElements("--bins", "--examples"))
 		})
 
-		it("is cleaned up", func() {
-			// To keep
-			Expect(os.MkdirAll(filepath.Join(cargoHome, "bin"), 0755)).ToNot(HaveOccurred())
-			Expect(os.MkdirAll(filepath.Join(cargoHome, "registry", "index"), 0755)).ToNot(HaveOccurred())
-			Expect(os.MkdirAll(filepath.Join(cargoHome, "registry", "cache"), 0755)).ToNot(HaveOccurred())
-			Expect(os.MkdirAll(filepath.Join(cargoHome, "git", "db"), 0755)).ToNot(HaveOccurred())
+		it("does not change an explicit example selection", func() {
+			runner := runner.NewCargoRunner(
+				runner.WithCargoHome(cargoHome),
+				runner.WithCargoInstallArgs("--bin=app --example=client"),
+				runner.WithExecutor(executor),
+				runner.WithIncludeExamples(true),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
 
-			// To destroy
-			Expect(os.MkdirAll(filepath.Join(cargoHome, "registry", "foo"), 0755)).ToNot(HaveOccurred())
-			Expect(os.MkdirAll(filepath.Join(cargoHome, "git", "bar"), 0755)).ToNot(HaveOccurred())
-			Expect(os.MkdirAll(filepath.Join(cargoHome, "baz"), 0755)).ToNot(HaveOccurred())
+			Expect(runner.Install(workingDir, destLayer)).To(Succeed())
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Args).ToNot(ContainElement("--bins"))
+			Expect(e.Args).ToNot(ContainElement("--examples"))
+		})
+	})
+
+	context("toolchain requirements", func() {
+		it("aggregates the editions and rust-version of the members", func() {
+			metadata := BuildMetadataWithPackages("/workspace",
+				buildMetadata{
+					members: []string{
+						"path+file:///workspace/api#api@1.0.0",
+						"path+file:///workspace/worker#worker@1.0.0",
+						"path+file:///workspace/legacy#legacy@0.1.0",
+						"path+file:///workspace/shared#shared@1.0.0",
+					},
+					packages: []buildPackage{
+						{id: "path+file:///workspace/api#api@1.0.0", edition: "2021", rustVersion: "1.70"},
+						{id: "path+file:///workspace/worker#worker@1.0.0", edition: "2021", rustVersion: "1.74.1"},
+						{id: "path+file:///workspace/legacy#legacy@0.1.0"},
+						{id: "path+file:///workspace/shared#shared@1.0.0", edition: "2018", rustVersion: "1.9"},
+					},
+				})
+
+			executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
 
 			runner := runner.NewCargoRunner(
 				runner.WithCargoHome(cargoHome),
 				runner.WithExecutor(executor),
 				runner.WithLogger(bard.Logger{}))
 
-			Expect(runner.CleanCargoHomeCache()).ToNot(HaveOccurred())
-			Expect(filepath.Join(cargoHome, "bin")).To(BeADirectory())
-			Expect(filepath.Join(cargoHome, "registry", "index")).To(BeADirectory())
-			Expect(filepath.Join(cargoHome, "registry", "cache")).To(BeADirectory())
-			Expect(filepath.Join(cargoHome, "git", "db")).To(BeADirectory())
-			Expect(filepath.Join(cargoHome, "registry", "foo")).ToNot(BeADirectory())
-			Expect(filepath.Join(cargoHome, "git", "bar")).ToNot(BeADirectory())
-			Expect(filepath.Join(cargoHome, "baz")).ToNot(BeADirectory())
+			requirements, err := runner.ToolchainRequirements("/workspace")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requirements.Editions).To(Equal([]string{"2015", "2018", "2021"}))
+			Expect(requirements.MSRV).To(Equal("1.74.1"))
 		})
 
-		it("handles when registry and git are not present", func() {
-			// To keep
-			Expect(os.MkdirAll(filepath.Join(cargoHome, "bin"), 0755)).ToNot(HaveOccurred())
+		it("has no MSRV if no member sets rust-version", func() {
+			metadata := BuildMetadataWithPackages("/workspace",
+				buildMetadata{
+					members:  []string{"path+file:///workspace#app@1.0.0"},
+					packages: []buildPackage{{id: "path+file:///workspace#app@1.0.0", edition: "2021"}},
+				})
 
-			// To destroy
-			Expect(os.MkdirAll(filepath.Join(cargoHome, "baz"), 0755)).ToNot(HaveOccurred())
+			executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
 
 			runner := runner.NewCargoRunner(
 				runner.WithCargoHome(cargoHome),
 				runner.WithExecutor(executor),
 				runner.WithLogger(bard.Logger{}))
 
-			Expect(runner.CleanCargoHomeCache()).ToNot(HaveOccurred())
-			Expect(filepath.Join(cargoHome, "bin")).To(BeADirectory())
-			Expect(filepath.Join(cargoHome, "baz")).ToNot(BeADirectory())
+			requirements, err := runner.ToolchainRequirements("/workspace")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requirements.Editions).To(Equal([]string{"2021"}))
+			Expect(requirements.MSRV).To(BeEmpty())
 		})
 	})
 
-	context("package targets", func() {
-		it("reads package target names", func() {
+	context("package target details", func() {
+		it("reads the member, edition and source path of each target", func() {
 			metadata := BuildMetadataWithPackages("/does/not/matter",
 				buildMetadata{
 					members: []string{
-						"basics 2.0.0 (path+file:///does/not/matter/basics)",
+						"path+file:///does/not/matter/api#api@1.0.0",
+						"path+file:///does/not/matter/worker#worker@1.0.0",
 					},
 					packages: []buildPackage{
 						{
-							id: "basics 2.0.0 (path+file:///does/not/matter/basics)",
+							id: "path+file:///does/not/matter/api#api@1.0.0",
 							targets: []buildTarget{
-								{kind: "lib", crateType: "lib", name: "inflector", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/Inflector-0.11.4/src/lib.rs", edition: "2015", doc: "true", doctest: "true", test: "true"},
-								{kind: "bin", crateType: "bin", name: "decrypt", srcPath: "/does/not/matter/src/bin/decrypt/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
-								{kind: "bin", crateType: "bin", name: "encrypt", srcPath: "/does/not/matter/src/bin/encrypt/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
-								{kind: "bin", crateType: "bin", name: "pksign", srcPath: "/does/not/matter/src/bin/pksign/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
-								{kind: "bin", crateType: "bin", name: "gcc-shim", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/cc-1.0.50/src/bin/gcc-shim.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "api", srcPath: "/does/not/matter/api/src/main.rs", edition: "2021", doc: "true", doctest: "false", test: "true"},
+							},
+						},
+						{
+							id: "path+file:///does/not/matter/worker#worker@1.0.0",
+							targets: []buildTarget{
+								{kind: "lib", crateType: "lib", name: "worker", srcPath: "/does/not/matter/worker/src/lib.rs", edition: "2021", doc: "true", doctest: "true", test: "true"},
+								{kind: "bin", crateType: "bin", name: "worker", srcPath: "/does/not/matter/worker/src/main.rs", edition: "2021", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "worker-admin", srcPath: "/does/not/matter/worker/src/bin/admin.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
 							},
 						},
 					},
 				})
 
-			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
-				Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
-				return true
-			})).Return(func(ex effect.Execution) error {
+			executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
 				_, err := ex.Stdout.Write([]byte(metadata))
 				Expect(err).ToNot(HaveOccurred())
 				return nil
 			})
 
+			expected := []runner.Target{
+				{Name: "api", Member: "api", MemberPath: "/does/not/matter/api", Edition: "2021", SrcPath: "/does/not/matter/api/src/main.rs"},
+				{Name: "worker", Member: "worker", MemberPath: "/does/not/matter/worker", Edition: "2021", SrcPath: "/does/not/matter/worker/src/main.rs"},
+				{Name: "worker-admin", Member: "worker", MemberPath: "/does/not/matter/worker", Edition: "2018", SrcPath: "/does/not/matter/worker/src/bin/admin.rs"},
+			}
+
 			runner := runner.NewCargoRunner(
 				runner.WithCargoHome(cargoHome),
 				runner.WithExecutor(executor),
 				runner.WithLogger(bard.Logger{}))
 
-			names, err := runner.ProjectTargets(workingDir)
+			targets, err := runner.ProjectTargetDetails(workingDir)
 			Expect(err).ToNot(HaveOccurred())
-			Expect(names).To(HaveLen(3))
-			Expect(names).To(ContainElement("decrypt"))
-			Expect(names).To(ContainElement("encrypt"))
-			Expect(names).To(ContainElement("pksign"))
+			Expect(targets).To(Equal(expected))
 		})
+	})
 
-		it("reads filtered target names", func() {
-			metadata := BuildMetadataWithPackages("/does/not/matter",
+	context("excluded binaries", func() {
+		var metadata string
+
+		it.Before(func() {
+			metadata = BuildMetadataWithPackages("/workspace",
 				buildMetadata{
 					members: []string{
-						"basics 2.0.0 (path+file:///does/not/matter/basics)",
-						"advanced 2.0.0 (path+file:///does/not/matter/advanced)",
-						"expert 2.0.0 (path+file:///does/not/matter/expert)",
+						"path+file:///workspace/api#api@1.0.0",
+						"path+file:///workspace/bench#bench@1.0.0",
 					},
 					packages: []buildPackage{
 						{
-							id: "advanced 2.0.0 (path+file:///does/not/matter/advanced)",
+							id: "path+file:///workspace/api#api@1.0.0",
 							targets: []buildTarget{
-								{kind: "lib", crateType: "lib", name: "inflector", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/Inflector-0.11.4/src/lib.rs", edition: "2015", doc: "true", doctest: "true", test: "true"},
-								{kind: "bin", crateType: "bin", name: "decrypt", srcPath: "/does/not/matter/src/bin/decrypt/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
-								{kind: "bin", crateType: "bin", name: "gcc-shim", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/cc-1.0.50/src/bin/gcc-shim.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "api", srcPath: "/workspace/api/src/main.rs", edition: "2021", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "api-bench", srcPath: "/workspace/api/src/bin/bench.rs", edition: "2021", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "test-client", srcPath: "/workspace/api/src/bin/client.rs", edition: "2021", doc: "true", doctest: "false", test: "true"},
 							},
 						},
 						{
-							id: "expert 2.0.0 (path+file:///does/not/matter/expert)",
+							id: "path+file:///workspace/bench#bench@1.0.0",
 							targets: []buildTarget{
-								{kind: "lib", crateType: "lib", name: "inflector", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/Inflector-0.11.4/src/lib.rs", edition: "2015", doc: "true", doctest: "true", test: "true"},
-								{kind: "bin", crateType: "bin", name: "foo", srcPath: "/does/not/matter/src/bin/encrypt/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
-								{kind: "bin", crateType: "bin", name: "bar", srcPath: "/does/not/matter/src/bin/pksign/main.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
-								{kind: "bin", crateType: "bin", name: "gcc-shim", srcPath: "/cargo_home/registry/src/github.com-1ecc6299db9ec823/cc-1.0.50/src/bin/gcc-shim.rs", edition: "2018", doc: "true", doctest: "false", test: "true"},
+								{kind: "bin", crateType: "bin", name: "load-bench", srcPath: "/workspace/bench/src/main.rs", edition: "2021", doc: "true", doctest: "false", test: "true"},
 							},
 						},
 					},
 				})
 
 			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
-				Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
-				return true
+				return ex.Args[0] == "metadata"
 			})).Return(func(ex effect.Execution) error {
 				_, err := ex.Stdout.Write([]byte(metadata))
 				Expect(err).ToNot(HaveOccurred())
 				return nil
 			})
+			executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+				return ex.Args[0] == "install"
+			})).Return(nil)
+		})
 
+		it("excludes matching binaries from the project targets", func() {
 			runner := runner.NewCargoRunner(
+				runner.WithBinExcludePatterns([]string{"*-bench", "test-*"}),
 				runner.WithCargoHome(cargoHome),
 				runner.WithExecutor(executor),
-				runner.WithCargoWorkspaceMembers("expert"),
 				runner.WithLogger(bard.Logger{}))
 
-			names, err := runner.ProjectTargets(workingDir)
+			targets, err := runner.ProjectTargets("/workspace")
 			Expect(err).ToNot(HaveOccurred())
-			Expect(names).To(HaveLen(2))
-			Expect(names).To(ContainElement("foo"))
-			Expect(names).To(ContainElement("bar"))
+			Expect(targets).To(Equal([]string{"api"}))
+		})
+
+		it("installs only the binaries which are not excluded", func() {
+			runner := runner.NewCargoRunner(
+				runner.WithBinExcludePatterns([]string{"*-bench", "test-*"}),
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			Expect(runner.InstallMember("/workspace/api", workingDir, destLayer)).To(Succeed())
+
+			e := executor.Calls[1].Arguments[0].(effect.Execution)
+			Expect(e.Args).To(ContainElement("--bin=api"))
+			Expect(e.Args).ToNot(ContainElement("--bin=api-bench"))
+			Expect(e.Args).ToNot(ContainElement("--bin=test-client"))
+		})
+
+		it("skips a member when all of its binaries are excluded", func() {
+			logBuf := bytes.Buffer{}
+
+			runner := runner.NewCargoRunner(
+				runner.WithBinExcludePatterns([]string{"*-bench", "test-*"}),
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&logBuf)))
+
+			Expect(runner.InstallMember("/workspace/bench", workingDir, destLayer)).To(Succeed())
+
+			Expect(executor.Calls).To(HaveLen(1))
+			Expect(logBuf.String()).To(ContainSubstring("Skipping /workspace/bench, all of its binary targets are excluded"))
+		})
+
+		it("only uses the selected binaries as project targets", func() {
+			runner := runner.NewCargoRunner(
+				runner.WithBins([]string{"api", "load-bench"}),
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.Logger{}))
+
+			targets, err := runner.ProjectTargets("/workspace")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(targets).To(Equal([]string{"api", "load-bench"}))
+		})
+
+		it("installs the selected binaries of each member", func() {
+			runner := runner.NewCargoRunner(
+				runner.WithBins([]string{"api", "load-bench"}),
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			Expect(runner.InstallMember("/workspace/api", workingDir, destLayer)).To(Succeed())
+			Expect(runner.InstallMember("/workspace/bench", workingDir, destLayer)).To(Succeed())
+
+			installs := slices.DeleteFunc(slices.Clone(executor.Calls), func(call mock.Call) bool {
+				return call.Arguments[0].(effect.Execution).Args[0] != "install"
+			})
+			Expect(installs).To(HaveLen(2))
+			Expect(installs[0].Arguments[0].(effect.Execution).Args).To(ContainElement("--bin=api"))
+			Expect(installs[0].Arguments[0].(effect.Execution).Args).ToNot(ContainElement("--bin=load-bench"))
+			Expect(installs[1].Arguments[0].(effect.Execution).Args).To(ContainElement("--bin=load-bench"))
+			Expect(installs[1].Arguments[0].(effect.Execution).Args).ToNot(ContainElement("--bin=api"))
+		})
+
+		it("skips a member without selected binaries", func() {
+			logBuf := bytes.Buffer{}
+
+			runner := runner.NewCargoRunner(
+				runner.WithBins([]string{"api"}),
+				runner.WithCargoHome(cargoHome),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&logBuf)))
+
+			Expect(runner.InstallMember("/workspace/bench", workingDir, destLayer)).To(Succeed())
+
+			Expect(executor.Calls).To(HaveLen(1))
+			Expect(logBuf.String()).To(ContainSubstring("Skipping /workspace/bench, none of its binary targets are in BP_CARGO_BINS"))
+		})
+
+		it("does not change an explicit binary selection", func() {
+			runner := runner.NewCargoRunner(
+				runner.WithBinExcludePatterns([]string{"*-bench"}),
+				runner.WithCargoHome(cargoHome),
+				runner.WithCargoInstallArgs("--bin=api-bench"),
+				runner.WithExecutor(executor),
+				runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+			Expect(runner.InstallMember("/workspace/api", workingDir, destLayer)).To(Succeed())
+
+			Expect(executor.Calls).To(HaveLen(1))
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Args).To(ContainElement("--bin=api-bench"))
+			Expect(e.Args).ToNot(ContainElement("--bin=api"))
 		})
 	})
 
@@ -816,13 +2938,21 @@ func testRunners(t *testing.T, context spec.G, it spec.S) {
 				Expect(url).To(Equal("path+file:///workspace/basics"))
 			})
 
+			it("parses git members", func() {
+				pkgName, version, url, err := runner.ParseWorkspaceMember("fork 0.3.0 (git+https://github.com/example/fork?branch=main#4f2c3b1a)")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pkgName).To(Equal("fork"))
+				Expect(version).To(Equal("0.3.0"))
+				Expect(url).To(Equal("git+https://github.com/example/fork?branch=main#4f2c3b1a"))
+			})
+
 			it("fails to parse because not enough spaces", func() {
 				_, _, _, err := runner.ParseWorkspaceMember("basics 2.0.0")
 				Expect(err).To(MatchError("unable to parse workspace member [basics 2.0.0], unexpected format"))
 			})
 		})
 
-		context("pre-rust 1.77.0", func() {
+		context("post-rust 1.77.0", func() {
 			it("parses them", func() {
 				pkgName, version, url, err := runner.ParseWorkspaceMember("path+file:///workspace/basics#basics@2.0.0")
 				Expect(err).ToNot(HaveOccurred())
@@ -836,6 +2966,30 @@ func testRunners(t *testing.T, context spec.G, it spec.S) {
 				Expect(err).To(MatchError("unable to parse workspace member [path+file:///workspace/basics], missing `#`"))
 			})
 
+			it("parses members whose name matches the directory", func() {
+				pkgName, version, url, err := runner.ParseWorkspaceMember("path+file:///workspace/basics#2.0.0")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pkgName).To(Equal("basics"))
+				Expect(version).To(Equal("2.0.0"))
+				Expect(url).To(Equal("path+file:///workspace/basics"))
+			})
+
+			it("parses git and registry members", func() {
+				pkgName, version, url, err := runner.ParseWorkspaceMember("git+https://github.com/example/fork?branch=main#fork@0.3.0")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pkgName).To(Equal("fork"))
+				Expect(version).To(Equal("0.3.0"))
+				Expect(url).To(Equal("git+https://github.com/example/fork?branch=main"))
+				Expect(runner.SourceKind(url)).To(Equal("git"))
+				Expect(runner.IsLocalMember(url)).To(BeFalse())
+
+				pkgName, version, url, err = runner.ParseWorkspaceMember("registry+https://github.com/rust-lang/crates.io-index#serde@1.0.0")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pkgName).To(Equal("serde"))
+				Expect(version).To(Equal("1.0.0"))
+				Expect(runner.SourceKind(url)).To(Equal("registry"))
+			})
+
 			it("fails to parse because there is no at sign", func() {
 				_, _, _, err := runner.ParseWorkspaceMember("path+file:///workspace/basics#foo")
 				Expect(err).To(MatchError("unable to parse workspace member [path+file:///workspace/basics#foo], missing `@`"))
@@ -850,8 +3004,10 @@ type buildMetadata struct {
 }
 
 type buildPackage struct {
-	id      string
-	targets []buildTarget
+	id          string
+	edition     string
+	rustVersion string
+	targets     []buildTarget
 }
 
 type buildTarget struct {
@@ -900,7 +3056,16 @@ func BuildMetadataWithPackages(workspacePath string, data buildMetadata) string
 
 	packageJson := `[`
 	for _, pkg := range data.packages {
-		packageJson += fmt.Sprintf(`{"id": "%s", "targets": [ `, pkg.id)
+		packageJson += fmt.Sprintf(`{"id": "%s", `, pkg.id)
+		if pkg.edition != "" {
+			packageJson += fmt.Sprintf(`"edition": "%s", `, pkg.edition)
+		}
+		if pkg.rustVersion != "" {
+			packageJson += fmt.Sprintf(`"rust_version": "%s", `, pkg.rustVersion)
+		} else {
+			packageJson += `"rust_version": null, `
+		}
+		packageJson += `"targets": [ `
 		for i, t := range pkg.targets {
 			packageJson += fmt.Sprintf(`{"kind": ["%s"], "crate_types": ["%s"], "name": "%s", "src_path": "%s", "edition": "%s", "doc": %s, "doctest": %s, "test": %s}`,
 				t.kind, t.crateType, t.name, t.srcPath, t.edition, t.doc, t.doctest, t.test)
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/paketo-buildpacks/libpak/effect"
+)
+
+// DefaultStripArgs are the arguments `strip` is run with if no arguments are configured
+var DefaultStripArgs = []string{"--strip-all"}
+
+// ParseStripArgs parses the arguments for `strip`, which must be non-empty tokens. Without arguments,
+// DefaultStripArgs are used.
+func ParseStripArgs(raw string) ([]string, error) {
+	args, err := shellwords.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse strip arguments\n%w", err)
+	}
+
+	for _, arg := range args {
+		if strings.TrimSpace(arg) == "" {
+			return nil, fmt.Errorf("unable to use strip arguments %q, arguments must not be empty", raw)
+		}
+	}
+
+	if len(args) == 0 {
+		return DefaultStripArgs, nil
+	}
+
+	return args, nil
+}
+
+// StripBinaries runs `strip` with StripArgs, or DefaultStripArgs, on every executable in binDir
+func (c CargoRunner) StripBinaries(binDir string) error {
+	entries, err := os.ReadDir(binDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to read %s\n%w", binDir, err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	stripArgs := c.StripArgs
+	if len(stripArgs) == 0 {
+		stripArgs = DefaultStripArgs
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("unable to read %s\n%w", entry.Name(), err)
+		}
+
+		if !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+
+		args := append(append([]string{}, stripArgs...), filepath.Join(binDir, entry.Name()))
+
+		if c.DryRun {
+			c.Logger.Bodyf("Dry run, skipping: strip %s", strings.Join(args, " "))
+			continue
+		}
+
+		c.Logger.Bodyf("strip %s", strings.Join(args, " "))
+
+		buf := &bytes.Buffer{}
+		if err := c.Executor.Execute(effect.Execution{
+			Command: "strip",
+			Args:    args,
+			Stdout:  buf,
+			Stderr:  buf,
+		}); err != nil {
+			return fmt.Errorf("unable to strip %s\n%s\n%w", entry.Name(), strings.TrimSpace(buf.String()), err)
+		}
+	}
+
+	return nil
+}
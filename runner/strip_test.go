@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/effect/mocks"
+	"github.com/paketo-community/cargo/runner"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+)
+
+func testStrip(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		binDir   string
+		executor *mocks.Executor
+	)
+
+	it.Before(func() {
+		binDir = t.TempDir()
+		executor = &mocks.Executor{}
+
+		Expect(os.WriteFile(filepath.Join(binDir, "server"), []byte{}, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binDir, "worker"), []byte{}, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binDir, "server.sha256"), []byte{}, 0644)).To(Succeed())
+	})
+
+	it("parses strip arguments", func() {
+		Expect(runner.ParseStripArgs("--strip-unneeded --keep-section=.comment")).To(Equal([]string{"--strip-unneeded", "--keep-section=.comment"}))
+		Expect(runner.ParseStripArgs("")).To(Equal([]string{"--strip-all"}))
+
+		_, err := runner.ParseStripArgs("--strip-unneeded ''")
+		Expect(err).To(MatchError(ContainSubstring("arguments must not be empty")))
+	})
+
+	it("strips every binary with the configured arguments", func() {
+		executor.On("Execute", mock.Anything).Return(nil)
+
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithExecutor(executor),
+			runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+			runner.WithStripArgs([]string{"--strip-unneeded"}))
+
+		Expect(cargoRunner.StripBinaries(binDir)).To(Succeed())
+
+		Expect(executor.Calls).To(HaveLen(2))
+		server := executor.Calls[0].Arguments[0].(effect.Execution)
+		Expect(server.Command).To(Equal("strip"))
+		Expect(server.Args).To(Equal([]string{"--strip-unneeded", filepath.Join(binDir, "server")}))
+		worker := executor.Calls[1].Arguments[0].(effect.Execution)
+		Expect(worker.Args).To(Equal([]string{"--strip-unneeded", filepath.Join(binDir, "worker")}))
+	})
+
+	it("strips with --strip-all by default", func() {
+		executor.On("Execute", mock.Anything).Return(nil)
+
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithExecutor(executor),
+			runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+		Expect(cargoRunner.StripBinaries(binDir)).To(Succeed())
+
+		Expect(executor.Calls[0].Arguments[0].(effect.Execution).Args).To(Equal([]string{"--strip-all", filepath.Join(binDir, "server")}))
+	})
+
+	it("fails if strip fails", func() {
+		executor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+			_, err := ex.Stderr.Write([]byte("strip: unrecognized option '--bogus'"))
+			Expect(err).ToNot(HaveOccurred())
+			return errors.New("exit status 1")
+		})
+
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithExecutor(executor),
+			runner.WithLogger(bard.NewLogger(&bytes.Buffer{})),
+			runner.WithStripArgs([]string{"--bogus"}))
+
+		err := cargoRunner.StripBinaries(binDir)
+		Expect(err).To(MatchError(ContainSubstring("unable to strip server")))
+		Expect(err).To(MatchError(ContainSubstring("unrecognized option")))
+	})
+}
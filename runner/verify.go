@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/paketo-buildpacks/libpak/effect"
+)
+
+// DefaultProbe are the arguments a binary is run with to verify that it still executes
+var DefaultProbe = []string{"--version"}
+
+// ParseBinaryProbes parses a `;` separated list of `<binary>=<arguments>` entries into a map of binary name to the
+// arguments it is run with when it is verified. Empty arguments skip the verification of the binary.
+func ParseBinaryProbes(raw string) (map[string][]string, error) {
+	probes := map[string][]string{}
+
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, rawArgs, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("unable to parse %q, expected <binary>=<arguments>", entry)
+		}
+
+		args, err := shellwords.Parse(rawArgs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse arguments of %s\n%w", name, err)
+		}
+
+		probes[name] = args
+	}
+
+	return probes, nil
+}
+
+// VerifyBinaries runs every executable in binDir with its probe, `--version` by default, and fails if one of them
+// does not run successfully, which happens if stripping corrupted it
+func (c CargoRunner) VerifyBinaries(binDir string) error {
+	entries, err := os.ReadDir(binDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to read %s\n%w", binDir, err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("unable to read %s\n%w", entry.Name(), err)
+		}
+
+		if !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+
+		args, ok := c.BinaryProbes[entry.Name()]
+		if !ok {
+			args = DefaultProbe
+		}
+
+		if len(args) == 0 {
+			c.Logger.Bodyf("Skipping verification of %s", entry.Name())
+			continue
+		}
+
+		if c.DryRun {
+			c.Logger.Bodyf("Dry run, skipping: %s %s", entry.Name(), strings.Join(args, " "))
+			continue
+		}
+
+		c.Logger.Bodyf("Verifying %s %s", entry.Name(), strings.Join(args, " "))
+
+		buf := &bytes.Buffer{}
+		if err := c.Executor.Execute(effect.Execution{
+			Command: filepath.Join(binDir, entry.Name()),
+			Args:    args,
+			Stdout:  buf,
+			Stderr:  buf,
+		}); err != nil {
+			return fmt.Errorf("unable to run %s %s, the binary may be corrupt, set a different probe with BP_CARGO_POST_STRIP_VERIFY_PROBES if it does not support these arguments\n%s\n%w",
+				entry.Name(), strings.Join(args, " "), strings.TrimSpace(buf.String()), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/effect/mocks"
+	"github.com/paketo-community/cargo/runner"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+)
+
+func testVerify(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		binDir   string
+		executor *mocks.Executor
+	)
+
+	it.Before(func() {
+		binDir = t.TempDir()
+		executor = &mocks.Executor{}
+
+		Expect(os.WriteFile(filepath.Join(binDir, "server"), []byte{}, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binDir, "worker"), []byte{}, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(binDir, "server.sha256"), []byte{}, 0644)).To(Succeed())
+	})
+
+	it("parses probes", func() {
+		probes, err := runner.ParseBinaryProbes("server=--help ; worker=--check 'a b';skipped=")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(probes).To(Equal(map[string][]string{
+			"server":  {"--help"},
+			"worker":  {"--check", "a b"},
+			"skipped": {},
+		}))
+
+		_, err = runner.ParseBinaryProbes("--help")
+		Expect(err).To(MatchError(ContainSubstring(`unable to parse "--help"`)))
+	})
+
+	it("runs every binary with its probe", func() {
+		executor.On("Execute", mock.Anything).Return(nil)
+
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithBinaryProbes(map[string][]string{"worker": {"--check"}}),
+			runner.WithExecutor(executor),
+			runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+		Expect(cargoRunner.VerifyBinaries(binDir)).To(Succeed())
+
+		Expect(executor.Calls).To(HaveLen(2))
+		server := executor.Calls[0].Arguments[0].(effect.Execution)
+		Expect(server.Command).To(Equal(filepath.Join(binDir, "server")))
+		Expect(server.Args).To(Equal([]string{"--version"}))
+		worker := executor.Calls[1].Arguments[0].(effect.Execution)
+		Expect(worker.Command).To(Equal(filepath.Join(binDir, "worker")))
+		Expect(worker.Args).To(Equal([]string{"--check"}))
+	})
+
+	it("skips binaries with an empty probe", func() {
+		executor.On("Execute", mock.Anything).Return(nil)
+
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithBinaryProbes(map[string][]string{"server": {}}),
+			runner.WithExecutor(executor),
+			runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+		Expect(cargoRunner.VerifyBinaries(binDir)).To(Succeed())
+
+		Expect(executor.Calls).To(HaveLen(1))
+		Expect(executor.Calls[0].Arguments[0].(effect.Execution).Command).To(Equal(filepath.Join(binDir, "worker")))
+	})
+
+	it("fails if a binary does not run", func() {
+		executor.On("Execute", mock.MatchedBy(func(ex effect.Execution) bool {
+			return ex.Command == filepath.Join(binDir, "worker")
+		})).Return(func(ex effect.Execution) error {
+			_, err := ex.Stderr.Write([]byte("Segmentation fault"))
+			Expect(err).ToNot(HaveOccurred())
+			return errors.New("exit status 139")
+		})
+		executor.On("Execute", mock.Anything).Return(nil)
+
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithExecutor(executor),
+			runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+		err := cargoRunner.VerifyBinaries(binDir)
+		Expect(err).To(MatchError(ContainSubstring("unable to run worker --version, the binary may be corrupt")))
+		Expect(err).To(MatchError(ContainSubstring("Segmentation fault")))
+	})
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+)
+
+// WarmBuildArgs converts the arguments of `cargo install` into the arguments of a `cargo build` of the same crate with
+// the same settings. `--path` becomes `--manifest-path`, install only arguments are dropped and the release profile,
+// which `cargo install` uses by default, is selected unless `--debug` or `--profile` is set.
+func WarmBuildArgs(installArgs []string) []string {
+	args := []string{"build"}
+	release := true
+
+	for i := 1; i < len(installArgs); i++ {
+		arg := installArgs[i]
+
+		switch {
+		case arg == "--root":
+			i++
+		case strings.HasPrefix(arg, "--root="), arg == "--force", arg == "-f":
+		case arg == "--path" && i+1 < len(installArgs):
+			i++
+			args = append(args, fmt.Sprintf("--manifest-path=%s", filepath.Join(installArgs[i], "Cargo.toml")))
+		case strings.HasPrefix(arg, "--path="):
+			args = append(args, fmt.Sprintf("--manifest-path=%s", filepath.Join(strings.TrimPrefix(arg, "--path="), "Cargo.toml")))
+		case arg == "--debug":
+			release = false
+		case arg == "--profile", strings.HasPrefix(arg, "--profile="):
+			release = false
+			args = append(args, arg)
+		default:
+			args = append(args, arg)
+		}
+	}
+
+	if release {
+		args = append(args, "--release")
+	}
+
+	return args
+}
+
+// warmBuild runs `cargo build` with the settings of `cargo install`, which then reuses the artifacts it compiled into
+// the target directory
+func (c CargoRunner) warmBuild(srcDir string, installArgs []string, env []string) error {
+	args := WarmBuildArgs(installArgs)
+
+	c.Logger.Bodyf("cargo %s", strings.Join(args, " "))
+	if err := c.executeWithTimeout(effect.Execution{
+		Command: "cargo",
+		Args:    args,
+		Dir:     srcDir,
+		Env:     env,
+		Stdout:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+		Stderr:  bard.NewWriter(c.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+	}); err != nil {
+		return fmt.Errorf("unable to build before install\n%w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runner_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/effect/mocks"
+	"github.com/paketo-community/cargo/runner"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+)
+
+func testWarm(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		cargoHome  string
+		destLayer  libcnb.Layer
+		executor   *mocks.Executor
+		workingDir string
+	)
+
+	it.Before(func() {
+		cargoHome = t.TempDir()
+		destLayer = libcnb.Layer{Name: "dest-layer", Path: t.TempDir()}
+		executor = &mocks.Executor{}
+		workingDir = t.TempDir()
+	})
+
+	it("converts install arguments into build arguments", func() {
+		Expect(runner.WarmBuildArgs([]string{"install", "--color=never", "--root=/layer", "--path=./api", "--features=tls", "--locked"})).
+			To(Equal([]string{"build", "--color=never", "--manifest-path=api/Cargo.toml", "--features=tls", "--locked", "--release"}))
+
+		Expect(runner.WarmBuildArgs([]string{"install", "--root", "/layer", "--path", ".", "--force", "--target=x86_64-unknown-linux-musl"})).
+			To(Equal([]string{"build", "--manifest-path=Cargo.toml", "--target=x86_64-unknown-linux-musl", "--release"}))
+	})
+
+	it("keeps the profile selected for install", func() {
+		Expect(runner.WarmBuildArgs([]string{"install", "--path=.", "--debug"})).
+			To(Equal([]string{"build", "--manifest-path=Cargo.toml"}))
+
+		Expect(runner.WarmBuildArgs([]string{"install", "--path=.", "--profile=dist"})).
+			To(Equal([]string{"build", "--manifest-path=Cargo.toml", "--profile=dist"}))
+	})
+
+	it("builds before installing in the same directory", func() {
+		executor.On("Execute", mock.Anything).Return(nil)
+
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithBuildBeforeInstall(true),
+			runner.WithCargoHome(cargoHome),
+			runner.WithExecutor(executor),
+			runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+		Expect(cargoRunner.InstallMember("./api", workingDir, destLayer)).To(Succeed())
+
+		Expect(executor.Calls).To(HaveLen(2))
+		build := executor.Calls[0].Arguments[0].(effect.Execution)
+		install := executor.Calls[1].Arguments[0].(effect.Execution)
+
+		Expect(build.Args).To(Equal([]string{"build", "--color=never", "--manifest-path=api/Cargo.toml", "--release"}))
+		Expect(install.Args[0]).To(Equal("install"))
+
+		// the target directory is found relative to the working directory, so both use the same one
+		Expect(build.Dir).To(Equal(workingDir))
+		Expect(install.Dir).To(Equal(workingDir))
+		Expect(build.Env).To(Equal(install.Env))
+	})
+
+	it("does not install when the build fails", func() {
+		executor.On("Execute", mock.Anything).Return(errors.New("exit status 101"))
+
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithBuildBeforeInstall(true),
+			runner.WithCargoHome(cargoHome),
+			runner.WithExecutor(executor),
+			runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+		Expect(cargoRunner.InstallMember("./api", workingDir, destLayer)).To(MatchError("unable to build before install\nexit status 101"))
+		Expect(executor.Calls).To(HaveLen(1))
+	})
+
+	it("only installs by default", func() {
+		executor.On("Execute", mock.Anything).Return(nil)
+
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithCargoHome(cargoHome),
+			runner.WithExecutor(executor),
+			runner.WithLogger(bard.NewLogger(&bytes.Buffer{})))
+
+		Expect(cargoRunner.InstallMember("./api", workingDir, destLayer)).To(Succeed())
+
+		Expect(executor.Calls).To(HaveLen(1))
+		Expect(executor.Calls[0].Arguments[0].(effect.Execution).Args[0]).To(Equal("install"))
+	})
+
+	it("logs both commands on a dry run", func() {
+		buf := &bytes.Buffer{}
+
+		cargoRunner := runner.NewCargoRunner(
+			runner.WithBuildBeforeInstall(true),
+			runner.WithCargoHome(cargoHome),
+			runner.WithDryRun(true),
+			runner.WithExecutor(executor),
+			runner.WithLogger(bard.NewLogger(buf)))
+
+		Expect(cargoRunner.InstallMember("./api", workingDir, destLayer)).To(Succeed())
+
+		Expect(executor.Calls).To(BeEmpty())
+		Expect(buf.String()).To(ContainSubstring("Dry run, skipping: cargo build --color=never --manifest-path=api/Cargo.toml --release"))
+		Expect(buf.String()).To(ContainSubstring("Dry run, skipping: cargo install"))
+	})
+}